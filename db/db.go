@@ -28,10 +28,59 @@ func InitDB() {
 		log.Fatal("Erro ao conectar ao banco de dados:", err)
 	}
 
+	// Remove pais órfãos antes de criar a chave estrangeira com cliente
+	if DB.Migrator().HasTable(&models.Pais{}) && DB.Migrator().HasTable(&models.Cliente{}) {
+		resultado := DB.Where("cliente_id NOT IN (?)", DB.Model(&models.Cliente{}).Select("id")).Delete(&models.Pais{})
+		if resultado.Error != nil {
+			log.Fatal("Erro ao remover pais órfãos:", resultado.Error)
+		}
+		if resultado.RowsAffected > 0 {
+			log.Printf("%d registros de pais órfãos removidos", resultado.RowsAffected)
+		}
+	}
+
+	// O btree_gist permite combinar igualdade e sobreposição de intervalos na
+	// restrição de exclusão das reservas
+	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS btree_gist").Error; err != nil {
+		log.Fatal("Erro ao habilitar a extensão btree_gist:", err)
+	}
+
 	// Adiciona a migração aqui
-	if err := DB.AutoMigrate(&models.Cliente{}, &models.Pais{}, &models.User{}, &models.Sale{}, &models.Produto{}, &models.Subscription{}); err != nil {
+	if err := DB.AutoMigrate(&models.Cliente{}, &models.Pais{}, &models.Guardian{}, &models.User{}, &models.Sale{}, &models.Produto{}, &models.Subscription{}, &models.PeriodoInadimplencia{}, &models.RegistroAuditoria{}, &models.Nota{}, &models.Tag{}, &models.PerfilSaude{}, &models.ContatoEmergencia{}, &models.Documento{}, &models.Familia{}, &models.RegraDescontoFamilia{}, &models.ProdutoFisico{}, &models.ProdutoServico{}, &models.Modalidade{}, &models.Aula{}, &models.ExcecaoAgenda{}, &models.Presenca{}, &models.CredencialCheckin{}, &models.Faixa{}, &models.Graduacao{}, &models.ExameGraduacao{}, &models.CandidatoExame{}, &models.Matricula{}, &models.Lead{}, &models.AulaExperimental{}, &models.Evento{}, &models.LoteEvento{}, &models.CategoriaEvento{}, &models.InscricaoEvento{}, &models.RegraRemuneracao{}, &models.AulaMinistrada{}, &models.ExtratoRemuneracao{}, &models.ItemExtrato{}, &models.Recurso{}, &models.JanelaDisponibilidade{}, &models.Reserva{}, &models.CongelamentoAssinatura{}); err != nil {
 		log.Fatal("Erro ao migrar as tabelas:", err)
 	}
 
+	// Vendas anteriores ao vencimento vencem na data da criação, como as novas
+	if resultado := DB.Exec("UPDATE sales SET vencimento = criado_em WHERE vencimento IS NULL"); resultado.Error != nil {
+		log.Fatal("Erro ao preencher o vencimento das vendas:", resultado.Error)
+	} else if resultado.RowsAffected > 0 {
+		log.Printf("Vencimento preenchido em %d vendas antigas", resultado.RowsAffected)
+	}
+
+	// Impede reservas sobrepostas do mesmo recurso ou do mesmo instrutor,
+	// mesmo com requisições simultâneas
+	for nome, coluna := range map[string]string{"reservas_recurso_sem_conflito": "recurso_id", "reservas_instrutor_sem_conflito": "instrutor_id"} {
+		if err := DB.Exec(fmt.Sprintf(`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '%[1]s') THEN
+				ALTER TABLE reservas ADD CONSTRAINT %[1]s
+					EXCLUDE USING gist (%[2]s WITH =, tstzrange(inicio, fim) WITH &&)
+					WHERE (status <> 'cancelada' AND %[2]s IS NOT NULL);
+			END IF;
+		END $$`, nome, coluna)).Error; err != nil {
+			log.Fatal("Erro ao criar a restrição de conflito das reservas:", err)
+		}
+	}
+
+	// Os índices cegos únicos impedem que cadastros simultâneos repitam CPF ou
+	// e-mail; anonimizados e duplicados já mesclados ficam de fora. Clientes
+	// duplicados anteriores impedem a criação, tentada novamente a cada início.
+	for nome, coluna := range map[string]string{"idx_clientes_cpf_hash_unico": "cpf_hash", "idx_clientes_email_hash_unico": "email_hash"} {
+		if err := DB.Exec(fmt.Sprintf(`CREATE UNIQUE INDEX IF NOT EXISTS %[1]s ON clientes (%[2]s)
+			WHERE anonimizado_em IS NULL AND %[2]s <> ''
+				AND (motivo_arquivamento IS NULL OR motivo_arquivamento <> 'duplicado')`, nome, coluna)).Error; err != nil {
+			log.Printf("Índice %s não criado; mescle os clientes duplicados listados em /clientes/duplicados: %v", nome, err)
+		}
+	}
+
 	fmt.Println("Banco de dados conectado e migrações executadas com sucesso!")
 }
@@ -0,0 +1,62 @@
+// events/events.go
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Tipos de evento emitidos pela API
+const (
+	ClienteMaioridade  = "cliente.maioridade"
+	AtestadoVencendo   = "cliente.atestado_vencendo"
+	MatriculaPromovida = "matricula.promovida"
+)
+
+// Evento representa algo relevante que aconteceu no sistema
+type Evento struct {
+	Tipo       string                 `json:"tipo"`
+	ClienteID  string                 `json:"cliente_id,omitempty"`
+	Dados      map[string]interface{} `json:"dados,omitempty"`
+	OcorridoEm time.Time              `json:"ocorrido_em"`
+}
+
+// Handler processa um evento publicado
+type Handler func(Evento)
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string][]Handler{}
+)
+
+// Subscribe registra um handler para um tipo de evento
+func Subscribe(tipo string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[tipo] = append(handlers[tipo], h)
+}
+
+// Publish registra o evento no log e o entrega aos handlers inscritos.
+// Cada handler roda em sua própria goroutine para não bloquear quem publicou.
+func Publish(e Evento) {
+	if e.OcorridoEm.IsZero() {
+		e.OcorridoEm = time.Now()
+	}
+	log.Printf("Evento %s (cliente %s): %v", e.Tipo, e.ClienteID, e.Dados)
+
+	mu.RLock()
+	inscritos := append([]Handler(nil), handlers[e.Tipo]...)
+	mu.RUnlock()
+
+	for _, h := range inscritos {
+		go func(h Handler) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Erro ao processar evento %s: %v", e.Tipo, r)
+				}
+			}()
+			h(e)
+		}(h)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"go-api/middleware"
 	"go-api/models"
 	"go-api/routes"
+	"go-api/services"
+	"go-api/tasks"
 	"go-api/utils"
 	"log"
 	"os"
@@ -19,10 +21,21 @@ import (
 func main() {
 	config.InitDB()
 
+	// Criptografar dados pessoais gravados antes da criptografia de campos
+	if err := services.CriptografarDadosLegados(); err != nil {
+		log.Println("Erro ao criptografar dados legados:", err)
+	}
+
 	// Verificar e criar o superadmin
 	createSuperAdmin()
 
-	app := fiber.New()
+	// Iniciar as tarefas agendadas
+	tasks.IniciarAgendador()
+
+	// O limite do corpo acompanha o tamanho máximo dos documentos, com folga para o multipart
+	app := fiber.New(fiber.Config{
+		BodyLimit: int(services.TamanhoMaximoDocumento()) + 1<<20,
+	})
 	middleware.SetupSecurity(app)
 	routes.SetupAuthRoutes(app)
 	routes.SetupClienteRoutes(app)
@@ -30,6 +43,17 @@ func main() {
 	routes.SetupSubscriptionRoutes(app)
 	routes.SetupProductRoutes(app)
 	routes.SetupSaleRoutes(app)
+	routes.SetupTagRoutes(app)
+	routes.SetupDocumentoRoutes(app)
+	routes.SetupFamiliaRoutes(app)
+	routes.SetupAulaRoutes(app)
+	routes.SetupPresencaRoutes(app)
+	routes.SetupCredencialRoutes(app)
+	routes.SetupGraduacaoRoutes(app)
+	routes.SetupLeadRoutes(app)
+	routes.SetupEventoRoutes(app)
+	routes.SetupRemuneracaoRoutes(app)
+	routes.SetupReservaRoutes(app)
 
 	log.Fatal(app.Listen(":3000"))
 }
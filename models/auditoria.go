@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// RegistroAuditoria guarda uma ação relevante executada por um usuário
+type RegistroAuditoria struct {
+	ID         string                 `json:"id" gorm:"primaryKey"`
+	Entidade   string                 `json:"entidade" gorm:"index:idx_auditoria_entidade"`
+	EntidadeID string                 `json:"entidade_id" gorm:"index:idx_auditoria_entidade"`
+	ClienteID  *string                `json:"cliente_id,omitempty" gorm:"index"`
+	Acao       string                 `json:"acao"`
+	Autor      string                 `json:"autor"`
+	Dados      map[string]interface{} `json:"dados,omitempty" gorm:"serializer:json;type:jsonb"`
+	CriadoEm   time.Time              `json:"criado_em" gorm:"index"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (r *RegistroAuditoria) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == "" {
+		r.ID, err = gonanoid.New()
+	}
+	if r.CriadoEm.IsZero() {
+		r.CriadoEm = time.Now()
+	}
+	return
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Modalidade é uma arte marcial ou atividade oferecida (ex.: Jiu-Jitsu, Judô)
+type Modalidade struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Nome      string    `json:"nome" gorm:"uniqueIndex;not null" validate:"required,min=2"`
+	Descricao string    `json:"descricao"`
+	Ativa     bool      `json:"ativa" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (m *Modalidade) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == "" {
+		m.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Aula é uma turma que se repete toda semana no mesmo dia e horário
+type Aula struct {
+	ID           string      `json:"id" gorm:"primaryKey"`
+	ModalidadeID string      `json:"modalidade_id" gorm:"index;not null" validate:"required"`
+	Modalidade   *Modalidade `json:"modalidade,omitempty" gorm:"foreignKey:ModalidadeID;constraint:OnDelete:RESTRICT"`
+	Nome         string      `json:"nome" validate:"required,min=3"`
+	DiaSemana    int         `json:"dia_semana" gorm:"index" validate:"min=0,max=6"` // 0 = domingo
+	HoraInicio   string      `json:"hora_inicio" validate:"required,datetime=15:04"`
+	HoraFim      string      `json:"hora_fim" validate:"required,datetime=15:04"`
+	Sala         string      `json:"sala" validate:"required"`
+	Capacidade   int         `json:"capacidade" validate:"required,gt=0"`
+	IdadeMinima  *int        `json:"idade_minima" validate:"omitempty,min=0"` // Em anos, inclusive
+	IdadeMaxima  *int        `json:"idade_maxima" validate:"omitempty,min=0"` // Em anos, inclusive
+	InstrutorID  *string     `json:"instrutor_id" gorm:"index"`
+	Instrutor    *User       `json:"instrutor,omitempty" gorm:"foreignKey:InstrutorID;constraint:OnDelete:SET NULL"`
+	Ativa        bool        `json:"ativa" gorm:"default:true"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (a *Aula) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == "" {
+		a.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Horario retorna o início e o fim da aula na data informada
+func (a *Aula) Horario(data time.Time) (inicio, fim time.Time) {
+	dia := time.Date(data.Year(), data.Month(), data.Day(), 0, 0, 0, 0, data.Location())
+	if h, err := time.Parse("15:04", a.HoraInicio); err == nil {
+		inicio = dia.Add(time.Duration(h.Hour())*time.Hour + time.Duration(h.Minute())*time.Minute)
+	}
+	if h, err := time.Parse("15:04", a.HoraFim); err == nil {
+		fim = dia.Add(time.Duration(h.Hour())*time.Hour + time.Duration(h.Minute())*time.Minute)
+	}
+	return
+}
+
+// ExcecaoAgenda cancela as aulas de uma data. Sem AulaID vale para todas
+// as aulas do dia, como em feriados e recessos.
+type ExcecaoAgenda struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Data      time.Time `json:"data" gorm:"type:date;index;not null" validate:"required"`
+	AulaID    *string   `json:"aula_id" gorm:"index"`
+	Aula      *Aula     `json:"aula,omitempty" gorm:"foreignKey:AulaID;constraint:OnDelete:CASCADE"`
+	Motivo    string    `json:"motivo" validate:"required"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (e *ExcecaoAgenda) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == "" {
+		e.ID, err = gonanoid.New()
+	}
+	return
+}
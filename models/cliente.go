@@ -6,14 +6,26 @@ import (
 	"gorm.io/gorm"
 )
 
+// Enum para o motivo de arquivamento do cliente
+type MotivoArquivamento string
+
+const (
+	MotivoMudanca     MotivoArquivamento = "mudou"
+	MotivoDesistencia MotivoArquivamento = "desistiu"
+	MotivoFalecimento MotivoArquivamento = "falecido"
+	MotivoDuplicado   MotivoArquivamento = "duplicado"
+)
+
 type Cliente struct {
 	ID                string    `json:"id" gorm:"primaryKey"`
 	Nome              string    `json:"nome" validate:"required,min=3"`
 	DataNascimento    time.Time `json:"data_nascimento" validate:"required"`
 	Genero            string    `json:"genero" validate:"required,oneof=Masculino Feminino Outro"`
-	Email             string    `json:"email" validate:"required,email"`
-	Telefone          string    `json:"telefone" validate:"required"`
-	CPF               string    `json:"cpf" validate:"required"`
+	Email             string    `json:"email" gorm:"serializer:criptografado;type:text" validate:"required,email"`
+	Telefone          string    `json:"telefone" gorm:"serializer:criptografado;type:text" validate:"required"`
+	CPF               string    `json:"cpf" gorm:"serializer:criptografado;type:text" validate:"required,cpf"`
+	EmailHash         string    `json:"-" gorm:"index"` // Índice cego do e-mail
+	CPFHash           string    `json:"-" gorm:"index"` // Índice cego do CPF
 	Endereco          string    `json:"endereco" validate:"required"`
 	Cidade            string    `json:"cidade" validate:"required"`
 	Estado            string    `json:"estado" validate:"required"`
@@ -21,6 +33,18 @@ type Cliente struct {
 	FlagAniversariante bool     `json:"flag_aniversariante"`
 	FlagInadimplente   bool     `json:"flag_inadimplente"`
 	PaisID            *string   `json:"pais_id"`
+	FamiliaID         *string   `json:"familia_id" gorm:"index"` // Gerenciado pelas rotas /familias
+	Pais              *Pais      `json:"pais,omitempty" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	Guardians         []Guardian `json:"guardians,omitempty" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	Tags              []Tag      `json:"tags,omitempty" gorm:"many2many:cliente_tags;constraint:OnDelete:CASCADE"`
+	Notas             []Nota     `json:"-" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	PerfilSaude       *PerfilSaude `json:"-" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	ContatosEmergencia []ContatoEmergencia `json:"-" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	Documentos        []Documento `json:"-" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	Credencial        *CredencialCheckin `json:"-" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	AnonimizadoEm     *time.Time `json:"anonimizado_em,omitempty"` // Preenchido após a anonimização pela LGPD
+	ArquivadoEm       *time.Time `json:"arquivado_em,omitempty" gorm:"index"`
+	MotivoArquivamento MotivoArquivamento `json:"motivo_arquivamento,omitempty"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 }
@@ -29,13 +53,14 @@ type Pais struct {
 	ID          string `json:"id" gorm:"primaryKey"`
 	ClienteID   string `json:"cliente_id" gorm:"unique;constraint:OnDelete:CASCADE"` // Chave estrangeira para Cliente
 	NomePai     string `json:"nome_pai"`
-	TelefonePai string `json:"telefone_pai"`
-	EmailPai    string `json:"email_pai"`
-	CPFPai      string `json:"cpf_pai"`
+	TelefonePai string `json:"telefone_pai" gorm:"serializer:criptografado;type:text"`
+	EmailPai    string `json:"email_pai" gorm:"serializer:criptografado;type:text"`
+	CPFPai      string `json:"cpf_pai" gorm:"serializer:criptografado;type:text"`
 	NomeMae     string `json:"nome_mae"`
-	TelefoneMae string `json:"telefone_mae"`
-	EmailMae    string `json:"email_mae"`
-	CPFMae      string `json:"cpf_mae"`
+	TelefoneMae string `json:"telefone_mae" gorm:"serializer:criptografado;type:text"`
+	EmailMae    string `json:"email_mae" gorm:"serializer:criptografado;type:text"`
+	CPFMae      string `json:"cpf_mae" gorm:"serializer:criptografado;type:text"`
+	ArquivadoEm *time.Time `json:"arquivado_em,omitempty"` // Preenchido quando o cliente atinge a maioridade
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -48,10 +73,31 @@ func (u *Pais) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// Atualiza os índices cegos antes de gravar os campos criptografados
+func (u *Cliente) BeforeSave(tx *gorm.DB) (err error) {
+	u.CPFHash = IndiceCPF(u.CPF)
+	u.EmailHash = IndiceEmail(u.Email)
+	return
+}
+
 // Gerar ID automaticamente com nanoid
 func (u *Cliente) BeforeCreate(tx *gorm.DB) (err error) {
 	if u.ID == "" {
 		u.ID, err = gonanoid.New()
 	}
 	return
-}
\ No newline at end of file
+}
+
+// Idade calcula a idade do cliente na data de referência
+func (c *Cliente) Idade(ref time.Time) int {
+	idade := ref.Year() - c.DataNascimento.Year()
+	if ref.Before(c.DataNascimento.AddDate(idade, 0, 0)) {
+		idade-- // Ajuste para o caso de o aniversário ainda não ter ocorrido este ano
+	}
+	return idade
+}
+
+// MenorDeIdade indica se o cliente tem menos de 18 anos hoje
+func (c *Cliente) MenorDeIdade() bool {
+	return c.Idade(time.Now()) < 18
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para a situação do congelamento
+type StatusCongelamento string
+
+const (
+	CongelamentoAgendado  StatusCongelamento = "agendado"
+	CongelamentoAtivo     StatusCongelamento = "ativo"
+	CongelamentoEncerrado StatusCongelamento = "encerrado"
+	CongelamentoCancelado StatusCongelamento = "cancelado"
+)
+
+// CongelamentoAssinatura suspende a assinatura entre Inicio e Fim (inclusive),
+// por viagem ou lesão do aluno. O acesso fica bloqueado no período, e a próxima
+// cobrança e o fim do contrato são adiados pela quantidade de dias congelados.
+type CongelamentoAssinatura struct {
+	ID             string             `json:"id" gorm:"primaryKey"`
+	SubscriptionID string             `json:"subscription_id" gorm:"not null;index"`
+	Subscription   *Subscription      `json:"subscription,omitempty" gorm:"foreignKey:SubscriptionID;constraint:OnDelete:CASCADE"`
+	Inicio         time.Time          `json:"inicio" gorm:"type:date;not null"`
+	Fim            time.Time          `json:"fim" gorm:"type:date;not null"`
+	Dias           int                `json:"dias"`
+	Motivo         string             `json:"motivo"`
+	Status         StatusCongelamento `json:"status" gorm:"index;not null"`
+	AdiouCobranca  bool               `json:"adiou_cobranca"` // A próxima cobrança foi adiada por este congelamento
+	CriadoPor      string             `json:"criado_por"`
+	EncerradoEm    *time.Time         `json:"encerrado_em"`
+	EncerradoPor   string             `json:"encerrado_por,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (c *CongelamentoAssinatura) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == "" {
+		c.ID, err = gonanoid.New()
+	}
+	return
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CredencialCheckin guarda o segredo usado para gerar os QR codes rotativos
+// de check-in do cliente. Revogada, nenhum código do cliente é aceito.
+type CredencialCheckin struct {
+	ClienteID       string     `json:"cliente_id" gorm:"primaryKey"`
+	Segredo         string     `json:"-" gorm:"serializer:criptografado;type:text;not null"`
+	RevogadaEm      *time.Time `json:"revogada_em"`
+	MotivoRevogacao string     `json:"motivo_revogacao,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
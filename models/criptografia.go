@@ -0,0 +1,72 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go-api/utils"
+
+	"gorm.io/gorm/schema"
+)
+
+// Prefixo que identifica valores já criptografados no banco.
+// Valores sem o prefixo são dados legados ainda em texto puro.
+const prefixoCriptografado = "enc:"
+
+func init() {
+	schema.RegisterSerializer("criptografado", SerializerCriptografado{})
+}
+
+// SerializerCriptografado criptografa campos de texto ao gravar e
+// descriptografa ao ler, usando a mesma chave dos dados de cartão.
+// Uso: `gorm:"serializer:criptografado"`
+type SerializerCriptografado struct{}
+
+func (SerializerCriptografado) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var texto string
+	switch valor := dbValue.(type) {
+	case nil:
+		return nil
+	case string:
+		texto = valor
+	case []byte:
+		texto = string(valor)
+	default:
+		return fmt.Errorf("tipo não suportado para campo criptografado: %T", dbValue)
+	}
+
+	if strings.HasPrefix(texto, prefixoCriptografado) {
+		decifrado, err := utils.Decrypt(strings.TrimPrefix(texto, prefixoCriptografado))
+		if err != nil {
+			return err
+		}
+		texto = string(decifrado)
+	}
+
+	return field.Set(ctx, dst, texto)
+}
+
+func (SerializerCriptografado) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	texto, _ := fieldValue.(string)
+	if texto == "" {
+		return "", nil
+	}
+
+	cifrado, err := utils.Encrypt([]byte(texto))
+	if err != nil {
+		return nil, err
+	}
+	return prefixoCriptografado + cifrado, nil
+}
+
+// IndiceCPF retorna o índice cego usado nas buscas por CPF
+func IndiceCPF(cpf string) string {
+	return utils.IndiceCego(utils.NormalizarCPF(cpf))
+}
+
+// IndiceEmail retorna o índice cego usado nas buscas por e-mail
+func IndiceEmail(email string) string {
+	return utils.IndiceCego(strings.ToLower(strings.TrimSpace(email)))
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para o tipo de documento do cliente
+type TipoDocumento string
+
+const (
+	DocumentoFoto                   TipoDocumento = "foto"
+	DocumentoTermoResponsabilidade  TipoDocumento = "termo_responsabilidade"
+	DocumentoAutorizacaoResponsavel TipoDocumento = "autorizacao_responsavel"
+	DocumentoAtestado               TipoDocumento = "atestado"
+	DocumentoOutro                  TipoDocumento = "outro"
+)
+
+// Documento é um arquivo enviado para o cliente. O conteúdo fica no
+// armazenamento configurado; aqui ficam apenas os metadados.
+type Documento struct {
+	ID          string        `json:"id" gorm:"primaryKey"`
+	ClienteID   string        `json:"cliente_id" gorm:"index;not null"`
+	GuardianID  *string       `json:"guardian_id,omitempty" gorm:"index"` // Responsável que assinou a autorização
+	Tipo        TipoDocumento `json:"tipo" gorm:"index" validate:"required,oneof=foto termo_responsabilidade autorizacao_responsavel atestado outro"`
+	NomeArquivo string        `json:"nome_arquivo"`
+	ContentType string        `json:"content_type"`
+	Tamanho     int64         `json:"tamanho"`
+	SHA256      string        `json:"sha256" gorm:"index"`
+	Chave       string        `json:"-" gorm:"not null"` // Chave do arquivo no armazenamento
+	EnviadoPor  string        `json:"enviado_por"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (d *Documento) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == "" {
+		d.ID, err = gonanoid.New()
+	}
+	return
+}
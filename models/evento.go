@@ -0,0 +1,160 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para o tipo de evento
+type TipoEvento string
+
+const (
+	EventoSeminario  TipoEvento = "seminario"
+	EventoCampeonato TipoEvento = "campeonato"
+	EventoWorkshop   TipoEvento = "workshop"
+	EventoOutro      TipoEvento = "outro"
+)
+
+// Enum para a situação do evento
+type StatusEvento string
+
+const (
+	EventoAberto    StatusEvento = "aberto"
+	EventoEncerrado StatusEvento = "encerrado" // Inscrições fechadas
+	EventoCancelado StatusEvento = "cancelado"
+)
+
+// Evento é um seminário, campeonato ou outra atividade cobrada à parte dos
+// planos. As inscrições são vendidas pelo produto criado junto com o evento.
+type Evento struct {
+	ID            string            `json:"id" gorm:"primaryKey"`
+	Nome          string            `json:"nome" validate:"required,min=3"`
+	Tipo          TipoEvento        `json:"tipo" gorm:"index" validate:"required,oneof=seminario campeonato workshop outro"`
+	Descricao     string            `json:"descricao"`
+	Local         string            `json:"local" validate:"required"`
+	Inicio        time.Time         `json:"inicio" gorm:"not null;index" validate:"required"`
+	Fim           time.Time         `json:"fim" gorm:"not null" validate:"required"`
+	InscricoesAte *time.Time        `json:"inscricoes_ate"`              // Sem data, as inscrições vão até o início
+	Capacidade    int               `json:"capacidade" validate:"gte=0"` // 0 = sem limite
+	ModalidadeID  *string           `json:"modalidade_id" gorm:"index"`  // Usada para conferir a faixa nas categorias
+	Modalidade    *Modalidade       `json:"modalidade,omitempty" gorm:"foreignKey:ModalidadeID;constraint:OnDelete:SET NULL"`
+	ProdutoID     string            `json:"produto_id" gorm:"not null"`
+	Status        StatusEvento      `json:"status" gorm:"index;not null"`
+	Lotes         []LoteEvento      `json:"lotes,omitempty" gorm:"foreignKey:EventoID;constraint:OnDelete:CASCADE"`
+	Categorias    []CategoriaEvento `json:"categorias,omitempty" gorm:"foreignKey:EventoID;constraint:OnDelete:CASCADE"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (e *Evento) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == "" {
+		e.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// LoteEvento é uma faixa de preço da inscrição. Vale o lote mais barato ainda
+// dentro da validade; lotes exclusivos para alunos não valem para externos.
+type LoteEvento struct {
+	ID           string     `json:"id" gorm:"primaryKey"`
+	EventoID     string     `json:"evento_id" gorm:"not null;index"`
+	Nome         string     `json:"nome" validate:"required"`
+	Preco        float64    `json:"preco" validate:"required,gt=0"`
+	ValidoAte    *time.Time `json:"valido_ate"` // Sem data, vale até o fim das inscrições
+	ApenasAlunos bool       `json:"apenas_alunos"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (l *LoteEvento) BeforeCreate(tx *gorm.DB) (err error) {
+	if l.ID == "" {
+		l.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// CategoriaEvento agrupa os participantes de um campeonato por idade, peso,
+// faixa e gênero. Limites nulos não restringem.
+type CategoriaEvento struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	EventoID      string    `json:"evento_id" gorm:"not null;index"`
+	Nome          string    `json:"nome" validate:"required"`
+	Genero        string    `json:"genero" validate:"omitempty,oneof=Masculino Feminino Outro"`
+	IdadeMinima   *int      `json:"idade_minima" validate:"omitempty,gte=0"`
+	IdadeMaxima   *int      `json:"idade_maxima" validate:"omitempty,gte=0"`
+	PesoMinimo    *float64  `json:"peso_minimo" validate:"omitempty,gte=0"` // Em kg
+	PesoMaximo    *float64  `json:"peso_maximo" validate:"omitempty,gt=0"`  // Em kg
+	FaixaMinimaID *string   `json:"faixa_minima_id"`
+	FaixaMinima   *Faixa    `json:"faixa_minima,omitempty" gorm:"foreignKey:FaixaMinimaID;constraint:OnDelete:RESTRICT"`
+	FaixaMaximaID *string   `json:"faixa_maxima_id"`
+	FaixaMaxima   *Faixa    `json:"faixa_maxima,omitempty" gorm:"foreignKey:FaixaMaximaID;constraint:OnDelete:RESTRICT"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (c *CategoriaEvento) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == "" {
+		c.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Enum para a situação da inscrição
+type StatusInscricao string
+
+const (
+	InscricaoConfirmada StatusInscricao = "confirmada"
+	InscricaoCancelada  StatusInscricao = "cancelada"
+)
+
+// InscricaoEvento é a inscrição de um cliente ou de um participante externo.
+// O pagamento é a venda vinculada; a inscrição só é considerada paga quando a
+// venda estiver paga.
+type InscricaoEvento struct {
+	ID             string           `json:"id" gorm:"primaryKey"`
+	EventoID       string           `json:"evento_id" gorm:"not null;index;uniqueIndex:idx_inscricao_cliente,priority:1,where:cliente_id IS NOT NULL AND status <> 'cancelada'"`
+	Evento         *Evento          `json:"evento,omitempty" gorm:"foreignKey:EventoID;constraint:OnDelete:RESTRICT"`
+	CategoriaID    *string          `json:"categoria_id" gorm:"index"`
+	Categoria      *CategoriaEvento `json:"categoria,omitempty" gorm:"foreignKey:CategoriaID;constraint:OnDelete:RESTRICT"`
+	ClienteID      *string          `json:"cliente_id" gorm:"index;uniqueIndex:idx_inscricao_cliente,priority:2"`
+	Cliente        *Cliente         `json:"cliente,omitempty" gorm:"foreignKey:ClienteID;constraint:OnDelete:SET NULL"`
+	Nome           string           `json:"nome"` // Dados do participante; copiados do cliente quando houver
+	Email          string           `json:"email" gorm:"serializer:criptografado;type:text"`
+	Telefone       string           `json:"telefone" gorm:"serializer:criptografado;type:text"`
+	DataNascimento *time.Time       `json:"data_nascimento"`
+	Genero         string           `json:"genero"`
+	Peso           *float64         `json:"peso"`
+	FaixaID        *string          `json:"faixa_id"` // Declarada pelo externo ou a atual do cliente
+	Faixa          *Faixa           `json:"faixa,omitempty" gorm:"foreignKey:FaixaID;constraint:OnDelete:SET NULL"`
+	Academia       string           `json:"academia"`
+	LoteID         *string          `json:"lote_id"`
+	Valor          float64          `json:"valor"`
+	VendaID        *string          `json:"venda_id" gorm:"index"`
+	Venda          *Sale            `json:"venda,omitempty" gorm:"foreignKey:VendaID;constraint:OnDelete:SET NULL"`
+	Status         StatusInscricao  `json:"status" gorm:"index;not null"`
+	CheckinEm      *time.Time       `json:"checkin_em"`
+	Pago           bool             `json:"pago" gorm:"-"` // Preenchido na listagem a partir da venda
+	InscritoPor    string           `json:"inscrito_por"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (i *InscricaoEvento) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == "" {
+		i.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Paga indica se a venda da inscrição já foi quitada. Inscrições sem venda
+// são de eventos gratuitos.
+func (i *InscricaoEvento) Paga() bool {
+	if i.VendaID == nil {
+		return i.Valor == 0
+	}
+	return i.Venda != nil && (i.Venda.Pago || i.Venda.Status == Paid)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Familia agrupa clientes que treinam e pagam juntos
+type Familia struct {
+	ID                      string    `json:"id" gorm:"primaryKey"`
+	Nome                    string    `json:"nome" validate:"required,min=3"`
+	ResponsavelFinanceiroID *string   `json:"responsavel_financeiro_id" gorm:"index"`
+	ResponsavelFinanceiro   *Cliente  `json:"responsavel_financeiro,omitempty" gorm:"foreignKey:ResponsavelFinanceiroID;constraint:OnDelete:SET NULL"`
+	DiaCobranca             *int      `json:"dia_cobranca" validate:"omitempty,min=1,max=31"` // Dia de cobrança unificado das assinaturas
+	Membros                 []Cliente `json:"membros,omitempty" gorm:"foreignKey:FamiliaID;constraint:OnDelete:SET NULL"`
+	CreatedAt               time.Time `json:"created_at"`
+	UpdatedAt               time.Time `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (f *Familia) BeforeCreate(tx *gorm.DB) (err error) {
+	if f.ID == "" {
+		f.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// RegraDescontoFamilia define o desconto aplicado à assinatura do N-ésimo
+// membro da família com assinatura ativa (ex.: 10% a partir do segundo)
+type RegraDescontoFamilia struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	APartirDe  int       `json:"a_partir_de" gorm:"uniqueIndex" validate:"required,min=2"`
+	Percentual float64   `json:"percentual" validate:"required,gt=0,lte=100"`
+	Ativa      bool      `json:"ativa" gorm:"default:true"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (r *RegraDescontoFamilia) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == "" {
+		r.ID, err = gonanoid.New()
+	}
+	return
+}
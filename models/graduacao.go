@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Faixa é um nível do sistema de graduação de uma modalidade. Os requisitos
+// valem para sair desta faixa: para a próxima faixa (tempo e presenças desde a
+// entrada na faixa) e para cada grau (tempo e presenças desde a última promoção).
+type Faixa struct {
+	ID                   string      `json:"id" gorm:"primaryKey"`
+	ModalidadeID         string      `json:"modalidade_id" gorm:"not null;uniqueIndex:idx_faixa_ordem,priority:1" validate:"required"`
+	Modalidade           *Modalidade `json:"modalidade,omitempty" gorm:"foreignKey:ModalidadeID;constraint:OnDelete:CASCADE"`
+	Nome                 string      `json:"nome" validate:"required"`
+	Cor                  string      `json:"cor" validate:"omitempty,hexcolor"`
+	Ordem                int         `json:"ordem" gorm:"not null;uniqueIndex:idx_faixa_ordem,priority:2" validate:"gte=0"`
+	GrausMaximos         int         `json:"graus_maximos" validate:"gte=0,lte=10"`
+	TempoMinimoMeses     int         `json:"tempo_minimo_meses" validate:"gte=0"`
+	PresencasMinimas     int         `json:"presencas_minimas" validate:"gte=0"`
+	TempoMinimoGrauMeses int         `json:"tempo_minimo_grau_meses" validate:"gte=0"`
+	PresencasMinimasGrau int         `json:"presencas_minimas_grau" validate:"gte=0"`
+	IdadeMinima          int         `json:"idade_minima" validate:"gte=0"`
+	CreatedAt            time.Time   `json:"created_at"`
+	UpdatedAt            time.Time   `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (f *Faixa) BeforeCreate(tx *gorm.DB) (err error) {
+	if f.ID == "" {
+		f.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Graduacao registra uma promoção do cliente; a mais recente de cada
+// modalidade é a graduação atual
+type Graduacao struct {
+	ID            string      `json:"id" gorm:"primaryKey"`
+	ClienteID     string      `json:"cliente_id" gorm:"not null;index"`
+	Cliente       *Cliente    `json:"cliente,omitempty" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	ModalidadeID  string      `json:"modalidade_id" gorm:"not null;index"`
+	Modalidade    *Modalidade `json:"modalidade,omitempty" gorm:"foreignKey:ModalidadeID;constraint:OnDelete:RESTRICT"`
+	FaixaID       string      `json:"faixa_id" gorm:"not null"`
+	Faixa         *Faixa      `json:"faixa,omitempty" gorm:"foreignKey:FaixaID;constraint:OnDelete:RESTRICT"`
+	Grau          int         `json:"grau"`
+	Data          time.Time   `json:"data" gorm:"type:date;not null"`
+	InstrutorID   *string     `json:"instrutor_id"` // Quem promoveu
+	Instrutor     *User       `json:"instrutor,omitempty" gorm:"foreignKey:InstrutorID;constraint:OnDelete:SET NULL"`
+	ExameID       *string     `json:"exame_id,omitempty" gorm:"index"`
+	Observacao    string      `json:"observacao"`
+	RegistradoPor string      `json:"registrado_por"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (g *Graduacao) BeforeCreate(tx *gorm.DB) (err error) {
+	if g.ID == "" {
+		g.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Enum para a situação do exame de graduação
+type StatusExame string
+
+const (
+	ExameAgendado  StatusExame = "agendado"
+	ExameRealizado StatusExame = "realizado"
+	ExameCancelado StatusExame = "cancelado"
+)
+
+// ExameGraduacao é um evento de exame de faixa de uma modalidade
+type ExameGraduacao struct {
+	ID           string           `json:"id" gorm:"primaryKey"`
+	ModalidadeID string           `json:"modalidade_id" gorm:"not null;index" validate:"required"`
+	Modalidade   *Modalidade      `json:"modalidade,omitempty" gorm:"foreignKey:ModalidadeID;constraint:OnDelete:RESTRICT"`
+	Data         time.Time        `json:"data" gorm:"not null" validate:"required"`
+	Local        string           `json:"local"`
+	Descricao    string           `json:"descricao"`
+	AvaliadorID  *string          `json:"avaliador_id"`
+	Avaliador    *User            `json:"avaliador,omitempty" gorm:"foreignKey:AvaliadorID;constraint:OnDelete:SET NULL"`
+	Status       StatusExame      `json:"status" gorm:"default:'agendado'"`
+	Candidatos   []CandidatoExame `json:"candidatos,omitempty" gorm:"foreignKey:ExameID;constraint:OnDelete:CASCADE"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (e *ExameGraduacao) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == "" {
+		e.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Enum para o resultado do candidato no exame
+type ResultadoExame string
+
+const (
+	ResultadoPendente  ResultadoExame = "pendente"
+	ResultadoAprovado  ResultadoExame = "aprovado"
+	ResultadoReprovado ResultadoExame = "reprovado"
+	ResultadoAusente   ResultadoExame = "ausente"
+)
+
+// CandidatoExame é um cliente inscrito no exame com a graduação pretendida
+type CandidatoExame struct {
+	ID                    string         `json:"id" gorm:"primaryKey"`
+	ExameID               string         `json:"exame_id" gorm:"not null;uniqueIndex:idx_candidato_exame,priority:1"`
+	ClienteID             string         `json:"cliente_id" gorm:"not null;uniqueIndex:idx_candidato_exame,priority:2"`
+	Cliente               *Cliente       `json:"cliente,omitempty" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	FaixaAtualID          *string        `json:"faixa_atual_id"`
+	GrauAtual             int            `json:"grau_atual"`
+	FaixaPretendidaID     string         `json:"faixa_pretendida_id" gorm:"not null"`
+	FaixaPretendida       *Faixa         `json:"faixa_pretendida,omitempty" gorm:"foreignKey:FaixaPretendidaID;constraint:OnDelete:RESTRICT"`
+	GrauPretendido        int            `json:"grau_pretendido"`
+	Resultado             ResultadoExame `json:"resultado" gorm:"default:'pendente'"`
+	Observacao            string         `json:"observacao"`
+	RequisitosDispensados bool           `json:"requisitos_dispensados"` // Inscrito sem atender aos requisitos, por decisão do administrador
+	GraduacaoID           *string        `json:"graduacao_id,omitempty"` // Promoção gerada pela aprovação
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (c *CandidatoExame) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == "" {
+		c.ID, err = gonanoid.New()
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para o grau de parentesco do responsável
+type Parentesco string
+
+const (
+	ParentescoPai        Parentesco = "pai"
+	ParentescoMae        Parentesco = "mae"
+	ParentescoAvo        Parentesco = "avo"
+	ParentescoTio        Parentesco = "tio"
+	ParentescoIrmao      Parentesco = "irmao"
+	ParentescoTutorLegal Parentesco = "tutor_legal"
+	ParentescoOutro      Parentesco = "outro"
+)
+
+// Guardian representa um responsável legal por um cliente.
+// Um cliente pode ter vários responsáveis, que não precisam ser os pais.
+type Guardian struct {
+	ID                    string     `json:"id" gorm:"primaryKey"`
+	ClienteID             string     `json:"cliente_id" gorm:"index;not null"`
+	Nome                  string     `json:"nome" validate:"required,min=3"`
+	Parentesco            Parentesco `json:"parentesco" validate:"required,oneof=pai mae avo tio irmao tutor_legal outro"`
+	Telefone              string     `json:"telefone" gorm:"serializer:criptografado;type:text" validate:"required"`
+	Email                 string     `json:"email" gorm:"serializer:criptografado;type:text" validate:"omitempty,email"`
+	CPF                   string     `json:"cpf" gorm:"serializer:criptografado;type:text" validate:"required,cpf"`
+	CPFHash               string     `json:"-" gorm:"index"` // Índice cego do CPF
+	ResponsavelFinanceiro bool       `json:"responsavel_financeiro"`
+	AutorizadoBuscar      bool       `json:"autorizado_buscar"`
+	ArquivadoEm           *time.Time `json:"arquivado_em,omitempty" gorm:"index"` // Preenchido quando o cliente atinge a maioridade
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// Atualiza o índice cego antes de gravar os campos criptografados
+func (g *Guardian) BeforeSave(tx *gorm.DB) (err error) {
+	g.CPFHash = IndiceCPF(g.CPF)
+	return
+}
+
+// Gerar ID automaticamente com nanoid
+func (g *Guardian) BeforeCreate(tx *gorm.DB) (err error) {
+	if g.ID == "" {
+		g.ID, err = gonanoid.New()
+	}
+	return
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// PeriodoInadimplencia registra o intervalo em que um cliente ficou inadimplente.
+// Fim nulo indica que o período ainda está em aberto.
+type PeriodoInadimplencia struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	ClienteID     string     `json:"cliente_id" gorm:"index;not null"`
+	Cliente       Cliente    `json:"-" gorm:"foreignKey:ClienteID;references:ID;constraint:OnDelete:CASCADE"`
+	Inicio        time.Time  `json:"inicio"`
+	Fim           *time.Time `json:"fim"`
+	ValorEmAberto float64    `json:"valor_em_aberto"` // Maior valor em aberto registrado durante o período
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (p *PeriodoInadimplencia) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == "" {
+		p.ID, err = gonanoid.New()
+	}
+	return
+}
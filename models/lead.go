@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para o canal pelo qual o interessado chegou
+type OrigemLead string
+
+const (
+	OrigemInstagram OrigemLead = "instagram"
+	OrigemFacebook  OrigemLead = "facebook"
+	OrigemGoogle    OrigemLead = "google"
+	OrigemSite      OrigemLead = "site"
+	OrigemWhatsApp  OrigemLead = "whatsapp"
+	OrigemIndicacao OrigemLead = "indicacao"
+	OrigemPassante  OrigemLead = "passante"
+	OrigemOutro     OrigemLead = "outro"
+)
+
+// Enum para a etapa do funil de vendas
+type StatusLead string
+
+const (
+	LeadNovo         StatusLead = "novo"
+	LeadAulaAgendada StatusLead = "aula_agendada"
+	LeadCompareceu   StatusLead = "compareceu"
+	LeadConvertido   StatusLead = "convertido"
+	LeadPerdido      StatusLead = "perdido"
+)
+
+// Lead é um interessado que ainda não é cliente, acompanhado até a matrícula
+type Lead struct {
+	ID                 string             `json:"id" gorm:"primaryKey"`
+	Nome               string             `json:"nome" validate:"required,min=3"`
+	Email              string             `json:"email" gorm:"serializer:criptografado;type:text" validate:"omitempty,email"`
+	Telefone           string             `json:"telefone" gorm:"serializer:criptografado;type:text" validate:"required"`
+	DataNascimento     *time.Time         `json:"data_nascimento"`
+	Origem             OrigemLead         `json:"origem" gorm:"index" validate:"required,oneof=instagram facebook google site whatsapp indicacao passante outro"`
+	Interesse          string             `json:"interesse"`                  // Descrição livre do que o interessado procura
+	ModalidadeID       *string            `json:"modalidade_id" gorm:"index"` // Modalidade de interesse
+	Modalidade         *Modalidade        `json:"modalidade,omitempty" gorm:"foreignKey:ModalidadeID;constraint:OnDelete:SET NULL"`
+	PlanoID            *string            `json:"plano_id"` // Plano de interesse, sugerido na conversão
+	Status             StatusLead         `json:"status" gorm:"index;not null"`
+	MotivoPerda        string             `json:"motivo_perda,omitempty"`
+	ResponsavelID      *string            `json:"responsavel_id" gorm:"index"` // Usuário que acompanha o lead
+	Responsavel        *User              `json:"responsavel,omitempty" gorm:"foreignKey:ResponsavelID;constraint:OnDelete:SET NULL"`
+	ProximoContatoEm   *time.Time         `json:"proximo_contato_em" gorm:"type:date;index"`
+	Observacoes        string             `json:"observacoes"`
+	ClienteID          *string            `json:"cliente_id" gorm:"index"` // Preenchido na conversão
+	Cliente            *Cliente           `json:"cliente,omitempty" gorm:"foreignKey:ClienteID;constraint:OnDelete:SET NULL"`
+	ConvertidoEm       *time.Time         `json:"convertido_em"`
+	AulasExperimentais []AulaExperimental `json:"aulas_experimentais,omitempty" gorm:"foreignKey:LeadID;constraint:OnDelete:CASCADE"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (l *Lead) BeforeCreate(tx *gorm.DB) (err error) {
+	if l.ID == "" {
+		l.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Encerrado indica se o lead já saiu do funil
+func (l *Lead) Encerrado() bool {
+	return l.Status == LeadConvertido || l.Status == LeadPerdido
+}
+
+// Enum para a situação da aula experimental
+type StatusAulaExperimental string
+
+const (
+	ExperimentalAgendada  StatusAulaExperimental = "agendada"
+	ExperimentalRealizada StatusAulaExperimental = "compareceu"
+	ExperimentalFalta     StatusAulaExperimental = "faltou"
+	ExperimentalCancelada StatusAulaExperimental = "cancelada"
+)
+
+// AulaExperimental é a aula de um lead em uma data da grade
+type AulaExperimental struct {
+	ID                string                 `json:"id" gorm:"primaryKey"`
+	LeadID            string                 `json:"lead_id" gorm:"not null;index"`
+	AulaID            string                 `json:"aula_id" gorm:"not null;index"`
+	Aula              *Aula                  `json:"aula,omitempty" gorm:"foreignKey:AulaID;constraint:OnDelete:RESTRICT"`
+	Data              time.Time              `json:"data" gorm:"type:date;not null;index"`
+	Status            StatusAulaExperimental `json:"status" gorm:"not null"`
+	LembreteEnviadoEm *time.Time             `json:"lembrete_enviado_em"`
+	AgendadaPor       string                 `json:"agendada_por"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (a *AulaExperimental) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == "" {
+		a.ID, err = gonanoid.New()
+	}
+	return
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para a situação da matrícula em uma aula
+type StatusMatricula string
+
+const (
+	MatriculaAtiva     StatusMatricula = "ativa"
+	MatriculaEspera    StatusMatricula = "espera"
+	MatriculaCancelada StatusMatricula = "cancelada"
+)
+
+// Matricula vincula o cliente a uma aula da grade. Quando a aula está lotada
+// a matrícula entra na lista de espera, ordenada pela data de inscrição.
+type Matricula struct {
+	ID                 string          `json:"id" gorm:"primaryKey"`
+	ClienteID          string          `json:"cliente_id" gorm:"not null;index;uniqueIndex:idx_matricula_vigente,priority:1,where:status <> 'cancelada'"`
+	Cliente            *Cliente        `json:"cliente,omitempty" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	AulaID             string          `json:"aula_id" gorm:"not null;index;uniqueIndex:idx_matricula_vigente,priority:2"`
+	Aula               *Aula           `json:"aula,omitempty" gorm:"foreignKey:AulaID;constraint:OnDelete:RESTRICT"`
+	Status             StatusMatricula `json:"status" gorm:"not null;index"`
+	PosicaoEspera      int             `json:"posicao_espera,omitempty" gorm:"-"`
+	Pendencias         []string        `json:"pendencias,omitempty" gorm:"serializer:json;type:jsonb"` // Registradas quando a matrícula é forçada
+	PromovidaEm        *time.Time      `json:"promovida_em"`
+	CanceladaEm        *time.Time      `json:"cancelada_em"`
+	MotivoCancelamento string          `json:"motivo_cancelamento,omitempty"`
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (m *Matricula) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == "" {
+		m.ID, err = gonanoid.New()
+	}
+	return
+}
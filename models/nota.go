@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para a visibilidade das notas
+type VisibilidadeNota string
+
+const (
+	VisibilidadeEquipe        VisibilidadeNota = "equipe"        // Todos os usuários com acesso ao cliente
+	VisibilidadePrivada       VisibilidadeNota = "privada"       // Apenas o autor
+	VisibilidadeAdministracao VisibilidadeNota = "administracao" // Apenas superadmins
+)
+
+// Nota é uma anotação livre da equipe sobre um cliente
+type Nota struct {
+	ID           string           `json:"id" gorm:"primaryKey"`
+	ClienteID    string           `json:"cliente_id" gorm:"index;not null"`
+	Autor        string           `json:"autor"`
+	Texto        string           `json:"texto" validate:"required"`
+	Visibilidade VisibilidadeNota `json:"visibilidade" gorm:"default:'equipe'" validate:"omitempty,oneof=equipe privada administracao"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// Tag é um rótulo definido pelos usuários para agrupar clientes
+type Tag struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Nome      string    `json:"nome" gorm:"uniqueIndex;not null" validate:"required,max=50"`
+	Cor       string    `json:"cor" validate:"omitempty,hexcolor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VisivelPara indica se a nota pode ser lida pelo usuário
+func (n *Nota) VisivelPara(email, role string) bool {
+	switch n.Visibilidade {
+	case VisibilidadePrivada:
+		return n.Autor == email
+	case VisibilidadeAdministracao:
+		return role == "superadmin"
+	}
+	return true
+}
+
+// Gerar ID automaticamente com nanoid
+func (n *Nota) BeforeCreate(tx *gorm.DB) (err error) {
+	if n.ID == "" {
+		n.ID, err = gonanoid.New()
+	}
+	if n.Visibilidade == "" {
+		n.Visibilidade = VisibilidadeEquipe
+	}
+	return
+}
+
+// Gerar ID automaticamente com nanoid
+func (t *Tag) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == "" {
+		t.ID, err = gonanoid.New()
+	}
+	return
+}
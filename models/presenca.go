@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para o meio de identificação usado no check-in
+type MetodoCheckin string
+
+const (
+	CheckinPorID  MetodoCheckin = "id"
+	CheckinPorCPF MetodoCheckin = "cpf"
+	CheckinPorQR  MetodoCheckin = "qr"
+)
+
+// Presenca registra o check-in de um cliente em uma aula em uma data.
+// Check-ins aceitos com pendências (assinatura em atraso, plano sem a
+// modalidade...) ficam marcados como irregulares para conferência.
+type Presenca struct {
+	ID            string        `json:"id" gorm:"primaryKey"`
+	ClienteID     string        `json:"cliente_id" gorm:"not null;uniqueIndex:idx_presenca_aula_dia,priority:1"`
+	Cliente       *Cliente      `json:"cliente,omitempty" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	AulaID        string        `json:"aula_id" gorm:"not null;index;uniqueIndex:idx_presenca_aula_dia,priority:2"`
+	Aula          *Aula         `json:"aula,omitempty" gorm:"foreignKey:AulaID;constraint:OnDelete:RESTRICT"`
+	Data          time.Time     `json:"data" gorm:"type:date;not null;index;uniqueIndex:idx_presenca_aula_dia,priority:3"`
+	InstrutorID   *string       `json:"instrutor_id" gorm:"index"` // Instrutor da aula no dia do check-in
+	Metodo        MetodoCheckin `json:"metodo"`
+	Irregular     bool          `json:"irregular" gorm:"index"`
+	Pendencias    []string      `json:"pendencias,omitempty" gorm:"serializer:json;type:jsonb"`
+	RegistradoPor string        `json:"registrado_por"`
+	CheckinEm     time.Time     `json:"checkin_em"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (p *Presenca) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == "" {
+		p.ID, err = gonanoid.New()
+	}
+	if p.CheckinEm.IsZero() {
+		p.CheckinEm = time.Now()
+	}
+	return
+}
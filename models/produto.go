@@ -13,6 +13,7 @@ type TipoProduto string
 const (
 	Fisico   TipoProduto = "fisico"
 	Servico   TipoProduto = "servico"
+	Inscricao TipoProduto = "inscricao" // Criado automaticamente para cada evento
 )
 
 // Produto é a estrutura base para todos os tipos de produtos
@@ -43,6 +44,8 @@ type ProdutoServico struct {
 	DuracaoMeses int       `json:"duracao_meses" validate:"required,gt=0"`
 	Recorrente   bool      `json:"recorrente"`
 	Beneficios   string    `json:"beneficios"`
+	MaxDiasCongelamento int `json:"max_dias_congelamento" validate:"gte=0"` // Dias de congelamento permitidos a cada 12 meses; 0 não permite
+	Modalidades  []Modalidade `json:"modalidades,omitempty" gorm:"many2many:plano_modalidades;joinForeignKey:ProdutoID;constraint:OnDelete:CASCADE"` // Sem modalidades o plano dá acesso a todas
 }
 
 // BeforeCreate será chamado antes de criar um novo produto
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para a forma de cálculo de uma regra de remuneração
+type TipoRegraRemuneracao string
+
+const (
+	RemuneracaoPorHora  TipoRegraRemuneracao = "hora"     // Valor por hora de aula ministrada
+	RemuneracaoPorAula  TipoRegraRemuneracao = "aula"     // Valor fixo por aula ministrada
+	RemuneracaoPorAluno TipoRegraRemuneracao = "aluno"    // Valor por check-in nas aulas ministradas
+	RemuneracaoComissao TipoRegraRemuneracao = "comissao" // Percentual sobre vendas e planos vendidos
+)
+
+// Enum para o andamento do extrato mensal
+type StatusExtrato string
+
+const (
+	ExtratoRascunho StatusExtrato = "rascunho"
+	ExtratoAprovado StatusExtrato = "aprovado"
+	ExtratoPago     StatusExtrato = "pago"
+)
+
+// RegraRemuneracao define quanto um instrutor recebe. Regras de aula podem
+// ser restritas a uma modalidade, que tem preferência sobre a regra geral do
+// mesmo tipo.
+type RegraRemuneracao struct {
+	ID           string               `json:"id" gorm:"primaryKey"`
+	InstrutorID  string               `json:"instrutor_id" gorm:"not null;index" validate:"required"`
+	Instrutor    *User                `json:"instrutor,omitempty" gorm:"foreignKey:InstrutorID;constraint:OnDelete:CASCADE"`
+	Tipo         TipoRegraRemuneracao `json:"tipo" gorm:"not null" validate:"required,oneof=hora aula aluno comissao"`
+	Valor        float64              `json:"valor" validate:"required,gt=0"` // Em reais, ou percentual na comissão
+	ModalidadeID *string              `json:"modalidade_id" gorm:"index"`
+	Modalidade   *Modalidade          `json:"modalidade,omitempty" gorm:"foreignKey:ModalidadeID;constraint:OnDelete:CASCADE"`
+	VigenteDesde time.Time            `json:"vigente_desde" gorm:"type:date;not null" validate:"required"`
+	VigenteAte   *time.Time           `json:"vigente_ate" gorm:"type:date"` // Sem data, vale por tempo indeterminado
+	Descricao    string               `json:"descricao"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+}
+
+// Vigente indica se a regra vale na data informada
+func (r *RegraRemuneracao) Vigente(dia time.Time) bool {
+	data := dia.Format("2006-01-02")
+	if data < r.VigenteDesde.Format("2006-01-02") {
+		return false
+	}
+	return r.VigenteAte == nil || data <= r.VigenteAte.Format("2006-01-02")
+}
+
+// AulaMinistrada registra que uma aula da grade aconteceu em uma data e quem
+// a deu. É criada no primeiro check-in do dia ou lançada manualmente, o que
+// também permite registrar substituições.
+type AulaMinistrada struct {
+	ID             string    `json:"id" gorm:"primaryKey"`
+	AulaID         string    `json:"aula_id" gorm:"not null;uniqueIndex:idx_aula_ministrada_dia,priority:1"`
+	Aula           *Aula     `json:"aula,omitempty" gorm:"foreignKey:AulaID;constraint:OnDelete:RESTRICT"`
+	Data           time.Time `json:"data" gorm:"type:date;not null;index;uniqueIndex:idx_aula_ministrada_dia,priority:2"`
+	InstrutorID    *string   `json:"instrutor_id" gorm:"index"`
+	Instrutor      *User     `json:"instrutor,omitempty" gorm:"foreignKey:InstrutorID;constraint:OnDelete:SET NULL"`
+	DuracaoMinutos int       `json:"duracao_minutos"`
+	Alunos         int       `json:"alunos" gorm:"-"` // Check-ins da data, preenchido nas consultas
+	Observacao     string    `json:"observacao"`
+	RegistradoPor  string    `json:"registrado_por"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ExtratoRemuneracao é o fechamento mensal de um instrutor. Só o rascunho
+// pode ser recalculado ou ajustado; o aprovado segue para a folha.
+type ExtratoRemuneracao struct {
+	ID               string        `json:"id" gorm:"primaryKey"`
+	InstrutorID      string        `json:"instrutor_id" gorm:"not null;uniqueIndex:idx_extrato_competencia,priority:1"`
+	Instrutor        *User         `json:"instrutor,omitempty" gorm:"foreignKey:InstrutorID;constraint:OnDelete:RESTRICT"`
+	Competencia      string        `json:"competencia" gorm:"size:7;not null;index;uniqueIndex:idx_extrato_competencia,priority:2"` // AAAA-MM
+	Status           StatusExtrato `json:"status" gorm:"index;not null"`
+	Aulas            int           `json:"aulas"`
+	Horas            float64       `json:"horas"`
+	Alunos           int           `json:"alunos"`
+	ValorAulas       float64       `json:"valor_aulas"` // Soma das regras por hora, aula e aluno
+	ValorComissoes   float64       `json:"valor_comissoes"`
+	Ajuste           float64       `json:"ajuste"` // Bônus ou desconto lançado manualmente
+	ObservacaoAjuste string        `json:"observacao_ajuste"`
+	Total            float64       `json:"total"`
+	Itens            []ItemExtrato `json:"itens,omitempty" gorm:"foreignKey:ExtratoID;constraint:OnDelete:CASCADE"`
+	CalculadoEm      time.Time     `json:"calculado_em"`
+	AprovadoPor      string        `json:"aprovado_por,omitempty"`
+	AprovadoEm       *time.Time    `json:"aprovado_em"`
+	PagoEm           *time.Time    `json:"pago_em"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+}
+
+// ItemExtrato é uma linha do extrato: uma aula ministrada sob uma regra ou
+// uma venda comissionada
+type ItemExtrato struct {
+	ID         string               `json:"id" gorm:"primaryKey"`
+	ExtratoID  string               `json:"extrato_id" gorm:"not null;index"`
+	RegraID    *string              `json:"regra_id"`
+	Tipo       TipoRegraRemuneracao `json:"tipo"`
+	Referencia string               `json:"referencia"` // ID da aula ministrada, venda ou assinatura
+	Data       time.Time            `json:"data" gorm:"type:date"`
+	Descricao  string               `json:"descricao"`
+	Quantidade float64              `json:"quantidade"` // Horas, aulas, alunos ou 1 na comissão
+	Base       float64              `json:"base"`       // Valor unitário da regra ou valor comissionado
+	Valor      float64              `json:"valor"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (r *RegraRemuneracao) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == "" {
+		r.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Gerar ID automaticamente com nanoid
+func (a *AulaMinistrada) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == "" {
+		a.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Gerar ID automaticamente com nanoid
+func (e *ExtratoRemuneracao) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == "" {
+		e.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Gerar ID automaticamente com nanoid
+func (i *ItemExtrato) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == "" {
+		i.ID, err = gonanoid.New()
+	}
+	return
+}
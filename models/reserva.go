@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// Enum para o tipo de recurso reservável
+type TipoRecurso string
+
+const (
+	RecursoSala        TipoRecurso = "sala"
+	RecursoTatame      TipoRecurso = "tatame"
+	RecursoEquipamento TipoRecurso = "equipamento"
+)
+
+// Enum para a situação da reserva
+type StatusReserva string
+
+const (
+	ReservaConfirmada StatusReserva = "confirmada"
+	ReservaCancelada  StatusReserva = "cancelada"
+)
+
+// Recurso é um espaço ou equipamento que pode ser reservado fora da grade de
+// aulas, como a sala de aulas particulares ou a área de tatame. Uma sala com o
+// mesmo nome usado nas aulas também fica ocupada nos horários da grade.
+type Recurso struct {
+	ID              string                  `json:"id" gorm:"primaryKey"`
+	Nome            string                  `json:"nome" gorm:"uniqueIndex" validate:"required,min=2"`
+	Tipo            TipoRecurso             `json:"tipo" gorm:"index;not null" validate:"required,oneof=sala tatame equipamento"`
+	Descricao       string                  `json:"descricao"`
+	ProdutoID       *string                 `json:"produto_id" gorm:"index"` // Serviço (ProdutoServico) que define o preço; sem ele a reserva é gratuita
+	CobrancaPorHora bool                    `json:"cobranca_por_hora"`       // O preço do serviço é por hora em vez de por reserva
+	Ativo           bool                    `json:"ativo" gorm:"default:true"`
+	Janelas         []JanelaDisponibilidade `json:"janelas,omitempty" gorm:"foreignKey:RecursoID;constraint:OnDelete:CASCADE"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+}
+
+// JanelaDisponibilidade é um horário semanal em que o recurso pode ser reservado
+type JanelaDisponibilidade struct {
+	ID         string `json:"id" gorm:"primaryKey"`
+	RecursoID  string `json:"recurso_id" gorm:"not null;index"`
+	DiaSemana  int    `json:"dia_semana" validate:"min=0,max=6"` // 0 = domingo
+	HoraInicio string `json:"hora_inicio" validate:"required,datetime=15:04"`
+	HoraFim    string `json:"hora_fim" validate:"required,datetime=15:04"`
+}
+
+// Reserva ocupa um recurso em um intervalo para um cliente, opcionalmente com
+// um instrutor. A restrição de exclusão criada em InitDB impede reservas
+// sobrepostas do mesmo recurso ou do mesmo instrutor.
+type Reserva struct {
+	ID                 string        `json:"id" gorm:"primaryKey"`
+	RecursoID          string        `json:"recurso_id" gorm:"not null;index" validate:"required"`
+	Recurso            *Recurso      `json:"recurso,omitempty" gorm:"foreignKey:RecursoID;constraint:OnDelete:RESTRICT"`
+	ClienteID          string        `json:"cliente_id" gorm:"not null;index" validate:"required"`
+	Cliente            *Cliente      `json:"cliente,omitempty" gorm:"foreignKey:ClienteID;constraint:OnDelete:CASCADE"`
+	InstrutorID        *string       `json:"instrutor_id" gorm:"index"`
+	Instrutor          *User         `json:"instrutor,omitempty" gorm:"foreignKey:InstrutorID;constraint:OnDelete:SET NULL"`
+	Inicio             time.Time     `json:"inicio" gorm:"not null;index" validate:"required"`
+	Fim                time.Time     `json:"fim" gorm:"not null" validate:"required"`
+	Status             StatusReserva `json:"status" gorm:"index;not null"`
+	Valor              float64       `json:"valor"`
+	VendaID            *string       `json:"venda_id" gorm:"index"`
+	Venda              *Sale         `json:"venda,omitempty" gorm:"foreignKey:VendaID;constraint:OnDelete:SET NULL"`
+	Observacao         string        `json:"observacao"`
+	CriadaPor          string        `json:"criada_por"`
+	CanceladaEm        *time.Time    `json:"cancelada_em"`
+	MotivoCancelamento string        `json:"motivo_cancelamento,omitempty"`
+	CreatedAt          time.Time     `json:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (r *Recurso) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == "" {
+		r.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Gerar ID automaticamente com nanoid
+func (j *JanelaDisponibilidade) BeforeCreate(tx *gorm.DB) (err error) {
+	if j.ID == "" {
+		j.ID, err = gonanoid.New()
+	}
+	return
+}
+
+// Gerar ID automaticamente com nanoid
+func (r *Reserva) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == "" {
+		r.ID, err = gonanoid.New()
+	}
+	return
+}
@@ -16,11 +16,14 @@ type Sale struct {
 	Custo           float64        `json:"custo" validate:"required,gte=0"`
 	LucroLiquido    float64        `json:"lucro_liquido"`
 	Pago            bool           `json:"pago" gorm:"default:false"`
-	ClienteID       string         `json:"cliente_id" validate:"required"`
-	Cliente         Cliente        `json:"cliente" gorm:"foreignKey:ClienteID;references:ID"`
+	ClienteID       *string        `json:"cliente_id" gorm:"index"` // Nulo apenas em inscrições de participantes externos
+	Cliente         *Cliente       `json:"cliente,omitempty" gorm:"foreignKey:ClienteID;references:ID"`
+	VendedorID      *string        `json:"vendedor_id" gorm:"index"` // Usuário que recebe comissão pela venda
+	Vendedor        *User          `json:"vendedor,omitempty" gorm:"foreignKey:VendedorID;constraint:OnDelete:SET NULL"`
 	Quantidade      int            `json:"quantidade" validate:"required,gt=0"`
 	FormaPagamento  PaymentMethod  `json:"forma_pagamento" validate:"required,oneof=boleto pix debit_card credit_card"`
 	Status          PaymentStatus  `json:"status" gorm:"default:'pending'"`
+	Vencimento      *time.Time     `json:"vencimento,omitempty" gorm:"index"`
 	CriadoEm        time.Time      `json:"criado_em"`
 	AtualizadoEm    time.Time     `json:"atualizado_em"`
 }
@@ -41,6 +44,12 @@ func (s *Sale) BeforeCreate(tx *gorm.DB) error {
 	
 	s.CriadoEm = time.Now()
 	s.AtualizadoEm = time.Now()
+
+	// Sem vencimento informado a venda vence na data da compra
+	if s.Vencimento == nil {
+		vencimento := s.CriadoEm
+		s.Vencimento = &vencimento
+	}
 	return nil
 }
 
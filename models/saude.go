@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// PerfilSaude guarda as informações médicas do cliente.
+// Os campos sensíveis são criptografados no banco e a leitura é restrita.
+type PerfilSaude struct {
+	ClienteID         string     `json:"cliente_id" gorm:"primaryKey"`
+	TipoSanguineo     string     `json:"tipo_sanguineo" gorm:"serializer:criptografado;type:text" validate:"omitempty,oneof=A+ A- B+ B- AB+ AB- O+ O-"`
+	Alergias          string     `json:"alergias" gorm:"serializer:criptografado;type:text"`
+	CondicoesMedicas  string     `json:"condicoes_medicas" gorm:"serializer:criptografado;type:text"`
+	Medicamentos      string     `json:"medicamentos" gorm:"serializer:criptografado;type:text"`
+	Observacoes       string     `json:"observacoes" gorm:"serializer:criptografado;type:text"`
+	AtestadoEmitidoEm *time.Time `json:"atestado_emitido_em"`
+	AtestadoValidoAte *time.Time `json:"atestado_valido_ate" gorm:"index"`
+	AtualizadoPor     string     `json:"atualizado_por"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// AtestadoVencido indica se o atestado médico está ausente ou vencido na data de referência
+func (p *PerfilSaude) AtestadoVencido(ref time.Time) bool {
+	return p.AtestadoValidoAte == nil || p.AtestadoValidoAte.Before(ref)
+}
+
+// ContatoEmergencia é uma pessoa a ser avisada em caso de emergência com o cliente
+type ContatoEmergencia struct {
+	ID                  string    `json:"id" gorm:"primaryKey"`
+	ClienteID           string    `json:"cliente_id" gorm:"index;not null"`
+	Nome                string    `json:"nome" validate:"required,min=3"`
+	Parentesco          string    `json:"parentesco" validate:"required"`
+	Telefone            string    `json:"telefone" gorm:"serializer:criptografado;type:text" validate:"required"`
+	TelefoneAlternativo string    `json:"telefone_alternativo" gorm:"serializer:criptografado;type:text"`
+	Prioridade          int       `json:"prioridade" gorm:"default:1" validate:"gte=0"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// Gerar ID automaticamente com nanoid
+func (c *ContatoEmergencia) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == "" {
+		c.ID, err = gonanoid.New()
+	}
+	return
+}
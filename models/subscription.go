@@ -32,13 +32,19 @@ type Subscription struct {
 	ID              string        `json:"id" gorm:"primaryKey"`
 	ClienteID       string        `json:"cliente_id" validate:"required"`
 	Cliente         Cliente       `json:"cliente" gorm:"foreignKey:ClienteID;references:ID"`
+	ProdutoID       *string       `json:"produto_id" gorm:"index"` // Plano (ProdutoServico) contratado
+	VendedorID      *string       `json:"vendedor_id" gorm:"index"` // Usuário que recebe comissão pelo plano
+	Vendedor        *User         `json:"vendedor,omitempty" gorm:"foreignKey:VendedorID;constraint:OnDelete:SET NULL"`
 	PaymentMethod   PaymentMethod `json:"payment_method" validate:"required,oneof=boleto pix debit_card credit_card"`
 	CardNumber      *string       `json:"card_number,omitempty" gorm:"type:text"` // Encrypted
 	CardCVV         *string       `json:"card_cvv,omitempty" gorm:"type:text"`    // Encrypted
 	BillingDay      int           `json:"billing_day" validate:"required,min=1,max=31"`
 	PaymentStatus   PaymentStatus `json:"payment_status" gorm:"default:'pending'"`
 	NextBillingDate time.Time     `json:"next_billing_date"`
+	DataFimContrato *time.Time    `json:"data_fim_contrato"` // Fim do contrato dos planos não recorrentes
 	Amount          float64       `json:"amount" validate:"required,gt=0"`
+	ValorOriginal   float64       `json:"valor_original"`   // Valor antes do desconto familiar
+	DescontoFamilia float64       `json:"desconto_familia"` // Percentual de desconto familiar aplicado
 	Active          bool          `json:"active" gorm:"default:true"`
 	CriadoEm        time.Time     `json:"criado_em"`
 	AtualizadoEm    time.Time     `json:"atualizado_em"`
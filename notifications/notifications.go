@@ -0,0 +1,74 @@
+// notifications/notifications.go
+package notifications
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"go-api/utils"
+)
+
+// Mensagem é uma notificação a ser entregue para um cliente
+type Mensagem struct {
+	Destinatario string `json:"destinatario"`
+	Assunto      string `json:"assunto"`
+	Texto        string `json:"texto"`
+}
+
+// Canal é um meio de entrega de notificações (e-mail, WhatsApp, SMS...)
+type Canal interface {
+	Nome() string
+	Enviar(m Mensagem) error
+}
+
+var (
+	mu     sync.RWMutex
+	canais []Canal
+)
+
+// Registrar adiciona um canal de entrega
+func Registrar(c Canal) {
+	mu.Lock()
+	defer mu.Unlock()
+	canais = append(canais, c)
+}
+
+// Enviar entrega a mensagem por todos os canais registrados.
+// Sem canais registrados a mensagem é apenas registrada no log.
+func Enviar(m Mensagem) {
+	mu.RLock()
+	registrados := append([]Canal(nil), canais...)
+	mu.RUnlock()
+
+	if len(registrados) == 0 {
+		LogCanal{}.Enviar(m)
+		return
+	}
+
+	for _, c := range registrados {
+		if err := c.Enviar(m); err != nil {
+			log.Printf("Erro ao enviar notificação pelo canal %s: %v", c.Nome(), err)
+		}
+	}
+}
+
+// LogCanal apenas registra as mensagens no log da aplicação
+type LogCanal struct{}
+
+func (LogCanal) Nome() string { return "log" }
+
+// O texto traz dados pessoais do cliente; o log guarda apenas o destinatário
+// mascarado e o assunto
+func (LogCanal) Enviar(m Mensagem) error {
+	log.Printf("Notificação para %s: %s", mascararDestinatario(m.Destinatario), m.Assunto)
+	return nil
+}
+
+// O destinatário pode ser um e-mail ou um telefone
+func mascararDestinatario(destinatario string) string {
+	if strings.Contains(destinatario, "@") {
+		return utils.MascararEmail(destinatario)
+	}
+	return utils.MascararTelefone(destinatario)
+}
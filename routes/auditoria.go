@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"log"
+
+	"go-api/db"
+	"go-api/services"
+)
+
+// Registra uma ação de auditoria fora de transação, apenas logando falhas
+func registrarAuditoria(entidade, entidadeID, clienteID, acao, autor string, dados map[string]interface{}) {
+	var cliente *string
+	if clienteID != "" {
+		cliente = &clienteID
+	}
+	if err := services.RegistrarAuditoria(config.DB, entidade, entidadeID, cliente, acao, autor, dados); err != nil {
+		log.Printf("Erro ao registrar auditoria %s de %s: %v", acao, entidadeID, err)
+	}
+}
@@ -0,0 +1,355 @@
+package routes
+
+import (
+	"errors"
+	"go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"go-api/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func SetupAulaRoutes(app *fiber.App) {
+	modalidadeGroup := app.Group("/modalidades", middleware.JWTMiddleware())
+
+	modalidadeGroup.Get("/", ListModalidades)
+	modalidadeGroup.Post("/", CreateModalidade)
+	modalidadeGroup.Put("/:id", UpdateModalidade)
+	modalidadeGroup.Delete("/:id", DeleteModalidade)
+
+	aulaGroup := app.Group("/aulas", middleware.JWTMiddleware())
+
+	aulaGroup.Get("/", ListAulas)
+	aulaGroup.Get("/grade", GetGradeSemanal)
+	aulaGroup.Get("/excecoes", ListExcecoesAgenda)
+	aulaGroup.Post("/excecoes", CreateExcecaoAgenda)
+	aulaGroup.Delete("/excecoes/:id", DeleteExcecaoAgenda)
+	aulaGroup.Get("/ministradas", ListAulasMinistradas)
+	aulaGroup.Delete("/ministradas/:id", DeleteAulaMinistrada)
+	aulaGroup.Get("/:id", GetAula)
+	aulaGroup.Post("/", CreateAula)
+	aulaGroup.Put("/:id", UpdateAula)
+	aulaGroup.Delete("/:id", DeleteAula)
+	aulaGroup.Get("/:id/matriculas", ListMatriculasAula)
+	aulaGroup.Post("/:id/matriculas", MatricularCliente)
+	aulaGroup.Post("/:id/matriculas/promover", PromoverListaEspera)
+	aulaGroup.Delete("/:id/matriculas/:clienteId", CancelarMatricula)
+	aulaGroup.Post("/:id/ministradas", RegistrarAulaMinistrada)
+}
+
+// Converte os erros de validação da agenda em respostas HTTP
+func respostaErroAula(c *fiber.Ctx, err error, mensagem string) error {
+	switch {
+	case errors.Is(err, services.ErrAulaNaoEncontrada):
+		return c.Status(404).JSON(fiber.Map{"error": "Aula não encontrada"})
+	case errors.Is(err, services.ErrHorarioInvalido),
+		errors.Is(err, services.ErrModalidadeInvalida),
+		errors.Is(err, services.ErrInstrutorInvalido),
+		errors.Is(err, services.ErrFaixaEtariaInvalida):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrConflitoSala), errors.Is(err, services.ErrConflitoInstrutor):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
+
+// ListModalidades retorna as modalidades; ?ativas=true omite as inativas
+func ListModalidades(c *fiber.Ctx) error {
+	query := config.DB.Order("nome")
+	if c.QueryBool("ativas") {
+		query = query.Where("ativa = ?", true)
+	}
+
+	var modalidades []models.Modalidade
+	if err := query.Find(&modalidades).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar modalidades"})
+	}
+
+	return c.JSON(modalidades)
+}
+
+func CreateModalidade(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var modalidade models.Modalidade
+	if err := c.BodyParser(&modalidade); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(modalidade); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	modalidade.ID = "" // Remove o ID enviado pelo cliente
+	modalidade.Ativa = true
+	if err := config.DB.Create(&modalidade).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma modalidade com este nome"})
+	}
+
+	return c.Status(201).JSON(modalidade)
+}
+
+func UpdateModalidade(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var modalidade models.Modalidade
+	if err := config.DB.First(&modalidade, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Modalidade não encontrada"})
+	}
+
+	if err := c.BodyParser(&modalidade); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(modalidade); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	modalidade.ID = c.Params("id")
+	if err := config.DB.Save(&modalidade).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma modalidade com este nome"})
+	}
+
+	return c.JSON(modalidade)
+}
+
+// DeleteModalidade remove uma modalidade sem aulas; com aulas ela deve ser desativada
+func DeleteModalidade(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var total int64
+	if err := config.DB.Model(&models.Aula{}).Where("modalidade_id = ?", c.Params("id")).Count(&total).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar modalidade"})
+	}
+	if total > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "Modalidade possui aulas; desative-a em vez de excluir"})
+	}
+
+	result := config.DB.Delete(&models.Modalidade{}, "id = ?", c.Params("id"))
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar modalidade"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Modalidade não encontrada"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// ListAulas retorna as aulas ativas; aceita ?modalidade_id=, ?instrutor_id= e ?inativas=true
+func ListAulas(c *fiber.Ctx) error {
+	query := config.DB.Preload("Modalidade").Preload("Instrutor").Order("dia_semana, hora_inicio")
+	if !c.QueryBool("inativas") {
+		query = query.Where("ativa = ?", true)
+	}
+	if modalidade := c.Query("modalidade_id"); modalidade != "" {
+		query = query.Where("modalidade_id = ?", modalidade)
+	}
+	if instrutor := c.Query("instrutor_id"); instrutor != "" {
+		query = query.Where("instrutor_id = ?", instrutor)
+	}
+
+	var aulas []models.Aula
+	if err := query.Find(&aulas).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar aulas"})
+	}
+
+	return c.JSON(aulas)
+}
+
+func GetAula(c *fiber.Ctx) error {
+	var aula models.Aula
+	if err := config.DB.Preload("Modalidade").Preload("Instrutor").First(&aula, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Aula não encontrada"})
+	}
+
+	return c.JSON(aula)
+}
+
+func CreateAula(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var aula models.Aula
+	if err := c.BodyParser(&aula); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(aula); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	aula.ID = "" // Remove o ID enviado pelo cliente
+	aula.Ativa = true
+	if err := services.SalvarAula(&aula); err != nil {
+		return respostaErroAula(c, err, "Erro ao criar aula")
+	}
+
+	return c.Status(201).JSON(aula)
+}
+
+func UpdateAula(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var aula models.Aula
+	if err := config.DB.First(&aula, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Aula não encontrada"})
+	}
+
+	if err := c.BodyParser(&aula); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(aula); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	aula.ID = c.Params("id")
+	if err := services.SalvarAula(&aula); err != nil {
+		return respostaErroAula(c, err, "Erro ao atualizar aula")
+	}
+
+	return c.JSON(aula)
+}
+
+// DeleteAula desativa a aula, preservando o histórico de frequência, e cancela as matrículas dela
+func DeleteAula(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	// As matrículas e a lista de espera da aula são canceladas junto
+	if err := services.DesativarAula(c.Params("id"), user["email"].(string)); err != nil {
+		return respostaErroAula(c, err, "Erro ao desativar aula")
+	}
+
+	return c.SendStatus(204)
+}
+
+// GetGradeSemanal retorna a grade de segunda a domingo da semana de ?data=AAAA-MM-DD
+// (padrão: semana atual), com filtros ?modalidade_id= e ?instrutor_id=
+func GetGradeSemanal(c *fiber.Ctx) error {
+	data := time.Now()
+	if valor := c.Query("data"); valor != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", valor, time.Local)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+		}
+		data = parsed
+	}
+
+	grade, err := services.GradeSemanal(data, services.FiltroGrade{
+		ModalidadeID: c.Query("modalidade_id"),
+		InstrutorID:  c.Query("instrutor_id"),
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao montar grade"})
+	}
+
+	return c.JSON(grade)
+}
+
+// ListExcecoesAgenda retorna as exceções a partir de hoje ou de ?de=AAAA-MM-DD
+func ListExcecoesAgenda(c *fiber.Ctx) error {
+	de := time.Now().Format("2006-01-02")
+	if valor := c.Query("de"); valor != "" {
+		de = valor
+	}
+
+	var excecoes []models.ExcecaoAgenda
+	if err := config.DB.Preload("Aula").Where("data >= ?", de).Order("data").Find(&excecoes).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar exceções da agenda"})
+	}
+
+	return c.JSON(excecoes)
+}
+
+// CreateExcecaoAgenda cancela uma aula em uma data ou, sem aula_id, todas as aulas do dia
+func CreateExcecaoAgenda(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var excecao models.ExcecaoAgenda
+	if err := c.BodyParser(&excecao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(excecao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if excecao.AulaID != nil {
+		var aula models.Aula
+		if err := config.DB.First(&aula, "id = ?", *excecao.AulaID).Error; err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Aula não encontrada"})
+		}
+		if aula.DiaSemana != int(excecao.Data.Weekday()) {
+			return c.Status(400).JSON(fiber.Map{"error": "A aula não acontece no dia da semana informado"})
+		}
+	}
+
+	excecao.ID = "" // Remove o ID enviado pelo cliente
+	if err := config.DB.Create(&excecao).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar exceção da agenda"})
+	}
+
+	return c.Status(201).JSON(excecao)
+}
+
+func DeleteExcecaoAgenda(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	result := config.DB.Delete(&models.ExcecaoAgenda{}, "id = ?", c.Params("id"))
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar exceção da agenda"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Exceção da agenda não encontrada"})
+	}
+
+	return c.SendStatus(204)
+}
@@ -2,14 +2,19 @@
 package routes
 
 import (
+	"errors"
 	"go-api/db"
 	"go-api/middleware"
 	"go-api/models"
+	"go-api/services"
 	"go-api/utils"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 var validate = utils.Validate
@@ -19,10 +24,62 @@ func SetupClienteRoutes(app *fiber.App) {
 
 	clienteGroup.Get("/", GetClientes)
 	clienteGroup.Get("/basic", GetClientesBasic)
+	clienteGroup.Get("/aniversariantes", GetAniversariantes)
+	clienteGroup.Get("/inadimplentes", GetInadimplentes)
+	clienteGroup.Post("/import", ImportClientes)
+	clienteGroup.Get("/import/:jobId", GetImportacao)
+	clienteGroup.Get("/export", ExportClientes)
+	clienteGroup.Get("/duplicados", GetDuplicados)
+	clienteGroup.Post("/merge", MergeClientes)
+	clienteGroup.Get("/saude/atestados-vencidos", GetAtestadosVencidos)
 	clienteGroup.Get("/:id", GetCliente)    // Nova rota para buscar cliente por ID
 	clienteGroup.Post("/", CreateCliente)
 	clienteGroup.Put("/:id", UpdateCliente)
 	clienteGroup.Delete("/:id", DeleteCliente)
+
+	clienteGroup.Get("/:id/inadimplencia", GetHistoricoInadimplencia)
+	clienteGroup.Post("/:id/arquivar", ArquivarCliente)
+	clienteGroup.Post("/:id/restaurar", RestaurarCliente)
+	clienteGroup.Get("/:id/lgpd/export", ExportLGPD)
+	clienteGroup.Post("/:id/lgpd/anonymize", AnonymizeLGPD)
+
+	// Rotas de notas, tags e linha do tempo do cliente
+	clienteGroup.Get("/:id/notas", ListNotas)
+	clienteGroup.Post("/:id/notas", CreateNota)
+	clienteGroup.Put("/:id/notas/:notaId", UpdateNota)
+	clienteGroup.Delete("/:id/notas/:notaId", DeleteNota)
+	clienteGroup.Put("/:id/tags", SetClienteTags)
+	clienteGroup.Get("/:id/timeline", GetTimeline)
+	clienteGroup.Get("/:id/presencas", ListPresencasCliente)
+	clienteGroup.Get("/:id/credencial", GetCredencial)
+	clienteGroup.Get("/:id/credencial/qr", GetCredencialQR)
+	clienteGroup.Post("/:id/credencial/reemitir", ReemitirCredencial)
+	clienteGroup.Get("/:id/graduacoes", ListGraduacoesCliente)
+	clienteGroup.Post("/:id/graduacoes", PromoverCliente)
+	clienteGroup.Get("/:id/graduacoes/elegibilidade", GetElegibilidadeCliente)
+	clienteGroup.Get("/:id/matriculas", ListMatriculasCliente)
+	clienteGroup.Get("/:id/reservas", ListReservasCliente)
+
+	// Rotas de saúde e contatos de emergência do cliente
+	clienteGroup.Get("/:id/saude", GetPerfilSaude)
+	clienteGroup.Put("/:id/saude", UpdatePerfilSaude)
+	clienteGroup.Get("/:id/contatos-emergencia", ListContatosEmergencia)
+	clienteGroup.Post("/:id/contatos-emergencia", CreateContatoEmergencia)
+	clienteGroup.Put("/:id/contatos-emergencia/:contatoId", UpdateContatoEmergencia)
+	clienteGroup.Delete("/:id/contatos-emergencia/:contatoId", DeleteContatoEmergencia)
+
+	// Rotas de documentos e fotos do cliente
+	clienteGroup.Get("/:id/documents", ListDocumentos)
+	clienteGroup.Post("/:id/documents", UploadDocumento)
+	clienteGroup.Get("/:id/documents/:documentoId", GetDocumento)
+	clienteGroup.Get("/:id/documents/:documentoId/download", DownloadDocumento)
+	clienteGroup.Delete("/:id/documents/:documentoId", DeleteDocumento)
+
+	// Rotas dos responsáveis legais do cliente
+	clienteGroup.Get("/:id/guardians", ListGuardians)
+	clienteGroup.Post("/:id/guardians", CreateGuardian)
+	clienteGroup.Put("/:id/guardians/:guardianId", UpdateGuardian)
+	clienteGroup.Delete("/:id/guardians/:guardianId", DeleteGuardian)
 }
 
 // GetCliente retorna os dados completos de um cliente específico pelo ID
@@ -37,14 +94,15 @@ func GetCliente(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var cliente models.Cliente
 
-	if err := config.DB.Preload("Pais").First(&cliente, "id = ?", id).Error; err != nil {
+	if err := config.DB.Preload("Guardians").Preload("Pais").Preload("Tags").First(&cliente, "id = ?", id).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
 	}
 
+	protegerPII(role, &cliente)
 	return c.JSON(cliente)
 }
 
-// GetClientes retorna todos os dados dos clientes, incluindo informações dos pais
+// GetClientes retorna todos os dados dos clientes, incluindo os responsáveis
 func GetClientes(c *fiber.Ctx) error {
 	user := c.Locals("user").(jwt.MapClaims)
 	role := user["role"].(string)
@@ -54,10 +112,67 @@ func GetClientes(c *fiber.Ctx) error {
 	}
 
 	var clientes []models.Cliente
-	config.DB.Preload("Pais").Find(&clientes) // Adicionado Preload para carregar dados dos pais
+	query := filtrarClientes(c, config.DB.Model(&models.Cliente{}))
+	query.Preload("Guardians").Preload("Pais").Preload("Tags").Find(&clientes) // Adicionado Preload para carregar os responsáveis
+	for i := range clientes {
+		protegerPII(role, &clientes[i])
+	}
 	return c.JSON(clientes)
 }
 
+// Aplica os filtros da listagem de clientes informados na query string.
+// Usado também pela exportação para que ambas retornem os mesmos clientes.
+func filtrarClientes(c *fiber.Ctx, query *gorm.DB) *gorm.DB {
+	// Clientes arquivados só aparecem quando pedidos explicitamente
+	switch c.Query("arquivados") {
+	case "true":
+		query = query.Where("clientes.arquivado_em IS NOT NULL")
+	case "todos":
+	default:
+		query = services.ApenasAtivos(query)
+	}
+
+	if nome := c.Query("nome"); nome != "" {
+		query = query.Where("clientes.nome ILIKE ?", "%"+nome+"%")
+	}
+	if cidade := c.Query("cidade"); cidade != "" {
+		query = query.Where("clientes.cidade ILIKE ?", cidade)
+	}
+	if estado := c.Query("estado"); estado != "" {
+		query = query.Where("clientes.estado ILIKE ?", estado)
+	}
+	if genero := c.Query("genero"); genero != "" {
+		query = query.Where("clientes.genero = ?", genero)
+	}
+	if inadimplente := c.Query("inadimplente"); inadimplente != "" {
+		query = query.Where("clientes.flag_inadimplente = ?", inadimplente == "true")
+	}
+	if aniversariante := c.Query("aniversariante"); aniversariante != "" {
+		query = query.Where("clientes.flag_aniversariante = ?", aniversariante == "true")
+	}
+	// ?tags=a,b retorna apenas os clientes que têm todas as tags informadas
+	if tags := c.Query("tags"); tags != "" {
+		nomes := []string{}
+		for _, nome := range strings.Split(tags, ",") {
+			if nome = strings.ToLower(strings.TrimSpace(nome)); nome != "" {
+				nomes = append(nomes, nome)
+			}
+		}
+		if len(nomes) > 0 {
+			query = query.Where(`clientes.id IN (
+				SELECT ct.cliente_id FROM cliente_tags ct JOIN tags t ON t.id = ct.tag_id
+				WHERE t.nome IN ? GROUP BY ct.cliente_id HAVING COUNT(DISTINCT t.id) = ?)`, nomes, len(nomes))
+		}
+	}
+	switch c.Query("menor") {
+	case "true":
+		query = query.Where("clientes.data_nascimento > CURRENT_DATE - INTERVAL '18 years'")
+	case "false":
+		query = query.Where("clientes.data_nascimento <= CURRENT_DATE - INTERVAL '18 years'")
+	}
+	return query
+}
+
 // GetClientesBasic retorna apenas ID, Nome Completo e E-mail dos clientes
 func GetClientesBasic(c *fiber.Ctx) error {
 	user := c.Locals("user").(jwt.MapClaims)
@@ -73,14 +188,150 @@ func GetClientesBasic(c *fiber.Ctx) error {
 		Email       string `json:"email"`
 	}
 
-	var clientesBasicos []ClienteBasico
-	config.DB.Model(&models.Cliente{}).
-		Select("id, nome_completo, email").
-		Find(&clientesBasicos)
+	// O e-mail é criptografado, então a leitura passa pelo modelo Cliente
+	var clientes []models.Cliente
+	config.DB.Scopes(services.ApenasAtivos).Select("id, nome, email").Find(&clientes)
+
+	clientesBasicos := make([]ClienteBasico, 0, len(clientes))
+	for _, cliente := range clientes {
+		protegerPII(role, &cliente)
+		clientesBasicos = append(clientesBasicos, ClienteBasico{
+			ID:           cliente.ID,
+			NomeCompleto: cliente.Nome,
+			Email:        cliente.Email,
+		})
+	}
 
 	return c.JSON(clientesBasicos)
 }
 
+// GetAniversariantes retorna os clientes que fazem aniversário no período
+// informado em ?periodo=hoje|semana|mes (padrão: hoje)
+func GetAniversariantes(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	janela := services.JanelaAniversario(c.Query("periodo", string(services.JanelaHoje)))
+	clientes, err := services.ListarAniversariantes(janela, time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrJanelaInvalida) {
+			return c.Status(400).JSON(fiber.Map{"error": "Período inválido", "details": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar aniversariantes"})
+	}
+
+	for i := range clientes {
+		protegerPII(role, &clientes[i])
+	}
+	return c.JSON(clientes)
+}
+
+// GetInadimplentes retorna os clientes inadimplentes com o valor em aberto
+func GetInadimplentes(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	relatorio, err := services.ListarInadimplentes()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar inadimplentes"})
+	}
+
+	for i := range relatorio {
+		protegerPII(role, &relatorio[i].Cliente)
+	}
+	return c.JSON(relatorio)
+}
+
+// GetHistoricoInadimplencia retorna os períodos de inadimplência de um cliente
+func GetHistoricoInadimplencia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	var periodos []models.PeriodoInadimplencia
+	if err := config.DB.Where("cliente_id = ?", id).Order("inicio DESC").Find(&periodos).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar histórico de inadimplência"})
+	}
+
+	return c.JSON(periodos)
+}
+
+// ArquivarCliente arquiva o cliente com o motivo informado em {"motivo": "..."}
+func ArquivarCliente(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	type ArquivarRequest struct {
+		Motivo models.MotivoArquivamento `json:"motivo"`
+	}
+
+	var req ArquivarRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	if err := services.ArquivarCliente(c.Params("id"), req.Motivo, user["email"].(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrMotivoArquivamentoInvalido):
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrClienteArquivado):
+			return c.Status(409).JSON(fiber.Map{"error": "Cliente já está arquivado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao arquivar cliente"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// RestaurarCliente devolve um cliente arquivado às listagens
+func RestaurarCliente(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	if err := services.RestaurarCliente(c.Params("id"), user["email"].(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrClienteNaoArquivado):
+			return c.Status(409).JSON(fiber.Map{"error": "Cliente não está arquivado"})
+		case errors.Is(err, services.ErrClienteAnonimizado):
+			return c.Status(409).JSON(fiber.Map{"error": "Cliente anonimizado não pode ser restaurado"})
+		case errors.Is(err, services.ErrClienteDuplicado):
+			return c.Status(409).JSON(fiber.Map{"error": "Já existe outro cliente ativo com este CPF ou e-mail"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao restaurar cliente"})
+	}
+
+	return c.SendStatus(204)
+}
+
 func CreateCliente(c *fiber.Ctx) error {
 	user := c.Locals("user").(jwt.MapClaims)
 	role := user["role"].(string)
@@ -90,8 +341,9 @@ func CreateCliente(c *fiber.Ctx) error {
 	}
 
 	type ClienteRequest struct {
-		Cliente models.Cliente `json:"cliente"`
-		Pais    models.Pais    `json:"pais"`
+		Cliente   models.Cliente    `json:"cliente"`
+		Pais      *models.Pais      `json:"pais,omitempty"`
+		Guardians []models.Guardian `json:"guardians"`
 	}
 
 	var req ClienteRequest
@@ -104,44 +356,33 @@ func CreateCliente(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
 	}
 
-	// Verificar se o cliente é menor de idade
-	idade := time.Now().Year() - req.Cliente.DataNascimento.Year()
-	if time.Now().Before(req.Cliente.DataNascimento.AddDate(idade, 0, 0)) {
-		idade-- // Ajuste para o caso de o aniversário ainda não ter ocorrido este ano
-	}
-
-	if idade < 18 {
-		// Validação dos dados do Pais (apenas se o cliente for menor de idade)
-		if err := validate.Struct(req.Pais); err != nil {
-			return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
-		}
-
-		// Ignorar o ID enviado pelo cliente e gerar um novo
-		req.Cliente.ID = "" // Remove o ID enviado pelo cliente
-		if err := config.DB.Create(&req.Cliente).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar cliente"})
-		}
-
-		// Associa o Pais ao Cliente
-		req.Pais.ClienteID = req.Cliente.ID
-		req.Pais.ID = "" // Remove o ID enviado pelo cliente
-		if err := config.DB.Create(&req.Pais).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar pais"})
+	// Responsáveis podem vir na raiz da requisição ou dentro do cliente
+	guardians := append(req.Guardians, req.Cliente.Guardians...)
+	for i := range guardians {
+		if err := validate.Struct(guardians[i]); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos do responsável", "details": err.Error()})
 		}
+	}
+	req.Cliente.Guardians = guardians
 
-		// Atualiza o PaisID no Cliente
-		req.Cliente.PaisID = &req.Pais.ID
-		if err := config.DB.Save(&req.Cliente).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar cliente"})
-		}
-	} else {
-		// Cliente é maior de idade, não adiciona os pais
-		req.Cliente.ID = "" // Remove o ID enviado pelo cliente
-		if err := config.DB.Create(&req.Cliente).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar cliente"})
+	if req.Pais == nil {
+		req.Pais = req.Cliente.Pais
+	}
+	req.Cliente.Pais = nil
+
+	// Cliente, responsáveis e pais são criados em uma única transação
+	if err := services.CriarCliente(&req.Cliente, req.Pais, user["email"].(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrResponsavelObrigatorio):
+			return c.Status(400).JSON(fiber.Map{"error": "Cliente menor de idade precisa de pelo menos um responsável"})
+		case errors.Is(err, services.ErrClienteDuplicado):
+			return c.Status(409).JSON(fiber.Map{"error": "Já existe um cliente com este CPF ou e-mail"})
 		}
+		log.Printf("Erro ao criar cliente: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar cliente"})
 	}
 
+	protegerPII(role, &req.Cliente)
 	return c.JSON(req.Cliente)
 }
 
@@ -158,17 +399,47 @@ func UpdateCliente(c *fiber.Ctx) error {
 	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
 	}
+	gravado := cliente
 
 	if err := c.BodyParser(&cliente); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
 	}
 
+	// Papéis sem permissão de PII editam a partir dos dados mascarados
+	restaurarPII(&cliente, &gravado)
+	if cliente.Pais != nil {
+		var paisGravado models.Pais
+		if err := config.DB.Where("cliente_id = ?", id).First(&paisGravado).Error; err == nil {
+			restaurarPIIPais(cliente.Pais, &paisGravado)
+		}
+	}
+
 	// Validação dos dados do cliente
 	if err := validate.Struct(cliente); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
 	}
 
-	config.DB.Save(&cliente)
+	// Os responsáveis são gerenciados pelas rotas /clientes/:id/guardians
+	cliente.Guardians = nil
+	cliente.ID = id
+	pais := cliente.Pais
+	cliente.Pais = nil
+
+	if err := services.AtualizarCliente(&cliente, pais, user["email"].(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrResponsavelObrigatorio):
+			return c.Status(400).JSON(fiber.Map{"error": "Cliente menor de idade precisa de pelo menos um responsável"})
+		case errors.Is(err, services.ErrClienteAnonimizado):
+			return c.Status(409).JSON(fiber.Map{"error": "Cliente anonimizado não pode ser alterado"})
+		case errors.Is(err, services.ErrClienteDuplicado):
+			return c.Status(409).JSON(fiber.Map{"error": "Já existe um cliente com este CPF ou e-mail"})
+		}
+		log.Printf("Erro ao atualizar cliente: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar cliente"})
+	}
+	protegerPII(role, &cliente)
 	return c.JSON(cliente)
 }
 
@@ -181,11 +452,79 @@ func DeleteCliente(c *fiber.Ctx) error {
 	}
 
 	id := c.Params("id")
-	var cliente models.Cliente
-	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+
+	// Responsáveis e pais são removidos em cascata na mesma transação
+	if err := services.DeletarCliente(id, user["email"].(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrClienteComRegistrosFinanceiros):
+			return c.Status(409).JSON(fiber.Map{"error": "Cliente possui vendas ou assinaturas; arquive-o ou use a anonimização da LGPD"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar cliente"})
 	}
 
-	config.DB.Delete(&cliente)
 	return c.SendStatus(204)
-}
\ No newline at end of file
+}
+
+// Mascara os dados pessoais dos clientes para papéis sem permissão de PII
+func protegerPII(role string, clientes ...*models.Cliente) {
+	if utils.PodeVerPII(role) {
+		return
+	}
+
+	for _, cliente := range clientes {
+		cliente.CPF = utils.MascararCPF(cliente.CPF)
+		cliente.Email = utils.MascararEmail(cliente.Email)
+		cliente.Telefone = utils.MascararTelefone(cliente.Telefone)
+		protegerPIIResponsaveis(role, cliente.Guardians)
+
+		if cliente.Pais != nil {
+			cliente.Pais.CPFPai = utils.MascararCPF(cliente.Pais.CPFPai)
+			cliente.Pais.EmailPai = utils.MascararEmail(cliente.Pais.EmailPai)
+			cliente.Pais.TelefonePai = utils.MascararTelefone(cliente.Pais.TelefonePai)
+			cliente.Pais.CPFMae = utils.MascararCPF(cliente.Pais.CPFMae)
+			cliente.Pais.EmailMae = utils.MascararEmail(cliente.Pais.EmailMae)
+			cliente.Pais.TelefoneMae = utils.MascararTelefone(cliente.Pais.TelefoneMae)
+		}
+	}
+}
+
+// Mantém os dados gravados nos campos que voltaram com a máscara de protegerPII
+func restaurarPII(cliente, gravado *models.Cliente) {
+	cliente.CPF = utils.ManterSeMascarado(cliente.CPF, gravado.CPF, utils.MascararCPF)
+	cliente.Email = utils.ManterSeMascarado(cliente.Email, gravado.Email, utils.MascararEmail)
+	cliente.Telefone = utils.ManterSeMascarado(cliente.Telefone, gravado.Telefone, utils.MascararTelefone)
+}
+
+func restaurarPIIPais(pais, gravado *models.Pais) {
+	pais.CPFPai = utils.ManterSeMascarado(pais.CPFPai, gravado.CPFPai, utils.MascararCPF)
+	pais.EmailPai = utils.ManterSeMascarado(pais.EmailPai, gravado.EmailPai, utils.MascararEmail)
+	pais.TelefonePai = utils.ManterSeMascarado(pais.TelefonePai, gravado.TelefonePai, utils.MascararTelefone)
+	pais.CPFMae = utils.ManterSeMascarado(pais.CPFMae, gravado.CPFMae, utils.MascararCPF)
+	pais.EmailMae = utils.ManterSeMascarado(pais.EmailMae, gravado.EmailMae, utils.MascararEmail)
+	pais.TelefoneMae = utils.ManterSeMascarado(pais.TelefoneMae, gravado.TelefoneMae, utils.MascararTelefone)
+}
+
+func restaurarPIIResponsavel(guardian, gravado *models.Guardian) {
+	guardian.CPF = utils.ManterSeMascarado(guardian.CPF, gravado.CPF, utils.MascararCPF)
+	guardian.Email = utils.ManterSeMascarado(guardian.Email, gravado.Email, utils.MascararEmail)
+	guardian.Telefone = utils.ManterSeMascarado(guardian.Telefone, gravado.Telefone, utils.MascararTelefone)
+}
+
+// Mascara os dados pessoais dos responsáveis para papéis sem permissão de PII
+func protegerPIIResponsaveis(role string, guardians []models.Guardian) {
+	for i := range guardians {
+		protegerPIIResponsavel(role, &guardians[i])
+	}
+}
+
+func protegerPIIResponsavel(role string, guardian *models.Guardian) {
+	if utils.PodeVerPII(role) {
+		return
+	}
+
+	guardian.CPF = utils.MascararCPF(guardian.CPF)
+	guardian.Email = utils.MascararEmail(guardian.Email)
+	guardian.Telefone = utils.MascararTelefone(guardian.Telefone)
+}
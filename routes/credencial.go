@@ -0,0 +1,147 @@
+package routes
+
+import (
+	"errors"
+	"go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"go-api/services"
+	"go-api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+func SetupCredencialRoutes(app *fiber.App) {
+	app.Post("/credenciais/verificar", middleware.JWTMiddleware(), VerificarCredencial)
+}
+
+// Converte os erros da credencial em respostas HTTP
+func respostaErroCredencial(c *fiber.Ctx, err error, mensagem string) error {
+	switch {
+	case errors.Is(err, services.ErrClienteNaoEncontrado):
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	case errors.Is(err, services.ErrCredencialRevogada):
+		return c.Status(410).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrTokenInvalido):
+		return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
+
+// GetCredencial retorna o conteúdo atual do QR code do cliente e quando ele expira
+func GetCredencial(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	codigo, err := services.GerarCodigoQR(c.Params("id"), time.Now())
+	if err != nil {
+		return respostaErroCredencial(c, err, "Erro ao gerar credencial")
+	}
+
+	return c.JSON(fiber.Map{
+		"conteudo":         codigo.Conteudo,
+		"expira_em":        codigo.ExpiraEm,
+		"periodo_segundos": int(codigo.Periodo.Seconds()),
+	})
+}
+
+// GetCredencialQR renderiza o QR code atual do cliente em ?formato=png|svg com
+// ?tamanho= em pixels (padrão: 256). O cabeçalho X-QR-Expira-Em indica quando
+// a imagem deve ser recarregada.
+func GetCredencialQR(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	tamanho, err := strconv.Atoi(c.Query("tamanho", "256"))
+	if err != nil || tamanho < 64 || tamanho > 1024 {
+		return c.Status(400).JSON(fiber.Map{"error": "Tamanho inválido, use entre 64 e 1024 pixels"})
+	}
+
+	codigo, err := services.GerarCodigoQR(c.Params("id"), time.Now())
+	if err != nil {
+		return respostaErroCredencial(c, err, "Erro ao gerar credencial")
+	}
+
+	var imagem []byte
+	switch c.Query("formato", "png") {
+	case "png":
+		imagem, err = utils.QRCodePNG(codigo.Conteudo, tamanho)
+		c.Set(fiber.HeaderContentType, "image/png")
+	case "svg":
+		imagem, err = utils.QRCodeSVG(codigo.Conteudo, tamanho)
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "Formato inválido, use png ou svg"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao gerar QR code"})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set("X-QR-Expira-Em", codigo.ExpiraEm.Format(time.RFC3339))
+	return c.Send(imagem)
+}
+
+// ReemitirCredencial gera um novo segredo para o cliente, invalidando os QR
+// codes anteriores e desfazendo uma revogação
+func ReemitirCredencial(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.Scopes(services.ApenasAtivos).Select("id").First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.ReemitirCredencial(tx, id, user["email"].(string))
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reemitir credencial"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// VerificarCredencial confere o código lido pelo leitor de QR e identifica o cliente,
+// sem registrar presença. Usado pelo tablet antes de escolher a aula do check-in.
+func VerificarCredencial(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	clienteID, err := services.ValidarTokenCheckin(config.DB, req.Token)
+	if err != nil {
+		return respostaErroCredencial(c, err, "Erro ao verificar credencial")
+	}
+
+	var cliente models.Cliente
+	if err := config.DB.Scopes(services.ApenasAtivos).Select("id", "nome").First(&cliente, "id = ?", clienteID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	return c.JSON(fiber.Map{"valido": true, "cliente_id": cliente.ID, "nome": cliente.Nome})
+}
@@ -0,0 +1,221 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"go-api/db"
+	"go-api/models"
+	"go-api/services"
+	"go-api/utils"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SetupDocumentoRoutes registra o download por link assinado, que dispensa o JWT
+// para poder ser aberto direto no navegador ou em uma tag <img>
+func SetupDocumentoRoutes(app *fiber.App) {
+	app.Get("/documentos/:id/download", DownloadDocumentoAssinado)
+}
+
+// Atestados contêm dados de saúde e seguem a mesma restrição do perfil de saúde
+func podeAcessarDocumento(role string, tipo models.TipoDocumento) bool {
+	if role != "admin" && role != "superadmin" {
+		return false
+	}
+	return tipo != models.DocumentoAtestado || utils.PodeVerSaude(role)
+}
+
+// ListDocumentos retorna os documentos do cliente; ?tipo= filtra por tipo
+func ListDocumentos(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.Select("id").First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	query := config.DB.Where("cliente_id = ?", id)
+	if tipo := c.Query("tipo"); tipo != "" {
+		query = query.Where("tipo = ?", tipo)
+	}
+	if !utils.PodeVerSaude(role) {
+		query = query.Where("tipo <> ?", models.DocumentoAtestado)
+	}
+
+	var documentos []models.Documento
+	if err := query.Order("created_at DESC").Find(&documentos).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar documentos"})
+	}
+
+	return c.JSON(documentos)
+}
+
+// UploadDocumento recebe um arquivo no campo "arquivo" com o "tipo" do documento
+// e, para autorizações, o "guardian_id" do responsável que assinou
+func UploadDocumento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	documento := models.Documento{
+		ClienteID: c.Params("id"),
+		Tipo:      models.TipoDocumento(c.FormValue("tipo")),
+	}
+	if guardianID := c.FormValue("guardian_id"); guardianID != "" {
+		documento.GuardianID = &guardianID
+	}
+
+	if !podeAcessarDocumento(role, documento.Tipo) {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(documento); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	arquivo, err := c.FormFile("arquivo")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Arquivo não enviado"})
+	}
+
+	aberto, err := arquivo.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao ler arquivo"})
+	}
+	defer aberto.Close()
+
+	documento.NomeArquivo = arquivo.Filename
+	if err := services.SalvarDocumento(&documento, aberto, arquivo.Size, user["email"].(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrResponsavelNaoEncontrado):
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrArquivoMuitoGrande):
+			return c.Status(413).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrTipoArquivoNaoPermitido):
+			return c.Status(415).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar documento"})
+	}
+
+	return c.Status(201).JSON(documento)
+}
+
+// GetDocumento retorna os metadados do documento com um link de download temporário.
+// ?validade= define a validade do link em minutos (máximo de 24 horas).
+func GetDocumento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	documento, err := services.BuscarDocumento(c.Params("id"), c.Params("documentoId"))
+	if err != nil {
+		if errors.Is(err, services.ErrDocumentoNaoEncontrado) {
+			return c.Status(404).JSON(fiber.Map{"error": "Documento não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar documento"})
+	}
+
+	if !podeAcessarDocumento(role, documento.Tipo) {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	validade := services.ValidadeLinkDocumento()
+	if minutos, err := strconv.Atoi(c.Query("validade")); err == nil && minutos > 0 && minutos <= 24*60 {
+		validade = time.Duration(minutos) * time.Minute
+	}
+	expiraEm := time.Now().Add(validade)
+
+	return c.JSON(fiber.Map{
+		"documento":     documento,
+		"url":           services.LinkDownload(documento, expiraEm),
+		"url_expira_em": expiraEm,
+	})
+}
+
+// DownloadDocumento envia o conteúdo do documento para o usuário autenticado
+func DownloadDocumento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	documento, err := services.BuscarDocumento(c.Params("id"), c.Params("documentoId"))
+	if err != nil {
+		if errors.Is(err, services.ErrDocumentoNaoEncontrado) {
+			return c.Status(404).JSON(fiber.Map{"error": "Documento não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar documento"})
+	}
+
+	if !podeAcessarDocumento(role, documento.Tipo) {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	return enviarDocumento(c, documento)
+}
+
+// DownloadDocumentoAssinado envia o documento a partir de um link assinado e ainda válido
+func DownloadDocumentoAssinado(c *fiber.Ctx) error {
+	documento, err := services.ValidarLinkDownload(c.Params("id"), c.Query("expira"), c.Query("assinatura"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLinkInvalido):
+			return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrDocumentoNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Documento não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar documento"})
+	}
+
+	return enviarDocumento(c, documento)
+}
+
+// DeleteDocumento remove o documento e o arquivo do armazenamento
+func DeleteDocumento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	documento, err := services.BuscarDocumento(c.Params("id"), c.Params("documentoId"))
+	if err != nil {
+		if errors.Is(err, services.ErrDocumentoNaoEncontrado) {
+			return c.Status(404).JSON(fiber.Map{"error": "Documento não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar documento"})
+	}
+
+	if !podeAcessarDocumento(role, documento.Tipo) {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	if err := services.RemoverDocumento(documento, user["email"].(string)); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar documento"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// Transmite o arquivo com o tipo detectado no envio e o checksum para conferência
+func enviarDocumento(c *fiber.Ctx, documento *models.Documento) error {
+	conteudo, err := services.AbrirDocumento(documento)
+	if err != nil {
+		if errors.Is(err, services.ErrDocumentoNaoEncontrado) {
+			return c.Status(404).JSON(fiber.Map{"error": "Arquivo do documento não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao abrir documento"})
+	}
+
+	c.Set(fiber.HeaderContentType, documento.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s"`, documento.NomeArquivo))
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	c.Set("X-Content-Type-Options", "nosniff")
+	c.Set("X-Checksum-SHA256", documento.SHA256)
+	return c.SendStream(conteudo, int(documento.Tamanho))
+}
@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"errors"
+	"go-api/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GetDuplicados retorna os pares de clientes que provavelmente são a mesma pessoa
+func GetDuplicados(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	pares, err := services.BuscarDuplicados()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar clientes duplicados"})
+	}
+
+	for i := range pares {
+		protegerPII(role, &pares[i].Clientes[0], &pares[i].Clientes[1])
+	}
+
+	return c.JSON(pares)
+}
+
+// MergeClientes mescla o cliente duplicado no sobrevivente
+func MergeClientes(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	type MergeRequest struct {
+		SobreviventeID string `json:"sobrevivente_id" validate:"required"`
+		DuplicadoID    string `json:"duplicado_id" validate:"required"`
+	}
+
+	var req MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if err := services.MesclarClientes(req.SobreviventeID, req.DuplicadoID, user["email"].(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrMesclagemMesmoCliente), errors.Is(err, services.ErrMesclagemAnonimizado),
+			errors.Is(err, services.ErrMesclagemArquivado):
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mesclar clientes"})
+	}
+
+	return c.SendStatus(204)
+}
@@ -0,0 +1,431 @@
+package routes
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	config "go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"go-api/services"
+	"go-api/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+func SetupEventoRoutes(app *fiber.App) {
+	eventoGroup := app.Group("/eventos", middleware.JWTMiddleware())
+
+	eventoGroup.Get("/", ListEventos)
+	eventoGroup.Get("/:id", GetEvento)
+	eventoGroup.Post("/", CreateEvento)
+	eventoGroup.Put("/:id", UpdateEvento)
+	eventoGroup.Put("/:id/status", UpdateStatusEvento)
+	eventoGroup.Post("/:id/lotes", CreateLoteEvento)
+	eventoGroup.Delete("/:id/lotes/:loteId", DeleteLoteEvento)
+	eventoGroup.Post("/:id/categorias", CreateCategoriaEvento)
+	eventoGroup.Delete("/:id/categorias/:categoriaId", DeleteCategoriaEvento)
+	eventoGroup.Get("/:id/inscricoes", ListInscricoesEvento)
+	eventoGroup.Post("/:id/inscricoes", InscreverEvento)
+	eventoGroup.Delete("/:id/inscricoes/:inscricaoId", CancelarInscricaoEvento)
+	eventoGroup.Post("/:id/inscricoes/:inscricaoId/checkin", CheckinEvento)
+	eventoGroup.Get("/:id/chaves", GetChavesEvento)
+}
+
+// Converte os erros de eventos em respostas HTTP
+func respostaErroEvento(c *fiber.Ctx, err error, mensagem string) error {
+	switch {
+	case errors.Is(err, services.ErrEventoNaoEncontrado),
+		errors.Is(err, services.ErrInscricaoNaoEncontrada),
+		errors.Is(err, services.ErrCategoriaNaoEncontrada),
+		errors.Is(err, services.ErrClienteNaoEncontrado):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrPeriodoEventoInvalido),
+		errors.Is(err, services.ErrModalidadeInvalida),
+		errors.Is(err, services.ErrFaixaEtariaInvalida),
+		errors.Is(err, services.ErrFaixaCategoriaInvalida),
+		errors.Is(err, services.ErrCategoriaObrigatoria),
+		errors.Is(err, services.ErrParticipanteIncompleto):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCategoriaIncompativel),
+		errors.Is(err, services.ErrInscricaoNaoPaga):
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrEventoFechado),
+		errors.Is(err, services.ErrEventoLotado),
+		errors.Is(err, services.ErrLoteIndisponivel),
+		errors.Is(err, services.ErrLoteEmUso),
+		errors.Is(err, services.ErrCategoriaEmUso),
+		errors.Is(err, services.ErrInscricaoDuplicada),
+		errors.Is(err, services.ErrCheckinEventoDuplicado):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
+
+// Mascara o e-mail e o telefone dos inscritos para quem não pode ver dados pessoais
+func protegerPIIInscricao(role string, inscricoes ...*models.InscricaoEvento) {
+	if utils.PodeVerPII(role) {
+		return
+	}
+
+	for _, inscricao := range inscricoes {
+		inscricao.Email = utils.MascararEmail(inscricao.Email)
+		inscricao.Telefone = utils.MascararTelefone(inscricao.Telefone)
+		if inscricao.Cliente != nil {
+			protegerPII(role, inscricao.Cliente)
+		}
+		if inscricao.Venda != nil && inscricao.Venda.Cliente != nil {
+			protegerPII(role, inscricao.Venda.Cliente)
+		}
+	}
+}
+
+// ListEventos lista os eventos com filtros ?status= e ?tipo=
+func ListEventos(c *fiber.Ctx) error {
+	query := config.DB.Preload("Modalidade").Order("inicio DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if tipo := c.Query("tipo"); tipo != "" {
+		query = query.Where("tipo = ?", tipo)
+	}
+
+	var eventos []models.Evento
+	if err := query.Find(&eventos).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar eventos"})
+	}
+
+	return c.JSON(eventos)
+}
+
+// GetEvento retorna o evento com os lotes e as categorias
+func GetEvento(c *fiber.Ctx) error {
+	var evento models.Evento
+	err := config.DB.Preload("Modalidade").
+		Preload("Lotes", func(db *gorm.DB) *gorm.DB { return db.Order("preco") }).
+		Preload("Categorias", func(db *gorm.DB) *gorm.DB { return db.Order("nome") }).
+		Preload("Categorias.FaixaMinima").Preload("Categorias.FaixaMaxima").
+		First(&evento, "id = ?", c.Params("id")).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "Evento não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar evento"})
+	}
+
+	return c.JSON(evento)
+}
+
+// CreateEvento cria o evento com lotes e categorias opcionais e o produto da inscrição
+func CreateEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var evento models.Evento
+	if err := c.BodyParser(&evento); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(evento); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+	for _, lote := range evento.Lotes {
+		if err := validate.Struct(lote); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+		}
+	}
+	for _, categoria := range evento.Categorias {
+		if err := validate.Struct(categoria); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+		}
+	}
+
+	if err := services.CriarEvento(&evento, user["email"].(string)); err != nil {
+		return respostaErroEvento(c, err, "Erro ao criar evento")
+	}
+
+	return c.Status(201).JSON(evento)
+}
+
+// UpdateEvento atualiza os dados gerais do evento
+func UpdateEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var evento models.Evento
+	if err := c.BodyParser(&evento); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(evento); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	evento.ID = c.Params("id")
+	if err := services.AtualizarEvento(&evento, user["email"].(string)); err != nil {
+		return respostaErroEvento(c, err, "Erro ao atualizar evento")
+	}
+
+	return c.JSON(evento)
+}
+
+// UpdateStatusEvento encerra, reabre ou cancela o evento. O cancelamento
+// cancela as inscrições e as vendas ainda não pagas.
+func UpdateStatusEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		Status models.StatusEvento `json:"status" validate:"required,oneof=aberto encerrado cancelado"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	clientes, err := services.AlterarStatusEvento(c.Params("id"), req.Status, user["email"].(string))
+	if err != nil {
+		return respostaErroEvento(c, err, "Erro ao alterar status do evento")
+	}
+	atualizarInadimplencia(clientes...)
+
+	return c.JSON(fiber.Map{"status": req.Status})
+}
+
+// CreateLoteEvento adiciona uma faixa de preço ao evento
+func CreateLoteEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var lote models.LoteEvento
+	if err := c.BodyParser(&lote); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(lote); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if err := services.AdicionarLote(c.Params("id"), &lote); err != nil {
+		return respostaErroEvento(c, err, "Erro ao criar lote")
+	}
+
+	return c.Status(201).JSON(lote)
+}
+
+// DeleteLoteEvento remove um lote que ainda não foi usado
+func DeleteLoteEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	if err := services.RemoverLote(c.Params("id"), c.Params("loteId")); err != nil {
+		return respostaErroEvento(c, err, "Erro ao remover lote")
+	}
+
+	return c.SendStatus(204)
+}
+
+// CreateCategoriaEvento adiciona uma categoria por gênero, idade, peso e faixa
+func CreateCategoriaEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var categoria models.CategoriaEvento
+	if err := c.BodyParser(&categoria); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(categoria); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if err := services.AdicionarCategoria(c.Params("id"), &categoria); err != nil {
+		return respostaErroEvento(c, err, "Erro ao criar categoria")
+	}
+
+	return c.Status(201).JSON(categoria)
+}
+
+// DeleteCategoriaEvento remove uma categoria sem inscrições
+func DeleteCategoriaEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	if err := services.RemoverCategoria(c.Params("id"), c.Params("categoriaId")); err != nil {
+		return respostaErroEvento(c, err, "Erro ao remover categoria")
+	}
+
+	return c.SendStatus(204)
+}
+
+// ListInscricoesEvento retorna os inscritos em ordem de nome, com a situação do
+// pagamento e do check-in; serve de lista de conferência na entrada do evento
+func ListInscricoesEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	inscricoes, err := services.ListarInscricoes(c.Params("id"))
+	if err != nil {
+		return respostaErroEvento(c, err, "Erro ao buscar inscrições")
+	}
+
+	for i := range inscricoes {
+		protegerPIIInscricao(role, &inscricoes[i])
+	}
+
+	return c.JSON(inscricoes)
+}
+
+// InscreverEvento inscreve um cliente (cliente_id) ou um participante externo
+// e gera a venda da inscrição pelo lote vigente
+func InscreverEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var solicitacao services.SolicitacaoInscricao
+	if err := c.BodyParser(&solicitacao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(solicitacao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	inscricao, err := services.InscreverParticipante(c.Params("id"), solicitacao, user["email"].(string))
+	if err != nil {
+		return respostaErroEvento(c, err, "Erro ao registrar inscrição")
+	}
+	atualizarInadimplencia(idCliente(inscricao.ClienteID))
+
+	protegerPIIInscricao(role, inscricao)
+	return c.Status(201).JSON(inscricao)
+}
+
+// CancelarInscricaoEvento cancela a inscrição; a venda é cancelada se ainda não foi paga
+func CancelarInscricaoEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	inscricao, err := services.CancelarInscricao(c.Params("id"), c.Params("inscricaoId"), user["email"].(string))
+	if err != nil {
+		return respostaErroEvento(c, err, "Erro ao cancelar inscrição")
+	}
+	atualizarInadimplencia(idCliente(inscricao.ClienteID))
+
+	return c.SendStatus(204)
+}
+
+// CheckinEvento registra a chegada do inscrito. Apenas admin e superadmin podem
+// forçar a entrada de uma inscrição não paga.
+func CheckinEvento(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	var req struct {
+		Forcar bool `json:"forcar"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+		}
+	}
+	if req.Forcar && role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Apenas administradores podem forçar o check-in"})
+	}
+
+	inscricao, err := services.RegistrarCheckinEvento(c.Params("id"), c.Params("inscricaoId"), req.Forcar, user["email"].(string))
+	if err != nil {
+		return respostaErroEvento(c, err, "Erro ao registrar check-in")
+	}
+
+	protegerPIIInscricao(role, inscricao)
+	return c.JSON(inscricao)
+}
+
+// GetChavesEvento monta as chaves de cada categoria em ?formato=json|csv.
+// Por padrão entram só os inscritos pagos; ?incluir_pendentes=true inclui todos.
+func GetChavesEvento(c *fiber.Ctx) error {
+	chaves, err := services.ChavesEvento(c.Params("id"), c.QueryBool("incluir_pendentes"))
+	if err != nil {
+		return respostaErroEvento(c, err, "Erro ao montar chaves")
+	}
+
+	switch c.Query("formato", "json") {
+	case "json":
+		return c.JSON(chaves)
+	case "csv":
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "Formato inválido, use json ou csv"})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="chaves-%s.csv"`, c.Params("id")))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		escritor := csv.NewWriter(w)
+		escritor.Write([]string{"categoria", "luta", "atleta_a", "academia_a", "atleta_b", "academia_b"})
+		for _, chave := range chaves {
+			for _, luta := range chave.Lutas {
+				linha := []string{chave.Categoria.Nome, fmt.Sprint(luta.Numero), "", "", "", ""}
+				if luta.AtletaA != nil {
+					linha[2], linha[3] = luta.AtletaA.Nome, luta.AtletaA.Academia
+				}
+				if luta.AtletaB != nil {
+					linha[4], linha[5] = luta.AtletaB.Nome, luta.AtletaB.Academia
+				}
+				escritor.Write(linha)
+			}
+		}
+		escritor.Flush()
+		if err := escritor.Error(); err != nil {
+			log.Printf("Erro ao exportar chaves do evento: %v", err)
+		}
+	})
+	return nil
+}
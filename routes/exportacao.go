@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"bufio"
+	"fmt"
+	"go-api/db"
+	"go-api/models"
+	"go-api/services"
+	"go-api/utils"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ExportClientes exporta os clientes em ?formato=csv|xlsx|jsonl, aceitando os mesmos
+// filtros da listagem. ?incluir=guardians,assinatura,inadimplencia adiciona colunas.
+// Colunas com dados pessoais só são exportadas para papéis com permissão de PII.
+func ExportClientes(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	opcoes := services.OpcoesExportacao{
+		Formato:    c.Query("formato", services.FormatoCSV),
+		IncluirPII: utils.PodeVerPII(role),
+	}
+	for _, item := range strings.Split(c.Query("incluir"), ",") {
+		switch strings.TrimSpace(item) {
+		case "guardians":
+			opcoes.Guardians = true
+		case "assinatura":
+			opcoes.Assinatura = true
+		case "inadimplencia":
+			opcoes.Inadimplencia = true
+		}
+	}
+
+	contentType, extensao, err := opcoes.ContentType()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	query := filtrarClientes(c, config.DB.Model(&models.Cliente{}))
+	nomeArquivo := fmt.Sprintf("clientes-%s.%s", time.Now().Format("20060102-150405"), extensao)
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, nomeArquivo))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := services.ExportarClientes(query, opcoes, w); err != nil {
+			log.Println("Erro ao exportar clientes:", err)
+		}
+		w.Flush()
+	})
+
+	return nil
+}
@@ -0,0 +1,359 @@
+package routes
+
+import (
+	"errors"
+	"go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"go-api/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func SetupFamiliaRoutes(app *fiber.App) {
+	familiaGroup := app.Group("/familias", middleware.JWTMiddleware())
+
+	// Regras de desconto familiar
+	familiaGroup.Get("/descontos", ListRegrasDesconto)
+	familiaGroup.Post("/descontos", CreateRegraDesconto)
+	familiaGroup.Put("/descontos/:id", UpdateRegraDesconto)
+	familiaGroup.Delete("/descontos/:id", DeleteRegraDesconto)
+
+	familiaGroup.Get("/", ListFamilias)
+	familiaGroup.Get("/:id", GetFamilia)
+	familiaGroup.Post("/", CreateFamilia)
+	familiaGroup.Put("/:id", UpdateFamilia)
+	familiaGroup.Delete("/:id", DeleteFamilia)
+	familiaGroup.Post("/:id/membros", AddMembroFamilia)
+	familiaGroup.Delete("/:id/membros/:clienteId", RemoveMembroFamilia)
+	familiaGroup.Get("/:id/cobranca", GetCobrancaFamilia)
+	familiaGroup.Post("/:id/cobranca/pagamento", PagarCobrancaFamilia)
+}
+
+// Converte os erros do serviço de famílias em respostas HTTP
+func respostaErroFamilia(c *fiber.Ctx, err error, mensagem string) error {
+	switch {
+	case errors.Is(err, services.ErrFamiliaNaoEncontrada):
+		return c.Status(404).JSON(fiber.Map{"error": "Família não encontrada"})
+	case errors.Is(err, services.ErrClienteNaoEncontrado):
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	case errors.Is(err, services.ErrClienteForaDaFamilia):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrClienteEmOutraFamilia):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrResponsavelFinanceiroInvalido):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
+
+// ListFamilias retorna as famílias com os membros
+func ListFamilias(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var familias []models.Familia
+	if err := config.DB.Preload("Membros").Order("nome").Find(&familias).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar famílias"})
+	}
+
+	for i := range familias {
+		for j := range familias[i].Membros {
+			protegerPII(role, &familias[i].Membros[j])
+		}
+	}
+
+	return c.JSON(familias)
+}
+
+// GetFamilia retorna a família com os membros e o responsável financeiro
+func GetFamilia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var familia models.Familia
+	if err := config.DB.Preload("Membros").Preload("ResponsavelFinanceiro").First(&familia, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Família não encontrada"})
+	}
+
+	for i := range familia.Membros {
+		protegerPII(role, &familia.Membros[i])
+	}
+	if familia.ResponsavelFinanceiro != nil {
+		protegerPII(role, familia.ResponsavelFinanceiro)
+	}
+
+	return c.JSON(familia)
+}
+
+// CreateFamilia cria uma família; o campo "membros" aceita os IDs dos clientes
+func CreateFamilia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var dados struct {
+		models.Familia
+		Membros []string `json:"membros"`
+	}
+	if err := c.BodyParser(&dados); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	familia := dados.Familia
+	// Validação dos dados
+	if err := validate.Struct(familia); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if err := services.CriarFamilia(&familia, dados.Membros, user["email"].(string)); err != nil {
+		return respostaErroFamilia(c, err, "Erro ao criar família")
+	}
+
+	return c.Status(201).JSON(familia)
+}
+
+// UpdateFamilia altera o nome, o responsável financeiro e o dia de cobrança unificado
+func UpdateFamilia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var familia models.Familia
+	if err := c.BodyParser(&familia); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(familia); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	familia.ID = c.Params("id")
+	familia.Membros = nil
+	if err := services.AtualizarFamilia(&familia, user["email"].(string)); err != nil {
+		return respostaErroFamilia(c, err, "Erro ao atualizar família")
+	}
+
+	return c.JSON(familia)
+}
+
+// DeleteFamilia desfaz a família; os clientes continuam cadastrados
+func DeleteFamilia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	result := config.DB.Delete(&models.Familia{}, "id = ?", id)
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar família"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Família não encontrada"})
+	}
+	registrarAuditoria("familia", id, "", "familia.exclusao", user["email"].(string), nil)
+
+	return c.SendStatus(204)
+}
+
+// AddMembroFamilia adiciona o cliente informado em "cliente_id" à família
+func AddMembroFamilia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var dados struct {
+		ClienteID string `json:"cliente_id" validate:"required"`
+	}
+	if err := c.BodyParser(&dados); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(dados); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if err := services.AdicionarMembro(c.Params("id"), dados.ClienteID, user["email"].(string)); err != nil {
+		return respostaErroFamilia(c, err, "Erro ao adicionar membro")
+	}
+
+	return c.SendStatus(204)
+}
+
+// RemoveMembroFamilia retira o cliente da família
+func RemoveMembroFamilia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	if err := services.RemoverMembro(c.Params("id"), c.Params("clienteId"), user["email"].(string)); err != nil {
+		return respostaErroFamilia(c, err, "Erro ao remover membro")
+	}
+
+	return c.SendStatus(204)
+}
+
+// GetCobrancaFamilia retorna a cobrança consolidada das assinaturas dos membros
+func GetCobrancaFamilia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	resumo, err := services.ResumoCobrancaFamilia(c.Params("id"))
+	if err != nil {
+		return respostaErroFamilia(c, err, "Erro ao calcular cobrança da família")
+	}
+	if resumo.ResponsavelFinanceiro != nil {
+		protegerPII(role, resumo.ResponsavelFinanceiro)
+	}
+
+	return c.JSON(resumo)
+}
+
+// PagarCobrancaFamilia quita as assinaturas pendentes ou em atraso de todos os membros
+func PagarCobrancaFamilia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	clientes, err := services.PagarCobrancaFamilia(c.Params("id"), user["email"].(string))
+	if err != nil {
+		return respostaErroFamilia(c, err, "Erro ao registrar pagamento da família")
+	}
+	atualizarInadimplencia(clientes...)
+
+	resumo, err := services.ResumoCobrancaFamilia(c.Params("id"))
+	if err != nil {
+		return respostaErroFamilia(c, err, "Erro ao calcular cobrança da família")
+	}
+	if resumo.ResponsavelFinanceiro != nil {
+		protegerPII(role, resumo.ResponsavelFinanceiro)
+	}
+
+	return c.JSON(resumo)
+}
+
+// ListRegrasDesconto retorna as regras de desconto familiar
+func ListRegrasDesconto(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var regras []models.RegraDescontoFamilia
+	if err := config.DB.Order("a_partir_de").Find(&regras).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar regras de desconto"})
+	}
+
+	return c.JSON(regras)
+}
+
+// CreateRegraDesconto cria uma regra de desconto; apenas superadmin
+func CreateRegraDesconto(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var regra models.RegraDescontoFamilia
+	if err := c.BodyParser(&regra); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(regra); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	regra.ID = "" // Remove o ID enviado pelo cliente
+	if err := config.DB.Create(&regra).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma regra para esta quantidade de membros"})
+	}
+
+	return c.Status(201).JSON(regra)
+}
+
+// UpdateRegraDesconto altera uma regra de desconto; vale apenas para novas assinaturas
+func UpdateRegraDesconto(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var regra models.RegraDescontoFamilia
+	if err := config.DB.First(&regra, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Regra de desconto não encontrada"})
+	}
+
+	if err := c.BodyParser(&regra); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(regra); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	regra.ID = c.Params("id")
+	if err := config.DB.Save(&regra).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma regra para esta quantidade de membros"})
+	}
+
+	return c.JSON(regra)
+}
+
+// DeleteRegraDesconto remove uma regra de desconto
+func DeleteRegraDesconto(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	result := config.DB.Delete(&models.RegraDescontoFamilia{}, "id = ?", c.Params("id"))
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar regra de desconto"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Regra de desconto não encontrada"})
+	}
+
+	return c.SendStatus(204)
+}
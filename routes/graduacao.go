@@ -0,0 +1,514 @@
+package routes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	config "go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"go-api/services"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func SetupGraduacaoRoutes(app *fiber.App) {
+	faixaGroup := app.Group("/faixas", middleware.JWTMiddleware())
+
+	faixaGroup.Get("/", ListFaixas)
+	faixaGroup.Post("/", CreateFaixa)
+	faixaGroup.Put("/:id", UpdateFaixa)
+	faixaGroup.Delete("/:id", DeleteFaixa)
+
+	graduacaoGroup := app.Group("/graduacoes", middleware.JWTMiddleware())
+
+	graduacaoGroup.Get("/elegiveis", GetElegiveisGraduacao)
+	graduacaoGroup.Get("/:id/certificado", GetCertificadoGraduacao)
+
+	exameGroup := app.Group("/exames", middleware.JWTMiddleware())
+
+	exameGroup.Get("/", ListExames)
+	exameGroup.Get("/:id", GetExame)
+	exameGroup.Post("/", CreateExame)
+	exameGroup.Put("/:id", UpdateExame)
+	exameGroup.Post("/:id/cancelar", CancelExame)
+	exameGroup.Post("/:id/candidatos", AddCandidatosExame)
+	exameGroup.Delete("/:id/candidatos/:clienteId", RemoveCandidatoExame)
+	exameGroup.Post("/:id/resultados", RegistrarResultadosExame)
+}
+
+// Converte os erros de graduação em respostas HTTP
+func respostaErroGraduacao(c *fiber.Ctx, err error, mensagem string) error {
+	switch {
+	case errors.Is(err, services.ErrClienteNaoEncontrado):
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	case errors.Is(err, services.ErrExameNaoEncontrado),
+		errors.Is(err, services.ErrCandidatoNaoEncontrado),
+		errors.Is(err, services.ErrGraduacaoNaoEncontrada):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrFaixaNaoEncontrada),
+		errors.Is(err, services.ErrGrauInvalido),
+		errors.Is(err, services.ErrGraduacaoRetroativa),
+		errors.Is(err, services.ErrDataGraduacaoAnterior):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrSemProximaGraduacao),
+		errors.Is(err, services.ErrExameEncerrado):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrRequisitosNaoAtendidos):
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
+
+// ListFaixas retorna as faixas em ordem de progressão; ?modalidade_id= filtra
+func ListFaixas(c *fiber.Ctx) error {
+	query := config.DB.Order("modalidade_id, ordem")
+	if modalidade := c.Query("modalidade_id"); modalidade != "" {
+		query = query.Where("modalidade_id = ?", modalidade)
+	}
+
+	var faixas []models.Faixa
+	if err := query.Find(&faixas).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar faixas"})
+	}
+
+	return c.JSON(faixas)
+}
+
+func CreateFaixa(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var faixa models.Faixa
+	if err := c.BodyParser(&faixa); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(faixa); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	var modalidade models.Modalidade
+	if err := config.DB.First(&modalidade, "id = ?", faixa.ModalidadeID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Modalidade não encontrada"})
+	}
+
+	faixa.ID = "" // Remove o ID enviado pelo cliente
+	if err := config.DB.Omit("Modalidade").Create(&faixa).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma faixa com esta ordem na modalidade"})
+	}
+
+	return c.Status(201).JSON(faixa)
+}
+
+func UpdateFaixa(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var faixa models.Faixa
+	if err := config.DB.First(&faixa, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Faixa não encontrada"})
+	}
+	modalidadeID := faixa.ModalidadeID
+
+	if err := c.BodyParser(&faixa); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(faixa); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	// A faixa não pode mudar de modalidade, pois já pode haver graduações nela
+	faixa.ID = c.Params("id")
+	faixa.ModalidadeID = modalidadeID
+	if err := config.DB.Omit("Modalidade").Save(&faixa).Error; err != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe uma faixa com esta ordem na modalidade"})
+	}
+
+	return c.JSON(faixa)
+}
+
+// DeleteFaixa remove uma faixa que ainda não foi usada em graduações ou exames
+func DeleteFaixa(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	result := config.DB.Delete(&models.Faixa{}, "id = ?", c.Params("id"))
+	if result.Error != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Faixa já usada em graduações ou exames"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Faixa não encontrada"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// ListGraduacoesCliente retorna o histórico de graduações do cliente, da mais recente
+func ListGraduacoesCliente(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	query := config.DB.Preload("Modalidade").Preload("Faixa").Preload("Instrutor").Where("cliente_id = ?", c.Params("id"))
+	if modalidade := c.Query("modalidade_id"); modalidade != "" {
+		query = query.Where("modalidade_id = ?", modalidade)
+	}
+
+	var graduacoes []models.Graduacao
+	if err := query.Order("data DESC, created_at DESC").Find(&graduacoes).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar graduações"})
+	}
+
+	return c.JSON(graduacoes)
+}
+
+// PromoverCliente registra uma promoção de faixa ou grau. "forcar" ignora os
+// requisitos de tempo e frequência e é permitido apenas ao superadmin.
+func PromoverCliente(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var promocao services.PromocaoGraduacao
+	if err := c.BodyParser(&promocao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(promocao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+	if promocao.Forcar && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Apenas o superadmin pode ignorar os requisitos"})
+	}
+
+	graduacao, err := services.PromoverCliente(c.Params("id"), promocao, nil, user["email"].(string))
+	if err != nil {
+		return respostaErroGraduacao(c, err, "Erro ao registrar graduação")
+	}
+
+	return c.Status(201).JSON(graduacao)
+}
+
+// GetElegibilidadeCliente mostra se o cliente pode ser promovido na ?modalidade_id=
+func GetElegibilidadeCliente(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	modalidade := c.Query("modalidade_id")
+	if modalidade == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Informe a modalidade_id"})
+	}
+
+	elegibilidade, err := services.VerificarElegibilidade(config.DB, c.Params("id"), modalidade, time.Now())
+	if err != nil {
+		return respostaErroGraduacao(c, err, "Erro ao verificar elegibilidade")
+	}
+
+	return c.JSON(elegibilidade)
+}
+
+// GetElegiveisGraduacao lista os clientes aptos à próxima graduação na ?modalidade_id=
+func GetElegiveisGraduacao(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	modalidade := c.Query("modalidade_id")
+	if modalidade == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Informe a modalidade_id"})
+	}
+
+	elegiveis, err := services.ListarElegiveis(modalidade, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar elegíveis"})
+	}
+
+	return c.JSON(elegiveis)
+}
+
+// GetCertificadoGraduacao gera o certificado da graduação em PDF
+func GetCertificadoGraduacao(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var graduacao models.Graduacao
+	if err := config.DB.Select("id").First(&graduacao, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Graduação não encontrada"})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="certificado-%s.pdf"`, id))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := services.GerarCertificado(id, w); err != nil {
+			log.Println("Erro ao gerar certificado:", err)
+		}
+		w.Flush()
+	})
+
+	return nil
+}
+
+// ListExames retorna os exames; aceita ?modalidade_id= e ?status=
+func ListExames(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	query := config.DB.Preload("Modalidade").Order("data DESC")
+	if modalidade := c.Query("modalidade_id"); modalidade != "" {
+		query = query.Where("modalidade_id = ?", modalidade)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var exames []models.ExameGraduacao
+	if err := query.Find(&exames).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar exames"})
+	}
+
+	return c.JSON(exames)
+}
+
+// GetExame retorna o exame com a lista de candidatos
+func GetExame(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var exame models.ExameGraduacao
+	if err := config.DB.Preload("Modalidade").Preload("Avaliador").
+		Preload("Candidatos.Cliente").Preload("Candidatos.FaixaPretendida").
+		First(&exame, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Exame não encontrado"})
+	}
+
+	for i := range exame.Candidatos {
+		if exame.Candidatos[i].Cliente != nil {
+			protegerPII(role, exame.Candidatos[i].Cliente)
+		}
+	}
+
+	return c.JSON(exame)
+}
+
+func CreateExame(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var exame models.ExameGraduacao
+	if err := c.BodyParser(&exame); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(exame); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	var modalidade models.Modalidade
+	if err := config.DB.First(&modalidade, "id = ?", exame.ModalidadeID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Modalidade não encontrada"})
+	}
+
+	exame.ID = "" // Remove o ID enviado pelo cliente
+	exame.Status = models.ExameAgendado
+	exame.Candidatos = nil
+	if err := config.DB.Omit("Modalidade", "Avaliador").Create(&exame).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar exame"})
+	}
+
+	return c.Status(201).JSON(exame)
+}
+
+// UpdateExame altera data, local, descrição e avaliador de um exame agendado
+func UpdateExame(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var exame models.ExameGraduacao
+	if err := config.DB.First(&exame, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Exame não encontrado"})
+	}
+	if exame.Status != models.ExameAgendado {
+		return c.Status(409).JSON(fiber.Map{"error": services.ErrExameEncerrado.Error()})
+	}
+	modalidadeID := exame.ModalidadeID
+
+	if err := c.BodyParser(&exame); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(exame); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	// Modalidade e situação não mudam por aqui; os candidatos têm rotas próprias
+	exame.ID = c.Params("id")
+	exame.ModalidadeID = modalidadeID
+	exame.Status = models.ExameAgendado
+	exame.Candidatos = nil
+	if err := config.DB.Omit("Modalidade", "Avaliador", "Candidatos").Save(&exame).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar exame"})
+	}
+
+	return c.JSON(exame)
+}
+
+// CancelExame cancela um exame agendado
+func CancelExame(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	result := config.DB.Model(&models.ExameGraduacao{}).
+		Where("id = ? AND status = ?", c.Params("id"), models.ExameAgendado).
+		Update("status", models.ExameCancelado)
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao cancelar exame"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Exame agendado não encontrado"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// AddCandidatosExame inscreve os clientes de "cliente_ids" no exame, conferindo
+// os requisitos salvo com "forcar"; sem a lista, inscreve todos os elegíveis
+// da modalidade na data do exame
+func AddCandidatosExame(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		ClienteIDs []string `json:"cliente_ids"`
+		Forcar     bool     `json:"forcar"` // Inscreve os clientes da lista mesmo sem atender aos requisitos
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+		}
+	}
+
+	inscritos, err := services.InscreverCandidatos(c.Params("id"), req.ClienteIDs, req.Forcar)
+	if err != nil {
+		return respostaErroGraduacao(c, err, "Erro ao inscrever candidatos")
+	}
+
+	return c.Status(201).JSON(inscritos)
+}
+
+// RemoveCandidatoExame retira um candidato ainda sem resultado do exame
+func RemoveCandidatoExame(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	result := config.DB.Where("exame_id = ? AND cliente_id = ? AND resultado = ?", c.Params("id"), c.Params("clienteId"), models.ResultadoPendente).
+		Delete(&models.CandidatoExame{})
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover candidato"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Candidato pendente não encontrado"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// RegistrarResultadosExame lança os resultados dos candidatos; os aprovados são promovidos
+func RegistrarResultadosExame(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		Resultados []services.ResultadoCandidato `json:"resultados" validate:"required,min=1,dive"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	exame, err := services.RegistrarResultadosExame(c.Params("id"), req.Resultados, user["email"].(string))
+	if err != nil {
+		return respostaErroGraduacao(c, err, "Erro ao registrar resultados")
+	}
+
+	for i := range exame.Candidatos {
+		if exame.Candidatos[i].Cliente != nil {
+			protegerPII(role, exame.Candidatos[i].Cliente)
+		}
+	}
+
+	return c.JSON(exame)
+}
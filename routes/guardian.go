@@ -0,0 +1,142 @@
+package routes
+
+import (
+	"go-api/db"
+	"go-api/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ListGuardians retorna os responsáveis de um cliente
+func ListGuardians(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	var guardians []models.Guardian
+	if err := config.DB.Where("cliente_id = ?", id).Order("created_at").Find(&guardians).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar responsáveis"})
+	}
+
+	protegerPIIResponsaveis(role, guardians)
+	return c.JSON(guardians)
+}
+
+// CreateGuardian adiciona um responsável ao cliente
+func CreateGuardian(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	var guardian models.Guardian
+	if err := c.BodyParser(&guardian); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(guardian); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	guardian.ID = "" // Remove o ID enviado pelo cliente
+	guardian.ClienteID = cliente.ID
+	if err := config.DB.Create(&guardian).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar responsável"})
+	}
+
+	protegerPIIResponsavel(role, &guardian)
+	return c.Status(201).JSON(guardian)
+}
+
+// UpdateGuardian atualiza ou substitui os dados de um responsável
+func UpdateGuardian(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	guardianID := c.Params("guardianId")
+	var guardian models.Guardian
+	if err := config.DB.First(&guardian, "id = ? AND cliente_id = ?", guardianID, id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Responsável não encontrado"})
+	}
+	gravado := guardian
+
+	if err := c.BodyParser(&guardian); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	restaurarPIIResponsavel(&guardian, &gravado)
+
+	// Validação dos dados
+	if err := validate.Struct(guardian); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	// Garantir que o responsável continue vinculado ao mesmo cliente
+	guardian.ID = guardianID
+	guardian.ClienteID = id
+	if err := config.DB.Save(&guardian).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar responsável"})
+	}
+
+	protegerPIIResponsavel(role, &guardian)
+	return c.JSON(guardian)
+}
+
+// DeleteGuardian remove um responsável, impedindo que um menor fique sem nenhum
+func DeleteGuardian(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	guardianID := c.Params("guardianId")
+	var guardian models.Guardian
+	if err := config.DB.First(&guardian, "id = ? AND cliente_id = ?", guardianID, id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Responsável não encontrado"})
+	}
+
+	if cliente.MenorDeIdade() {
+		var total int64
+		config.DB.Model(&models.Guardian{}).Where("cliente_id = ? AND arquivado_em IS NULL", id).Count(&total)
+		if total <= 1 {
+			return c.Status(409).JSON(fiber.Map{"error": "Cliente menor de idade precisa de pelo menos um responsável"})
+		}
+	}
+
+	if err := config.DB.Delete(&guardian).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar responsável"})
+	}
+
+	return c.SendStatus(204)
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"go-api/services"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ImportClientes importa clientes de um arquivo CSV ou XLSX enviado no campo "arquivo".
+// O campo "mapeamento" aceita um JSON campo -> coluna e "dry_run=true" apenas valida as linhas.
+func ImportClientes(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	arquivo, err := c.FormFile("arquivo")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Arquivo não enviado"})
+	}
+
+	mapeamento := map[string]string{}
+	if valor := c.FormValue("mapeamento"); valor != "" {
+		if err := json.Unmarshal([]byte(valor), &mapeamento); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Mapeamento de colunas inválido", "details": err.Error()})
+		}
+	}
+
+	aberto, err := arquivo.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao ler arquivo"})
+	}
+	defer aberto.Close()
+
+	conteudo, err := io.ReadAll(aberto)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao ler arquivo"})
+	}
+
+	planilha, err := services.LerPlanilha(arquivo.Filename, conteudo)
+	if err != nil {
+		if errors.Is(err, services.ErrFormatoNaoSuportado) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao ler planilha", "details": err.Error()})
+	}
+
+	linhas, err := services.ValidarImportacao(planilha, mapeamento)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Erro ao validar planilha", "details": err.Error()})
+	}
+
+	// Em modo de simulação apenas o resultado da validação é retornado
+	if c.FormValue("dry_run") == "true" || c.Query("dry_run") == "true" {
+		validas := 0
+		erros := []services.ErroLinha{}
+		for _, linha := range linhas {
+			if len(linha.Erros) == 0 {
+				validas++
+				continue
+			}
+			erros = append(erros, services.ErroLinha{Linha: linha.Linha, Erros: linha.Erros})
+		}
+
+		return c.JSON(fiber.Map{
+			"total":     len(linhas),
+			"validas":   validas,
+			"invalidas": len(linhas) - validas,
+			"erros":     erros,
+		})
+	}
+
+	job, err := services.IniciarImportacao(linhas, user["email"].(string))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar importação"})
+	}
+
+	return c.Status(202).JSON(job)
+}
+
+// GetImportacao retorna o progresso de uma importação
+func GetImportacao(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	job, ok := services.BuscarImportacao(c.Params("jobId"))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "Importação não encontrada"})
+	}
+
+	return c.JSON(job)
+}
@@ -0,0 +1,360 @@
+package routes
+
+import (
+	"errors"
+	config "go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"go-api/services"
+	"go-api/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+func SetupLeadRoutes(app *fiber.App) {
+	leadGroup := app.Group("/leads", middleware.JWTMiddleware())
+
+	leadGroup.Get("/", ListLeads)
+	leadGroup.Get("/funil", GetFunilLeads)
+	leadGroup.Get("/:id", GetLead)
+	leadGroup.Post("/", CreateLead)
+	leadGroup.Put("/:id", UpdateLead)
+	leadGroup.Delete("/:id", DeleteLead)
+	leadGroup.Put("/:id/status", UpdateStatusLead)
+	leadGroup.Post("/:id/aulas-experimentais", AgendarAulaExperimental)
+	leadGroup.Put("/:id/aulas-experimentais/:experimentalId", RegistrarAulaExperimental)
+	leadGroup.Post("/:id/converter", ConverterLead)
+}
+
+// Converte os erros do funil de leads em respostas HTTP
+func respostaErroLead(c *fiber.Ctx, err error, mensagem string) error {
+	switch {
+	case errors.Is(err, services.ErrLeadNaoEncontrado),
+		errors.Is(err, services.ErrAulaNaoEncontrada),
+		errors.Is(err, services.ErrAulaExperimentalNaoEncontrada):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrConversaoInvalida),
+		errors.Is(err, services.ErrResponsavelLeadInvalido),
+		errors.Is(err, services.ErrMotivoPerdaObrigatorio),
+		errors.Is(err, services.ErrAulaForaDoDia),
+		errors.Is(err, services.ErrAulaCancelada),
+		errors.Is(err, services.ErrPlanoNaoEncontrado),
+		errors.Is(err, services.ErrVendedorNaoEncontrado):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrResponsavelObrigatorio):
+		return c.Status(400).JSON(fiber.Map{"error": "Cliente menor de idade precisa de pelo menos um responsável"})
+	case errors.Is(err, services.ErrClienteDuplicado):
+		return c.Status(409).JSON(fiber.Map{"error": "Já existe um cliente com este CPF ou e-mail"})
+	case errors.Is(err, services.ErrLeadEncerrado),
+		errors.Is(err, services.ErrTransicaoLeadInvalida),
+		errors.Is(err, services.ErrAulaLotada),
+		errors.Is(err, services.ErrAulaExperimentalJaRegistrada):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
+
+// Mascara o e-mail e o telefone do lead para quem não pode ver dados pessoais
+func protegerPIILead(role string, leads ...*models.Lead) {
+	if utils.PodeVerPII(role) {
+		return
+	}
+
+	for _, lead := range leads {
+		lead.Email = utils.MascararEmail(lead.Email)
+		lead.Telefone = utils.MascararTelefone(lead.Telefone)
+		if lead.Cliente != nil {
+			protegerPII(role, lead.Cliente)
+		}
+	}
+}
+
+// ListLeads lista os leads com filtros ?status=, ?origem=, ?responsavel_id=,
+// ?modalidade_id= e ?contato_ate=AAAA-MM-DD para os retornos pendentes
+func ListLeads(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	query := config.DB.Preload("Modalidade").Preload("Responsavel").Order("created_at DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if origem := c.Query("origem"); origem != "" {
+		query = query.Where("origem = ?", origem)
+	}
+	if responsavel := c.Query("responsavel_id"); responsavel != "" {
+		query = query.Where("responsavel_id = ?", responsavel)
+	}
+	if modalidade := c.Query("modalidade_id"); modalidade != "" {
+		query = query.Where("modalidade_id = ?", modalidade)
+	}
+	if contatoAte := c.Query("contato_ate"); contatoAte != "" {
+		if _, err := time.Parse("2006-01-02", contatoAte); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+		}
+		query = query.Where("proximo_contato_em <= ? AND status NOT IN ?", contatoAte,
+			[]models.StatusLead{models.LeadConvertido, models.LeadPerdido})
+	}
+
+	var leads []models.Lead
+	if err := query.Find(&leads).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar leads"})
+	}
+
+	for i := range leads {
+		protegerPIILead(role, &leads[i])
+	}
+	return c.JSON(leads)
+}
+
+// GetLead retorna o lead com as aulas experimentais e o cliente gerado na conversão
+func GetLead(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var lead models.Lead
+	if err := config.DB.Preload("Modalidade").Preload("Responsavel").Preload("Cliente").
+		Preload("AulasExperimentais", func(tx *gorm.DB) *gorm.DB { return tx.Order("data DESC") }).
+		Preload("AulasExperimentais.Aula").
+		First(&lead, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Lead não encontrado"})
+	}
+
+	protegerPIILead(role, &lead)
+	return c.JSON(lead)
+}
+
+func CreateLead(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var lead models.Lead
+	if err := c.BodyParser(&lead); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(lead); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if err := services.CriarLead(&lead, user["email"].(string)); err != nil {
+		return respostaErroLead(c, err, "Erro ao criar lead")
+	}
+
+	protegerPIILead(role, &lead)
+	return c.Status(201).JSON(lead)
+}
+
+func UpdateLead(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var lead models.Lead
+	if err := config.DB.First(&lead, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Lead não encontrado"})
+	}
+
+	if err := c.BodyParser(&lead); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(lead); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	lead.ID = c.Params("id")
+	if err := services.AtualizarLead(&lead, user["email"].(string)); err != nil {
+		return respostaErroLead(c, err, "Erro ao atualizar lead")
+	}
+
+	protegerPIILead(role, &lead)
+	return c.JSON(lead)
+}
+
+// DeleteLead exclui um lead cadastrado por engano; leads convertidos são mantidos
+func DeleteLead(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var lead models.Lead
+	if err := config.DB.First(&lead, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Lead não encontrado"})
+	}
+	if lead.Status == models.LeadConvertido {
+		return c.Status(409).JSON(fiber.Map{"error": "Lead convertido não pode ser excluído"})
+	}
+
+	if err := config.DB.Delete(&lead).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao excluir lead"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// UpdateStatusLead move o lead no funil; "perdido" exige o motivo
+func UpdateStatusLead(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		Status models.StatusLead `json:"status" validate:"required,oneof=novo aula_agendada compareceu perdido"`
+		Motivo string            `json:"motivo"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	lead, err := services.AlterarStatusLead(c.Params("id"), req.Status, req.Motivo, user["email"].(string))
+	if err != nil {
+		return respostaErroLead(c, err, "Erro ao alterar etapa do lead")
+	}
+
+	protegerPIILead(role, lead)
+	return c.JSON(lead)
+}
+
+// AgendarAulaExperimental reserva uma aula da grade para o lead na data informada
+func AgendarAulaExperimental(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		AulaID string `json:"aula_id" validate:"required"`
+		Data   string `json:"data" validate:"required,datetime=2006-01-02"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	data, _ := time.ParseInLocation("2006-01-02", req.Data, time.Local)
+	agora := time.Now()
+	if data.Before(time.Date(agora.Year(), agora.Month(), agora.Day(), 0, 0, 0, 0, time.Local)) {
+		return c.Status(400).JSON(fiber.Map{"error": "A aula experimental não pode ser agendada no passado"})
+	}
+
+	experimental, err := services.AgendarAulaExperimental(c.Params("id"), req.AulaID, data, user["email"].(string))
+	if err != nil {
+		return respostaErroLead(c, err, "Erro ao agendar aula experimental")
+	}
+
+	return c.Status(201).JSON(experimental)
+}
+
+// RegistrarAulaExperimental registra o comparecimento, a falta ou o cancelamento
+func RegistrarAulaExperimental(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		Status models.StatusAulaExperimental `json:"status" validate:"required,oneof=compareceu faltou cancelada"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	experimental, err := services.RegistrarAulaExperimental(c.Params("id"), c.Params("experimentalId"), req.Status, user["email"].(string))
+	if err != nil {
+		return respostaErroLead(c, err, "Erro ao registrar aula experimental")
+	}
+
+	return c.JSON(experimental)
+}
+
+// ConverterLead cria o cliente e a primeira assinatura a partir do lead. Nome,
+// e-mail, telefone, nascimento e plano não informados vêm do próprio lead.
+func ConverterLead(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req services.ConversaoLead
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if req.Pais == nil {
+		req.Pais = req.Cliente.Pais
+	}
+	req.Cliente.Pais = nil
+
+	if err := services.ConverterLead(c.Params("id"), &req, user["email"].(string)); err != nil {
+		if errors.Is(err, services.ErrConversaoInvalida) {
+			return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+		}
+		return respostaErroLead(c, err, "Erro ao converter lead")
+	}
+	atualizarInadimplencia(req.Cliente.ID)
+
+	protegerPII(role, &req.Cliente)
+	return c.Status(201).JSON(fiber.Map{"cliente": req.Cliente, "assinatura": req.Assinatura})
+}
+
+// GetFunilLeads resume os leads criados no período por etapa e origem, com a taxa de conversão
+func GetFunilLeads(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	de, ate, err := periodoRelatorio(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	funil, err := services.ResumoFunilLeads(de, ate)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao gerar funil de leads"})
+	}
+
+	return c.JSON(funil)
+}
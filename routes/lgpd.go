@@ -0,0 +1,57 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"go-api/services"
+	"go-api/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ExportLGPD gera o pacote com todos os dados pessoais do titular em JSON
+func ExportLGPD(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	// Apenas papéis com acesso a dados pessoais podem exportar
+	if !utils.PodeVerPII(role) {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	pacote, err := services.ExportarDadosLGPD(id, user["email"].(string))
+	if err != nil {
+		if errors.Is(err, services.ErrClienteNaoEncontrado) {
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao exportar dados do cliente"})
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="lgpd-%s.json"`, id))
+	return c.JSON(pacote)
+}
+
+// AnonymizeLGPD anonimiza de forma irreversível os dados pessoais do titular
+func AnonymizeLGPD(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	if err := services.AnonimizarCliente(id, user["email"].(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrClienteAnonimizado):
+			return c.Status(409).JSON(fiber.Map{"error": "Cliente já foi anonimizado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao anonimizar cliente"})
+	}
+
+	return c.SendStatus(204)
+}
@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"errors"
+	config "go-api/db"
+	"go-api/models"
+	"go-api/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Converte os erros de matrícula em respostas HTTP
+func respostaErroMatricula(c *fiber.Ctx, err error, mensagem string) error {
+	var recusada *services.MatriculaRecusadaError
+	switch {
+	case errors.As(err, &recusada):
+		return c.Status(422).JSON(fiber.Map{"error": "Matrícula recusada", "pendencias": recusada.Pendencias})
+	case errors.Is(err, services.ErrClienteNaoEncontrado):
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	case errors.Is(err, services.ErrAulaNaoEncontrada), errors.Is(err, services.ErrMatriculaNaoEncontrada):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrMatriculaDuplicada):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
+
+// ListMatriculasAula retorna os matriculados, as vagas livres e a lista de espera em ordem
+func ListMatriculasAula(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	matriculas, err := services.ListarMatriculasAula(c.Params("id"))
+	if err != nil {
+		return respostaErroMatricula(c, err, "Erro ao buscar matrículas")
+	}
+
+	for i := range matriculas.Ativas {
+		protegerPII(role, matriculas.Ativas[i].Cliente)
+	}
+	for i := range matriculas.Espera {
+		protegerPII(role, matriculas.Espera[i].Cliente)
+	}
+
+	return c.JSON(matriculas)
+}
+
+// MatricularCliente matricula o cliente na aula ou o coloca na lista de espera
+// se ela estiver lotada. Apenas o superadmin pode forçar uma matrícula com pendências.
+func MatricularCliente(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		ClienteID string `json:"cliente_id" validate:"required"`
+		Forcar    bool   `json:"forcar"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+	if req.Forcar && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Apenas o superadmin pode forçar a matrícula"})
+	}
+
+	matricula, err := services.MatricularCliente(c.Params("id"), req.ClienteID, req.Forcar, user["email"].(string))
+	if err != nil {
+		return respostaErroMatricula(c, err, "Erro ao registrar matrícula")
+	}
+
+	return c.Status(201).JSON(matricula)
+}
+
+// CancelarMatricula retira o cliente da aula ou da lista de espera; ?motivo= é registrado
+func CancelarMatricula(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	err := services.CancelarMatricula(c.Params("id"), c.Params("clienteId"), c.Query("motivo"), user["email"].(string))
+	if err != nil {
+		return respostaErroMatricula(c, err, "Erro ao cancelar matrícula")
+	}
+
+	return c.SendStatus(204)
+}
+
+// PromoverListaEspera oferece as vagas livres da aula à lista de espera
+func PromoverListaEspera(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	promovidas, err := services.PromoverListaEspera(c.Params("id"))
+	if err != nil {
+		return respostaErroMatricula(c, err, "Erro ao promover lista de espera")
+	}
+
+	for i := range promovidas {
+		protegerPII(role, promovidas[i].Cliente)
+	}
+
+	return c.JSON(promovidas)
+}
+
+// ListMatriculasCliente retorna as matrículas do cliente; ?todas=true inclui as canceladas
+func ListMatriculasCliente(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	query := config.DB.Preload("Aula.Modalidade").Where("cliente_id = ?", c.Params("id"))
+	if !c.QueryBool("todas") {
+		query = query.Where("status <> ?", models.MatriculaCancelada)
+	}
+
+	var matriculas []models.Matricula
+	if err := query.Order("created_at DESC").Find(&matriculas).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar matrículas"})
+	}
+
+	return c.JSON(matriculas)
+}
@@ -0,0 +1,197 @@
+package routes
+
+import (
+	"errors"
+	"go-api/db"
+	"go-api/models"
+	"go-api/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ListNotas retorna as notas do cliente visíveis ao usuário
+func ListNotas(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	var notas []models.Nota
+	if err := config.DB.Where("cliente_id = ?", id).Order("created_at DESC").Find(&notas).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar notas"})
+	}
+
+	visiveis := []models.Nota{}
+	for _, nota := range notas {
+		if nota.VisivelPara(user["email"].(string), role) {
+			visiveis = append(visiveis, nota)
+		}
+	}
+
+	return c.JSON(visiveis)
+}
+
+// CreateNota adiciona uma nota ao cliente em nome do usuário autenticado
+func CreateNota(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	var nota models.Nota
+	if err := c.BodyParser(&nota); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(nota); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	nota.ID = "" // Remove o ID enviado pelo cliente
+	nota.ClienteID = id
+	nota.Autor = user["email"].(string)
+	if err := config.DB.Create(&nota).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar nota"})
+	}
+
+	return c.Status(201).JSON(nota)
+}
+
+// UpdateNota altera o texto ou a visibilidade de uma nota; apenas o autor ou um superadmin
+func UpdateNota(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var nota models.Nota
+	if err := config.DB.First(&nota, "id = ? AND cliente_id = ?", c.Params("notaId"), c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Nota não encontrada"})
+	}
+
+	if nota.Autor != user["email"].(string) && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Apenas o autor pode alterar a nota"})
+	}
+
+	type NotaRequest struct {
+		Texto        string                  `json:"texto" validate:"required"`
+		Visibilidade models.VisibilidadeNota `json:"visibilidade" validate:"omitempty,oneof=equipe privada administracao"`
+	}
+
+	var req NotaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	nota.Texto = req.Texto
+	if req.Visibilidade != "" {
+		nota.Visibilidade = req.Visibilidade
+	}
+	if err := config.DB.Save(&nota).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar nota"})
+	}
+
+	return c.JSON(nota)
+}
+
+// DeleteNota remove uma nota; apenas o autor ou um superadmin
+func DeleteNota(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var nota models.Nota
+	if err := config.DB.First(&nota, "id = ? AND cliente_id = ?", c.Params("notaId"), c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Nota não encontrada"})
+	}
+
+	if nota.Autor != user["email"].(string) && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Apenas o autor pode remover a nota"})
+	}
+
+	if err := config.DB.Delete(&nota).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar nota"})
+	}
+
+	return c.SendStatus(204)
+}
+
+// SetClienteTags substitui as tags do cliente por {"tags": ["nome", ...]}
+func SetClienteTags(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	type TagsRequest struct {
+		Tags []string `json:"tags"`
+	}
+
+	var req TagsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	tags, err := services.DefinirTags(c.Params("id"), req.Tags, user["email"].(string))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrTagInvalida):
+			return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar tags"})
+	}
+
+	return c.JSON(tags)
+}
+
+// GetTimeline retorna a linha do tempo do cliente; ?limite= restringe a quantidade de itens
+func GetTimeline(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	itens, err := services.Timeline(c.Params("id"), user["email"].(string), role, c.QueryInt("limite", 100))
+	if err != nil {
+		if errors.Is(err, services.ErrClienteNaoEncontrado) {
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao montar linha do tempo"})
+	}
+
+	return c.JSON(itens)
+}
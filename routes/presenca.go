@@ -0,0 +1,247 @@
+package routes
+
+import (
+	"errors"
+	"go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"go-api/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func SetupPresencaRoutes(app *fiber.App) {
+	app.Post("/checkin", middleware.JWTMiddleware(), Checkin)
+
+	presencaGroup := app.Group("/presencas", middleware.JWTMiddleware())
+
+	presencaGroup.Get("/", ListPresencas)
+	presencaGroup.Get("/relatorio/clientes", GetRelatorioPresencaClientes)
+	presencaGroup.Get("/relatorio/aulas", GetRelatorioPresencaAulas)
+	presencaGroup.Get("/relatorio/instrutores", GetRelatorioPresencaInstrutores)
+	presencaGroup.Delete("/:id", DeletePresenca)
+}
+
+// Lê o período de ?de= e ?ate= (AAAA-MM-DD); por padrão, os últimos 30 dias
+func periodoRelatorio(c *fiber.Ctx) (time.Time, time.Time, error) {
+	ate := time.Now()
+	de := ate.AddDate(0, 0, -30)
+
+	if valor := c.Query("de"); valor != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", valor, time.Local)
+		if err != nil {
+			return de, ate, err
+		}
+		de = parsed
+	}
+	if valor := c.Query("ate"); valor != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", valor, time.Local)
+		if err != nil {
+			return de, ate, err
+		}
+		ate = parsed
+	}
+	return de, ate, nil
+}
+
+// Checkin registra a presença do cliente, identificado por cliente_id, cpf ou
+// qr_token, em uma aula. Apenas admin e superadmin podem forçar um check-in
+// com pendências.
+func Checkin(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	var solicitacao services.SolicitacaoCheckin
+	if err := c.BodyParser(&solicitacao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(solicitacao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+	if solicitacao.Forcar && role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Apenas administradores podem forçar o check-in"})
+	}
+
+	presenca, err := services.RegistrarCheckin(solicitacao, user["email"].(string))
+	if err != nil {
+		var recusado *services.CheckinRecusadoError
+		switch {
+		case errors.As(err, &recusado):
+			return c.Status(422).JSON(fiber.Map{"error": "Check-in recusado", "pendencias": recusado.Pendencias})
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrAulaNaoEncontrada):
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrCheckinDuplicado):
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrTokenInvalido):
+			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrIdentificacaoCheckin),
+			errors.Is(err, services.ErrAulaForaDoDia),
+			errors.Is(err, services.ErrAulaCancelada):
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao registrar check-in"})
+	}
+
+	return c.Status(201).JSON(presenca)
+}
+
+// ListPresencas lista os check-ins do período com filtros ?cliente_id=, ?aula_id=,
+// ?instrutor_id= e ?irregular=true
+func ListPresencas(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	de, ate, err := periodoRelatorio(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+	}
+
+	query := config.DB.Preload("Cliente").Preload("Aula").
+		Where("data >= ? AND data <= ?", de.Format("2006-01-02"), ate.Format("2006-01-02"))
+	if cliente := c.Query("cliente_id"); cliente != "" {
+		query = query.Where("cliente_id = ?", cliente)
+	}
+	if aula := c.Query("aula_id"); aula != "" {
+		query = query.Where("aula_id = ?", aula)
+	}
+	if instrutor := c.Query("instrutor_id"); instrutor != "" {
+		query = query.Where("instrutor_id = ?", instrutor)
+	}
+	if c.QueryBool("irregular") {
+		query = query.Where("irregular = ?", true)
+	}
+
+	var presencas []models.Presenca
+	if err := query.Order("data DESC, checkin_em DESC").Find(&presencas).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar presenças"})
+	}
+
+	for i := range presencas {
+		if presencas[i].Cliente != nil {
+			protegerPII(role, presencas[i].Cliente)
+		}
+	}
+
+	return c.JSON(presencas)
+}
+
+// ListPresencasCliente retorna o histórico de check-ins do cliente no período
+func ListPresencasCliente(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	de, ate, err := periodoRelatorio(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+	}
+
+	var presencas []models.Presenca
+	if err := config.DB.Preload("Aula").
+		Where("cliente_id = ? AND data >= ? AND data <= ?", c.Params("id"), de.Format("2006-01-02"), ate.Format("2006-01-02")).
+		Order("data DESC, checkin_em DESC").
+		Find(&presencas).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar presenças"})
+	}
+
+	return c.JSON(presencas)
+}
+
+// DeletePresenca desfaz um check-in registrado por engano
+func DeletePresenca(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var presenca models.Presenca
+	if err := config.DB.First(&presenca, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Presença não encontrada"})
+	}
+
+	if err := config.DB.Delete(&presenca).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar presença"})
+	}
+	registrarAuditoria("presenca", presenca.ID, presenca.ClienteID, "presenca.exclusao", user["email"].(string),
+		map[string]interface{}{"aula_id": presenca.AulaID, "data": presenca.Data.Format("2006-01-02")})
+
+	return c.SendStatus(204)
+}
+
+func GetRelatorioPresencaClientes(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	de, ate, err := periodoRelatorio(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+	}
+
+	relatorio, err := services.RelatorioPresencaPorCliente(de, ate)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao gerar relatório de presenças"})
+	}
+
+	return c.JSON(relatorio)
+}
+
+func GetRelatorioPresencaAulas(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	de, ate, err := periodoRelatorio(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+	}
+
+	relatorio, err := services.RelatorioPresencaPorAula(de, ate)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao gerar relatório de presenças"})
+	}
+
+	return c.JSON(relatorio)
+}
+
+func GetRelatorioPresencaInstrutores(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	de, ate, err := periodoRelatorio(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+	}
+
+	relatorio, err := services.RelatorioPresencaPorInstrutor(de, ate)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao gerar relatório de presenças"})
+	}
+
+	return c.JSON(relatorio)
+}
@@ -1,13 +1,16 @@
 package routes
 
 import (
+	"errors"
 	config "go-api/db"
 	"go-api/middleware"
 	"go-api/models"
+	"go-api/services"
 	"go-api/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 func SetupProductRoutes(app *fiber.App) {
@@ -18,6 +21,7 @@ func SetupProductRoutes(app *fiber.App) {
 	produtoGroup.Post("/", CreateProduto)
 	produtoGroup.Put("/:id", EditProduto)
 	produtoGroup.Delete("/:id", DelProdutos)
+	produtoGroup.Put("/:id/modalidades", SetModalidadesPlano)
 }
 
 func GetProdutos(c *fiber.Ctx) error {
@@ -62,7 +66,7 @@ func GetProduto(c *fiber.Ctx) error {
 
 	case models.Servico:
 		var produtoServico models.ProdutoServico
-		if err := config.DB.Where("id = ?", id).First(&produtoServico).Error; err != nil {
+		if err := config.DB.Preload("Modalidades").Where("id = ?", id).First(&produtoServico).Error; err != nil {
 			return c.Status(404).JSON(fiber.Map{"error": "Detalhes do serviço não encontrados"})
 		}
 		return c.JSON(produtoServico)
@@ -218,9 +222,49 @@ func DelProdutos(c *fiber.Ctx) error {
 	}
 
 	// Deletar o produto e seus detalhes específicos
-	if err := config.DB.Delete(&produto).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).Delete(&models.ProdutoFisico{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", id).Delete(&models.ProdutoServico{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&produto).Error
+	})
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar produto"})
 	}
 
 	return c.SendStatus(204)
 }
+
+// SetModalidadesPlano define as modalidades às quais o plano dá acesso.
+// Uma lista vazia libera todas as modalidades.
+func SetModalidadesPlano(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		ModalidadeIDs []string `json:"modalidade_ids"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	plano, err := services.DefinirModalidadesPlano(c.Params("id"), req.ModalidadeIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPlanoNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, services.ErrModalidadeInvalida):
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao definir modalidades do plano"})
+	}
+
+	return c.JSON(plano)
+}
@@ -0,0 +1,406 @@
+package routes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	config "go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"go-api/services"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+func SetupRemuneracaoRoutes(app *fiber.App) {
+	remuneracaoGroup := app.Group("/remuneracao", middleware.JWTMiddleware())
+
+	remuneracaoGroup.Get("/regras", ListRegrasRemuneracao)
+	remuneracaoGroup.Post("/regras", CreateRegraRemuneracao)
+	remuneracaoGroup.Put("/regras/:id", UpdateRegraRemuneracao)
+	remuneracaoGroup.Delete("/regras/:id", DeleteRegraRemuneracao)
+	remuneracaoGroup.Get("/extratos", ListExtratos)
+	remuneracaoGroup.Post("/extratos/calcular", CalcularExtratos)
+	remuneracaoGroup.Get("/extratos/:id", GetExtrato)
+	remuneracaoGroup.Put("/extratos/:id/ajuste", AjustarExtrato)
+	remuneracaoGroup.Put("/extratos/:id/status", UpdateStatusExtrato)
+	remuneracaoGroup.Get("/folha", ExportFolha)
+}
+
+// Converte os erros de remuneração em respostas HTTP
+func respostaErroRemuneracao(c *fiber.Ctx, err error, mensagem string) error {
+	switch {
+	case errors.Is(err, services.ErrRegraNaoEncontrada),
+		errors.Is(err, services.ErrExtratoNaoEncontrado),
+		errors.Is(err, services.ErrAulaNaoEncontrada),
+		errors.Is(err, services.ErrAulaMinistradaNaoEncontrada):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInstrutorInvalido),
+		errors.Is(err, services.ErrModalidadeInvalida),
+		errors.Is(err, services.ErrRegraRemuneracaoInvalida),
+		errors.Is(err, services.ErrCompetenciaInvalida),
+		errors.Is(err, services.ErrFormatoExportacao),
+		errors.Is(err, services.ErrAulaFutura),
+		errors.Is(err, services.ErrAulaForaDoDia),
+		errors.Is(err, services.ErrAulaCancelada):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrExtratoFechado),
+		errors.Is(err, services.ErrTransicaoExtratoInvalida),
+		errors.Is(err, services.ErrAulaMinistradaComPresencas):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
+
+// ListRegrasRemuneracao lista as regras com filtros ?instrutor_id= e ?vigentes=true
+func ListRegrasRemuneracao(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	query := config.DB.Preload("Instrutor").Preload("Modalidade").Order("instrutor_id, tipo, vigente_desde DESC")
+	if instrutor := c.Query("instrutor_id"); instrutor != "" {
+		query = query.Where("instrutor_id = ?", instrutor)
+	}
+	if c.QueryBool("vigentes") {
+		hoje := time.Now().Format("2006-01-02")
+		query = query.Where("vigente_desde <= ? AND (vigente_ate IS NULL OR vigente_ate >= ?)", hoje, hoje)
+	}
+
+	var regras []models.RegraRemuneracao
+	if err := query.Find(&regras).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar regras de remuneração"})
+	}
+
+	return c.JSON(regras)
+}
+
+func CreateRegraRemuneracao(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var regra models.RegraRemuneracao
+	if err := c.BodyParser(&regra); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(regra); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	regra.ID = "" // Remove o ID enviado pelo cliente
+	if err := services.SalvarRegraRemuneracao(&regra, user["email"].(string)); err != nil {
+		return respostaErroRemuneracao(c, err, "Erro ao criar regra de remuneração")
+	}
+
+	return c.Status(201).JSON(regra)
+}
+
+// UpdateRegraRemuneracao altera a regra; extratos já calculados guardam os valores usados
+func UpdateRegraRemuneracao(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var regra models.RegraRemuneracao
+	if err := config.DB.First(&regra, "id = ?", c.Params("id")).Error; err != nil {
+		return respostaErroRemuneracao(c, services.ErrRegraNaoEncontrada, "Erro ao buscar regra de remuneração")
+	}
+
+	if err := c.BodyParser(&regra); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(regra); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	regra.ID = c.Params("id")
+	if err := services.SalvarRegraRemuneracao(&regra, user["email"].(string)); err != nil {
+		return respostaErroRemuneracao(c, err, "Erro ao atualizar regra de remuneração")
+	}
+
+	return c.JSON(regra)
+}
+
+func DeleteRegraRemuneracao(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	result := config.DB.Delete(&models.RegraRemuneracao{}, "id = ?", c.Params("id"))
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar regra de remuneração"})
+	}
+	if result.RowsAffected == 0 {
+		return respostaErroRemuneracao(c, services.ErrRegraNaoEncontrada, "")
+	}
+	registrarAuditoria("regra_remuneracao", c.Params("id"), "", "remuneracao.regra_remocao", user["email"].(string), nil)
+
+	return c.SendStatus(204)
+}
+
+// Instrutores sem perfil administrativo veem apenas os próprios extratos
+func extratosVisiveis(user jwt.MapClaims) *gorm.DB {
+	query := config.DB.Model(&models.ExtratoRemuneracao{})
+	role := user["role"].(string)
+	if role != "admin" && role != "superadmin" {
+		query = query.Where("instrutor_id IN (?)",
+			config.DB.Model(&models.User{}).Select("id").Where("email = ?", user["email"].(string)))
+	}
+	return query
+}
+
+// ListExtratos lista os extratos com filtros ?competencia=AAAA-MM, ?instrutor_id= e ?status=
+func ListExtratos(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+
+	query := extratosVisiveis(user).Preload("Instrutor").Order("competencia DESC, instrutor_id")
+	if competencia := c.Query("competencia"); competencia != "" {
+		query = query.Where("competencia = ?", competencia)
+	}
+	if instrutor := c.Query("instrutor_id"); instrutor != "" {
+		query = query.Where("instrutor_id = ?", instrutor)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var extratos []models.ExtratoRemuneracao
+	if err := query.Find(&extratos).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar extratos"})
+	}
+
+	return c.JSON(extratos)
+}
+
+// GetExtrato retorna o extrato com os itens em ordem de data
+func GetExtrato(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+
+	var extrato models.ExtratoRemuneracao
+	err := extratosVisiveis(user).Preload("Instrutor").
+		Preload("Itens", func(db *gorm.DB) *gorm.DB { return db.Order("data, tipo") }).
+		First(&extrato, "id = ?", c.Params("id")).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return respostaErroRemuneracao(c, services.ErrExtratoNaoEncontrado, "")
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar extrato"})
+	}
+
+	return c.JSON(extrato)
+}
+
+// CalcularExtratos refaz os rascunhos da competência, de um instrutor ou de
+// todos os que tiveram regras vigentes ou aulas no mês
+func CalcularExtratos(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		Competencia string `json:"competencia" validate:"required,datetime=2006-01"`
+		InstrutorID string `json:"instrutor_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	if req.InstrutorID != "" {
+		extrato, err := services.CalcularExtrato(req.InstrutorID, req.Competencia, user["email"].(string))
+		if err != nil {
+			return respostaErroRemuneracao(c, err, "Erro ao calcular extrato")
+		}
+		return c.JSON(extrato)
+	}
+
+	extratos, err := services.CalcularExtratosCompetencia(req.Competencia, user["email"].(string))
+	if err != nil {
+		return respostaErroRemuneracao(c, err, "Erro ao calcular extratos")
+	}
+	return c.JSON(extratos)
+}
+
+// AjustarExtrato lança um bônus ou desconto no rascunho
+func AjustarExtrato(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		Ajuste     float64 `json:"ajuste"`
+		Observacao string  `json:"observacao" validate:"required"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	extrato, err := services.AjustarExtrato(c.Params("id"), req.Ajuste, req.Observacao, user["email"].(string))
+	if err != nil {
+		return respostaErroRemuneracao(c, err, "Erro ao ajustar extrato")
+	}
+
+	return c.JSON(extrato)
+}
+
+// UpdateStatusExtrato aprova, reabre ou marca o extrato como pago. Apenas o
+// superadmin fecha a folha.
+func UpdateStatusExtrato(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var req struct {
+		Status models.StatusExtrato `json:"status" validate:"required,oneof=rascunho aprovado pago"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	extrato, err := services.AlterarStatusExtrato(c.Params("id"), req.Status, user["email"].(string))
+	if err != nil {
+		return respostaErroRemuneracao(c, err, "Erro ao alterar status do extrato")
+	}
+
+	return c.JSON(extrato)
+}
+
+// ExportFolha exporta os extratos aprovados da ?competencia=AAAA-MM em
+// ?formato=csv|xlsx|jsonl para a folha de pagamento
+func ExportFolha(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	competencia := c.Query("competencia")
+	opcoes := services.OpcoesExportacao{Formato: c.Query("formato", services.FormatoCSV)}
+	contentType, extensao, err := opcoes.ContentType()
+	if err != nil {
+		return respostaErroRemuneracao(c, err, "")
+	}
+	if _, err := time.Parse("2006-01", competencia); err != nil {
+		return respostaErroRemuneracao(c, services.ErrCompetenciaInvalida, "")
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="folha-%s.%s"`, competencia, extensao))
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := services.ExportarFolha(competencia, opcoes.Formato, w); err != nil {
+			log.Println("Erro ao exportar folha:", err)
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// ListAulasMinistradas lista as aulas dadas no período (?de= e ?ate=) com
+// filtros ?aula_id= e ?instrutor_id= e a quantidade de check-ins
+func ListAulasMinistradas(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	de, ate, err := periodoRelatorio(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+	}
+
+	ministradas, err := services.ListarAulasMinistradas(services.FiltroAulasMinistradas{
+		De:          de,
+		Ate:         ate,
+		AulaID:      c.Query("aula_id"),
+		InstrutorID: c.Query("instrutor_id"),
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar aulas ministradas"})
+	}
+
+	return c.JSON(ministradas)
+}
+
+// RegistrarAulaMinistrada lança uma aula sem check-ins ou registra o
+// instrutor substituto de uma data
+func RegistrarAulaMinistrada(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var dados services.DadosAulaMinistrada
+	if err := c.BodyParser(&dados); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(dados); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	ministrada, err := services.SalvarAulaMinistrada(c.Params("id"), dados, user["email"].(string))
+	if err != nil {
+		return respostaErroRemuneracao(c, err, "Erro ao registrar aula ministrada")
+	}
+
+	return c.JSON(ministrada)
+}
+
+func DeleteAulaMinistrada(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	if err := services.RemoverAulaMinistrada(c.Params("id"), user["email"].(string)); err != nil {
+		return respostaErroRemuneracao(c, err, "Erro ao remover aula ministrada")
+	}
+
+	return c.SendStatus(204)
+}
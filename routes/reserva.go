@@ -0,0 +1,320 @@
+package routes
+
+import (
+	"errors"
+	config "go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"go-api/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func SetupReservaRoutes(app *fiber.App) {
+	recursoGroup := app.Group("/recursos", middleware.JWTMiddleware())
+
+	recursoGroup.Get("/", ListRecursos)
+	recursoGroup.Get("/:id", GetRecurso)
+	recursoGroup.Get("/:id/disponibilidade", GetDisponibilidadeRecurso)
+	recursoGroup.Post("/", CreateRecurso)
+	recursoGroup.Put("/:id", UpdateRecurso)
+	recursoGroup.Delete("/:id", DeleteRecurso)
+
+	reservaGroup := app.Group("/reservas", middleware.JWTMiddleware())
+
+	reservaGroup.Get("/", ListReservas)
+	reservaGroup.Get("/:id", GetReserva)
+	reservaGroup.Post("/", CreateReserva)
+	reservaGroup.Delete("/:id", CancelarReserva)
+}
+
+// Converte os erros de recursos e reservas em respostas HTTP
+func respostaErroReserva(c *fiber.Ctx, err error, mensagem string) error {
+	switch {
+	case errors.Is(err, services.ErrClienteNaoEncontrado):
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	case errors.Is(err, services.ErrRecursoNaoEncontrado),
+		errors.Is(err, services.ErrReservaNaoEncontrada):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrJanelaReservaInvalida),
+		errors.Is(err, services.ErrPlanoNaoEncontrado),
+		errors.Is(err, services.ErrInstrutorInvalido),
+		errors.Is(err, services.ErrPeriodoReservaInvalido),
+		errors.Is(err, services.ErrReservaPassada):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrRecursoDuplicado),
+		errors.Is(err, services.ErrRecursoInativo),
+		errors.Is(err, services.ErrReservaForaDaJanela),
+		errors.Is(err, services.ErrConflitoReserva),
+		errors.Is(err, services.ErrConflitoReservaInstrutor),
+		errors.Is(err, services.ErrConflitoInstrutor):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
+
+// ListRecursos retorna os recursos; ?tipo= filtra e ?ativos=true omite os desativados
+func ListRecursos(c *fiber.Ctx) error {
+	query := config.DB.Preload("Janelas").Order("nome")
+	if tipo := c.Query("tipo"); tipo != "" {
+		query = query.Where("tipo = ?", tipo)
+	}
+	if c.QueryBool("ativos") {
+		query = query.Where("ativo = ?", true)
+	}
+
+	var recursos []models.Recurso
+	if err := query.Find(&recursos).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar recursos"})
+	}
+
+	return c.JSON(recursos)
+}
+
+func GetRecurso(c *fiber.Ctx) error {
+	var recurso models.Recurso
+	if err := config.DB.Preload("Janelas").First(&recurso, "id = ?", c.Params("id")).Error; err != nil {
+		return respostaErroReserva(c, services.ErrRecursoNaoEncontrado, "")
+	}
+
+	return c.JSON(recurso)
+}
+
+// GetDisponibilidadeRecurso mostra as ocupações e os horários livres do
+// recurso na ?data=AAAA-MM-DD (padrão: hoje)
+func GetDisponibilidadeRecurso(c *fiber.Ctx) error {
+	dia := time.Now()
+	if valor := c.Query("data"); valor != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", valor, time.Local)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+		}
+		dia = parsed
+	}
+
+	disponibilidade, err := services.ConsultarDisponibilidade(c.Params("id"), dia)
+	if err != nil {
+		return respostaErroReserva(c, err, "Erro ao consultar disponibilidade")
+	}
+
+	return c.JSON(disponibilidade)
+}
+
+// CreateRecurso cria o recurso com suas janelas de disponibilidade
+func CreateRecurso(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var recurso models.Recurso
+	if err := c.BodyParser(&recurso); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(recurso); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+	for _, janela := range recurso.Janelas {
+		if err := validate.Struct(janela); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+		}
+	}
+
+	recurso.ID = "" // Remove o ID enviado pelo cliente
+	recurso.Ativo = true
+	if err := services.SalvarRecurso(&recurso, user["email"].(string)); err != nil {
+		return respostaErroReserva(c, err, "Erro ao criar recurso")
+	}
+
+	return c.Status(201).JSON(recurso)
+}
+
+// UpdateRecurso atualiza o recurso; as janelas enviadas substituem as atuais
+func UpdateRecurso(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var recurso models.Recurso
+	if err := c.BodyParser(&recurso); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(recurso); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+	for _, janela := range recurso.Janelas {
+		if err := validate.Struct(janela); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+		}
+	}
+
+	recurso.ID = c.Params("id")
+	if err := services.SalvarRecurso(&recurso, user["email"].(string)); err != nil {
+		return respostaErroReserva(c, err, "Erro ao atualizar recurso")
+	}
+
+	return c.JSON(recurso)
+}
+
+// DeleteRecurso desativa o recurso, mantendo o histórico de reservas
+func DeleteRecurso(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	result := config.DB.Model(&models.Recurso{}).Where("id = ?", c.Params("id")).Update("ativo", false)
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao desativar recurso"})
+	}
+	if result.RowsAffected == 0 {
+		return respostaErroReserva(c, services.ErrRecursoNaoEncontrado, "")
+	}
+
+	return c.SendStatus(204)
+}
+
+// ListReservas lista as reservas do período (?de= e ?ate=) com filtros
+// ?recurso_id=, ?cliente_id=, ?instrutor_id= e ?status=
+func ListReservas(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	de, ate, err := periodoRelatorio(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Data inválida, use AAAA-MM-DD"})
+	}
+
+	query := config.DB.Preload("Recurso").Preload("Cliente").Preload("Instrutor").
+		Where("inicio >= ? AND inicio < ?", de, ate.AddDate(0, 0, 1)).Order("inicio")
+	if recurso := c.Query("recurso_id"); recurso != "" {
+		query = query.Where("recurso_id = ?", recurso)
+	}
+	if cliente := c.Query("cliente_id"); cliente != "" {
+		query = query.Where("cliente_id = ?", cliente)
+	}
+	if instrutor := c.Query("instrutor_id"); instrutor != "" {
+		query = query.Where("instrutor_id = ?", instrutor)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var reservas []models.Reserva
+	if err := query.Find(&reservas).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar reservas"})
+	}
+
+	for i := range reservas {
+		if reservas[i].Cliente != nil {
+			protegerPII(role, reservas[i].Cliente)
+		}
+	}
+
+	return c.JSON(reservas)
+}
+
+func GetReserva(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var reserva models.Reserva
+	if err := config.DB.Preload("Recurso").Preload("Cliente").Preload("Instrutor").Preload("Venda").
+		First(&reserva, "id = ?", c.Params("id")).Error; err != nil {
+		return respostaErroReserva(c, services.ErrReservaNaoEncontrada, "")
+	}
+
+	if reserva.Cliente != nil {
+		protegerPII(role, reserva.Cliente)
+	}
+	return c.JSON(reserva)
+}
+
+// CreateReserva reserva o recurso para o cliente e gera a venda pelo serviço do recurso
+func CreateReserva(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var solicitacao services.SolicitacaoReserva
+	if err := c.BodyParser(&solicitacao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(solicitacao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	reserva, err := services.CriarReserva(solicitacao, user["email"].(string))
+	if err != nil {
+		return respostaErroReserva(c, err, "Erro ao criar reserva")
+	}
+	atualizarInadimplencia(reserva.ClienteID)
+
+	return c.Status(201).JSON(reserva)
+}
+
+// CancelarReserva libera o horário; ?motivo= é registrado
+func CancelarReserva(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	reserva, err := services.CancelarReserva(c.Params("id"), c.Query("motivo"), user["email"].(string))
+	if err != nil {
+		return respostaErroReserva(c, err, "Erro ao cancelar reserva")
+	}
+	atualizarInadimplencia(reserva.ClienteID)
+
+	return c.SendStatus(204)
+}
+
+// ListReservasCliente retorna as reservas do cliente; ?todas=true inclui as canceladas
+func ListReservasCliente(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	query := config.DB.Preload("Recurso").Preload("Instrutor").Where("cliente_id = ?", c.Params("id"))
+	if !c.QueryBool("todas") {
+		query = query.Where("status <> ?", models.ReservaCancelada)
+	}
+
+	var reservas []models.Reserva
+	if err := query.Order("inicio DESC").Find(&reservas).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar reservas"})
+	}
+
+	return c.JSON(reservas)
+}
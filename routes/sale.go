@@ -4,7 +4,9 @@ import (
 	"go-api/db"
 	"go-api/middleware"
 	"go-api/models"
+	"go-api/services"
 	"go-api/utils"
+	"log"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -34,6 +36,12 @@ func ListSales(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar vendas"})
 	}
 
+	for i := range sales {
+		if sales[i].Cliente != nil {
+			protegerPII(role, sales[i].Cliente)
+		}
+	}
+
 	return c.JSON(sales)
 }
 
@@ -51,6 +59,9 @@ func GetSale(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Venda não encontrada"})
 	}
 
+	if sale.Cliente != nil {
+		protegerPII(role, sale.Cliente)
+	}
 	return c.JSON(sale)
 }
 
@@ -71,11 +82,24 @@ func CreateSale(c *fiber.Ctx) error {
 	if err := utils.Validate.Struct(sale); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
 	}
+	// Vendas sem cliente são criadas apenas pelas inscrições em eventos
+	if sale.ClienteID == nil || *sale.ClienteID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": "cliente_id é obrigatório"})
+	}
+
+	if err := services.ValidarVendedor(config.DB, sale.VendedorID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Vendedor não encontrado"})
+	}
 
 	// Criar a venda
 	if err := config.DB.Create(&sale).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar venda"})
 	}
+	atualizarInadimplencia(idCliente(sale.ClienteID))
+	if sale.Pago {
+		registrarAuditoria("sale", sale.ID, idCliente(sale.ClienteID), "venda.pagamento", user["email"].(string),
+			map[string]interface{}{"valor": sale.Valor, "forma_pagamento": sale.FormaPagamento})
+	}
 
 	return c.Status(201).JSON(sale)
 }
@@ -93,6 +117,8 @@ func UpdateSale(c *fiber.Ctx) error {
 	if err := config.DB.First(&sale, "id = ?", id).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Venda não encontrada"})
 	}
+	clienteAnterior := sale.ClienteID
+	pagoAnterior := sale.Pago
 
 	// Parse do corpo da requisição
 	if err := c.BodyParser(&sale); err != nil {
@@ -103,11 +129,23 @@ func UpdateSale(c *fiber.Ctx) error {
 	if err := utils.Validate.Struct(sale); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
 	}
+	if clienteAnterior != nil && (sale.ClienteID == nil || *sale.ClienteID == "") {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": "cliente_id é obrigatório"})
+	}
+
+	if err := services.ValidarVendedor(config.DB, sale.VendedorID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Vendedor não encontrado"})
+	}
 
 	// Atualizar a venda
 	if err := config.DB.Save(&sale).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar venda"})
 	}
+	atualizarInadimplencia(idCliente(clienteAnterior), idCliente(sale.ClienteID))
+	if sale.Pago && !pagoAnterior {
+		registrarAuditoria("sale", sale.ID, idCliente(sale.ClienteID), "venda.pagamento", user["email"].(string),
+			map[string]interface{}{"valor": sale.Valor, "forma_pagamento": sale.FormaPagamento})
+	}
 
 	return c.JSON(sale)
 }
@@ -130,6 +168,29 @@ func DeleteSale(c *fiber.Ctx) error {
 	if err := config.DB.Delete(&sale).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar venda"})
 	}
+	atualizarInadimplencia(idCliente(sale.ClienteID))
 
 	return c.Status(204).Send(nil)
-}
\ No newline at end of file
+}
+
+// Recalcula a inadimplência dos clientes afetados por uma mudança de pagamento
+func atualizarInadimplencia(clienteIDs ...string) {
+	vistos := map[string]bool{}
+	for _, id := range clienteIDs {
+		if id == "" || vistos[id] {
+			continue
+		}
+		vistos[id] = true
+		if err := services.RecalcularInadimplencia(id); err != nil {
+			log.Printf("Erro ao recalcular inadimplência do cliente %s: %v", id, err)
+		}
+	}
+}
+
+// Retorna o ID do cliente da venda ou vazio para vendas sem cliente
+func idCliente(clienteID *string) string {
+	if clienteID == nil {
+		return ""
+	}
+	return *clienteID
+}
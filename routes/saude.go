@@ -0,0 +1,220 @@
+package routes
+
+import (
+	"errors"
+	"go-api/db"
+	"go-api/models"
+	"go-api/services"
+	"go-api/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GetPerfilSaude retorna as informações médicas do cliente; restrito aos papéis de SAUDE_ROLES
+func GetPerfilSaude(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if !utils.PodeVerSaude(role) {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	perfil, err := services.BuscarPerfilSaude(c.Params("id"), user["email"].(string))
+	if err != nil {
+		if errors.Is(err, services.ErrClienteNaoEncontrado) {
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar perfil de saúde"})
+	}
+
+	return c.JSON(perfil)
+}
+
+// UpdatePerfilSaude cria ou substitui as informações médicas do cliente
+func UpdatePerfilSaude(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if !utils.PodeVerSaude(role) {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var perfil models.PerfilSaude
+	if err := c.BodyParser(&perfil); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(perfil); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+	if perfil.AtestadoEmitidoEm != nil && perfil.AtestadoValidoAte != nil && perfil.AtestadoValidoAte.Before(*perfil.AtestadoEmitidoEm) {
+		return c.Status(400).JSON(fiber.Map{"error": "A validade do atestado não pode ser anterior à emissão"})
+	}
+
+	perfil.ClienteID = c.Params("id")
+	if err := services.SalvarPerfilSaude(&perfil, user["email"].(string)); err != nil {
+		if errors.Is(err, services.ErrClienteNaoEncontrado) {
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar perfil de saúde"})
+	}
+
+	return c.JSON(perfil)
+}
+
+// GetAtestadosVencidos lista os clientes com assinatura ativa e atestado vencido.
+// Com ?sem_atestado=true inclui também quem nunca apresentou atestado.
+func GetAtestadosVencidos(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	relatorio, err := services.ListarAtestadosVencidos(time.Now(), c.QueryBool("sem_atestado"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar atestados vencidos"})
+	}
+
+	for i := range relatorio {
+		protegerPII(role, &relatorio[i].Cliente)
+	}
+
+	return c.JSON(relatorio)
+}
+
+// ListContatosEmergencia retorna os contatos de emergência do cliente por prioridade
+func ListContatosEmergencia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	var contatos []models.ContatoEmergencia
+	if err := config.DB.Where("cliente_id = ?", id).Order("prioridade, nome").Find(&contatos).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar contatos de emergência"})
+	}
+
+	for i := range contatos {
+		protegerPIIContato(role, &contatos[i])
+	}
+
+	return c.JSON(contatos)
+}
+
+// CreateContatoEmergencia adiciona um contato de emergência ao cliente
+func CreateContatoEmergencia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+	}
+
+	var contato models.ContatoEmergencia
+	if err := c.BodyParser(&contato); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(contato); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	contato.ID = "" // Remove o ID enviado pelo cliente
+	contato.ClienteID = id
+	if err := config.DB.Create(&contato).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar contato de emergência"})
+	}
+
+	protegerPIIContato(role, &contato)
+	return c.Status(201).JSON(contato)
+}
+
+// UpdateContatoEmergencia atualiza um contato de emergência do cliente
+func UpdateContatoEmergencia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var contato models.ContatoEmergencia
+	if err := config.DB.First(&contato, "id = ? AND cliente_id = ?", c.Params("contatoId"), c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Contato de emergência não encontrado"})
+	}
+
+	var dados models.ContatoEmergencia
+	if err := c.BodyParser(&dados); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := validate.Struct(dados); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	// Telefones devolvidos mascarados pela listagem mantêm o valor gravado
+	dados.Telefone = utils.ManterSeMascarado(dados.Telefone, contato.Telefone, utils.MascararTelefone)
+	dados.TelefoneAlternativo = utils.ManterSeMascarado(dados.TelefoneAlternativo, contato.TelefoneAlternativo, utils.MascararTelefone)
+
+	// Mantém a identificação e o vínculo com o cliente
+	dados.ID = contato.ID
+	dados.ClienteID = contato.ClienteID
+	dados.CreatedAt = contato.CreatedAt
+	if err := config.DB.Save(&dados).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar contato de emergência"})
+	}
+
+	protegerPIIContato(role, &dados)
+	return c.JSON(dados)
+}
+
+// Mascara os telefones do contato para papéis sem acesso aos dados pessoais
+func protegerPIIContato(role string, contato *models.ContatoEmergencia) {
+	if utils.PodeVerPII(role) {
+		return
+	}
+
+	contato.Telefone = utils.MascararTelefone(contato.Telefone)
+	contato.TelefoneAlternativo = utils.MascararTelefone(contato.TelefoneAlternativo)
+}
+
+// DeleteContatoEmergencia remove um contato de emergência do cliente
+func DeleteContatoEmergencia(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	result := config.DB.Where("id = ? AND cliente_id = ?", c.Params("contatoId"), c.Params("id")).Delete(&models.ContatoEmergencia{})
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar contato de emergência"})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Contato de emergência não encontrado"})
+	}
+
+	return c.SendStatus(204)
+}
@@ -1,10 +1,13 @@
 package routes
 
 import (
+	"errors"
 	"go-api/db"
 	"go-api/middleware"
 	"go-api/models"
+	"go-api/services"
 	"go-api/utils"
+	"log"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -19,6 +22,11 @@ func SetupSubscriptionRoutes(app *fiber.App) {
 	subGroup.Post("/", CreateSubscription)
 	subGroup.Put("/:id", UpdateSubscription)
 	subGroup.Delete("/:id", CancelSubscription)
+
+	// Congelamento (férias, viagem ou lesão)
+	subGroup.Get("/:id/congelamentos", ListCongelamentos)
+	subGroup.Post("/:id/congelamentos", FreezeSubscription)
+	subGroup.Delete("/:id/congelamentos/:congelamentoId", UnfreezeSubscription)
 }
 
 func ListSubscriptions(c *fiber.Ctx) error {
@@ -72,30 +80,17 @@ func CreateSubscription(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
 	}
 
-	// Criptografar dados do cartão se fornecidos
-	if subscription.CardNumber != nil {
-		encryptedNumber, err := utils.Encrypt([]byte(*subscription.CardNumber))
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "Erro ao criptografar dados do cartão"})
-		}
-		subscription.CardNumber = &encryptedNumber
-	}
-
-	if subscription.CardCVV != nil {
-		encryptedCVV, err := utils.Encrypt([]byte(*subscription.CardCVV))
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "Erro ao criptografar dados do cartão"})
+	// Criar assinatura com o desconto familiar, se houver
+	if err := services.CriarAssinatura(&subscription, user["email"].(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrClienteNaoEncontrado):
+			return c.Status(404).JSON(fiber.Map{"error": "Cliente não encontrado"})
+		case errors.Is(err, services.ErrPlanoNaoEncontrado), errors.Is(err, services.ErrVendedorNaoEncontrado):
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 		}
-		subscription.CardCVV = &encryptedCVV
-	}
-
-	// Configurar status inicial
-	subscription.PaymentStatus = models.Pending
-
-	// Criar assinatura
-	if err := config.DB.Create(&subscription).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar assinatura"})
 	}
+	atualizarInadimplencia(subscription.ClienteID)
 
 	return c.JSON(subscription)
 }
@@ -113,6 +108,8 @@ func UpdateSubscription(c *fiber.Ctx) error {
 	if err := config.DB.First(&subscription, "id = ?", id).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Assinatura não encontrada"})
 	}
+	clienteAnterior := subscription.ClienteID
+	statusAnterior := subscription.PaymentStatus
 
 	if err := c.BodyParser(&subscription); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
@@ -123,10 +120,22 @@ func UpdateSubscription(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
 	}
 
+	if err := services.ValidarVendedor(config.DB, subscription.VendedorID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Vendedor não encontrado"})
+	}
+
 	// Atualizar assinatura
 	if err := config.DB.Save(&subscription).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar assinatura"})
 	}
+	atualizarInadimplencia(clienteAnterior, subscription.ClienteID)
+
+	acao := "assinatura.atualizacao"
+	if subscription.PaymentStatus == models.Paid && statusAnterior != models.Paid {
+		acao = "assinatura.pagamento"
+	}
+	registrarAuditoria("subscription", subscription.ID, subscription.ClienteID, acao, user["email"].(string),
+		map[string]interface{}{"status": subscription.PaymentStatus, "status_anterior": statusAnterior, "valor": subscription.Amount, "ativa": subscription.Active})
 
 	return c.JSON(subscription)
 }
@@ -139,19 +148,101 @@ func CancelSubscription(c *fiber.Ctx) error {
 		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
 	}
 
-	id := c.Params("id")
-	var subscription models.Subscription
-	if err := config.DB.First(&subscription, "id = ?", id).Error; err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Assinatura não encontrada"})
+	// O cancelamento e a revogação do QR code de check-in são gravados juntos
+	subscription, err := services.CancelarAssinatura(c.Params("id"), user["email"].(string))
+	if err != nil {
+		if errors.Is(err, services.ErrAssinaturaNaoEncontrada) {
+			return c.Status(404).JSON(fiber.Map{"error": "Assinatura não encontrada"})
+		}
+		log.Printf("Erro ao cancelar assinatura %s: %v", c.Params("id"), err)
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao cancelar assinatura"})
 	}
+	atualizarInadimplencia(subscription.ClienteID)
+
+	return c.SendStatus(204)
+}
 
-	// Marcar como cancelada e inativa
-	subscription.PaymentStatus = models.Cancelled
-	subscription.Active = false
+// Traduz os erros do congelamento de assinaturas em respostas HTTP
+func respostaErroCongelamento(c *fiber.Ctx, err error, mensagem string) error {
+	switch {
+	case errors.Is(err, services.ErrAssinaturaNaoEncontrada),
+		errors.Is(err, services.ErrCongelamentoNaoEncontrado):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrPeriodoCongelamento),
+		errors.Is(err, services.ErrCongelamentoForaContrato),
+		errors.Is(err, services.ErrPlanoNaoEncontrado):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAssinaturaInativa),
+		errors.Is(err, services.ErrCongelamentoSobreposto),
+		errors.Is(err, services.ErrCongelamentoEncerrado):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCongelamentoNaoPermitido),
+		errors.Is(err, services.ErrLimiteCongelamento):
+		return c.Status(422).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": mensagem})
+}
 
-	if err := config.DB.Save(&subscription).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao cancelar assinatura"})
+// ListCongelamentos retorna o histórico de congelamentos da assinatura
+func ListCongelamentos(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
 	}
 
-	return c.SendStatus(204)
-}
\ No newline at end of file
+	congelamentos, err := services.ListarCongelamentos(c.Params("id"))
+	if err != nil {
+		return respostaErroCongelamento(c, err, "Erro ao buscar congelamentos")
+	}
+	return c.JSON(congelamentos)
+}
+
+// FreezeSubscription congela a assinatura entre as datas informadas, adiando a
+// próxima cobrança e o fim do contrato
+func FreezeSubscription(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var solicitacao services.SolicitacaoCongelamento
+	if err := c.BodyParser(&solicitacao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	// Validação dos dados
+	if err := utils.Validate.Struct(solicitacao); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	congelamento, err := services.CongelarAssinatura(c.Params("id"), solicitacao, user["email"].(string))
+	if err != nil {
+		return respostaErroCongelamento(c, err, "Erro ao congelar assinatura")
+	}
+	atualizarInadimplencia(congelamento.Subscription.ClienteID)
+
+	return c.Status(201).JSON(congelamento)
+}
+
+// UnfreezeSubscription reativa a assinatura antes do fim do congelamento ou
+// cancela um congelamento ainda não iniciado
+func UnfreezeSubscription(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	congelamento, err := services.EncerrarCongelamento(c.Params("id"), c.Params("congelamentoId"), user["email"].(string))
+	if err != nil {
+		return respostaErroCongelamento(c, err, "Erro ao encerrar congelamento")
+	}
+	atualizarInadimplencia(congelamento.Subscription.ClienteID)
+
+	return c.JSON(congelamento)
+}
@@ -0,0 +1,104 @@
+package routes
+
+import (
+	"go-api/db"
+	"go-api/middleware"
+	"go-api/models"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func SetupTagRoutes(app *fiber.App) {
+	tagGroup := app.Group("/tags", middleware.JWTMiddleware())
+
+	tagGroup.Get("/", ListTags)
+	tagGroup.Post("/", CreateTag)
+	tagGroup.Delete("/:id", DeleteTag)
+}
+
+// ListTags retorna todas as tags com a quantidade de clientes em cada uma
+func ListTags(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	type TagResumo struct {
+		models.Tag
+		Clientes int64 `json:"clientes"`
+	}
+
+	var tags []TagResumo
+	if err := config.DB.Model(&models.Tag{}).
+		Select("tags.*, COUNT(cliente_tags.cliente_id) AS clientes").
+		Joins("LEFT JOIN cliente_tags ON cliente_tags.tag_id = tags.id").
+		Group("tags.id").Order("tags.nome").
+		Scan(&tags).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar tags"})
+	}
+
+	return c.JSON(tags)
+}
+
+func CreateTag(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	var tag models.Tag
+	if err := c.BodyParser(&tag); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Requisição inválida"})
+	}
+
+	tag.ID = "" // Remove o ID enviado pelo cliente
+	tag.Nome = strings.ToLower(strings.TrimSpace(tag.Nome))
+
+	// Validação dos dados
+	if err := validate.Struct(tag); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos", "details": err.Error()})
+	}
+
+	var total int64
+	config.DB.Model(&models.Tag{}).Where("nome = ?", tag.Nome).Count(&total)
+	if total > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "Tag já existe"})
+	}
+
+	if err := config.DB.Create(&tag).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar tag"})
+	}
+
+	return c.Status(201).JSON(tag)
+}
+
+// DeleteTag remove a tag e seus vínculos com clientes
+func DeleteTag(c *fiber.Ctx) error {
+	user := c.Locals("user").(jwt.MapClaims)
+	role := user["role"].(string)
+
+	if role != "admin" && role != "superadmin" {
+		return c.Status(403).JSON(fiber.Map{"error": "Acesso proibido"})
+	}
+
+	id := c.Params("id")
+	var tag models.Tag
+	if err := config.DB.First(&tag, "id = ?", id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Tag não encontrada"})
+	}
+
+	if err := config.DB.Exec("DELETE FROM cliente_tags WHERE tag_id = ?", id).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar tag"})
+	}
+	if err := config.DB.Delete(&tag).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar tag"})
+	}
+
+	return c.SendStatus(204)
+}
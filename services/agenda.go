@@ -0,0 +1,231 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrHorarioInvalido    = errors.New("o horário de término deve ser posterior ao de início")
+	ErrModalidadeInvalida = errors.New("modalidade não encontrada ou inativa")
+	ErrInstrutorInvalido  = errors.New("instrutor não encontrado")
+	ErrConflitoSala       = errors.New("a sala já está ocupada neste horário")
+	ErrConflitoInstrutor  = errors.New("o instrutor já dá outra aula neste horário")
+	ErrPlanoNaoEncontrado = errors.New("plano não encontrado ou não é um serviço")
+)
+
+// OcorrenciaAula é uma aula em uma data específica da grade
+type OcorrenciaAula struct {
+	Aula      models.Aula `json:"aula"`
+	Data      string      `json:"data"`
+	Inicio    time.Time   `json:"inicio"`
+	Fim       time.Time   `json:"fim"`
+	Cancelada bool        `json:"cancelada"`
+	Motivo    string      `json:"motivo,omitempty"`
+}
+
+// DiaGrade reúne as aulas de um dia da semana
+type DiaGrade struct {
+	Data      string           `json:"data"`
+	DiaSemana int              `json:"dia_semana"`
+	Aulas     []OcorrenciaAula `json:"aulas"`
+}
+
+// FiltroGrade restringe a grade a uma modalidade ou instrutor
+type FiltroGrade struct {
+	ModalidadeID string
+	InstrutorID  string
+}
+
+// ValidarAula confere horário, modalidade, instrutor e conflitos de sala e
+// instrutor com as outras aulas ativas do mesmo dia da semana
+func ValidarAula(tx *gorm.DB, aula *models.Aula) error {
+	if aula.HoraFim <= aula.HoraInicio {
+		return ErrHorarioInvalido
+	}
+	if aula.IdadeMinima != nil && aula.IdadeMaxima != nil && *aula.IdadeMinima > *aula.IdadeMaxima {
+		return ErrFaixaEtariaInvalida
+	}
+
+	var modalidade models.Modalidade
+	if err := tx.First(&modalidade, "id = ? AND ativa = ?", aula.ModalidadeID, true).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrModalidadeInvalida
+		}
+		return err
+	}
+
+	if aula.InstrutorID != nil {
+		var instrutor models.User
+		if err := tx.First(&instrutor, "id = ?", *aula.InstrutorID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInstrutorInvalido
+			}
+			return err
+		}
+	}
+
+	// Os horários "HH:MM" podem ser comparados como texto
+	sobrepostas := tx.Model(&models.Aula{}).
+		Where("ativa = ? AND dia_semana = ? AND hora_inicio < ? AND hora_fim > ?", true, aula.DiaSemana, aula.HoraFim, aula.HoraInicio)
+	if aula.ID != "" {
+		sobrepostas = sobrepostas.Where("id <> ?", aula.ID)
+	}
+
+	var total int64
+	if err := sobrepostas.Session(&gorm.Session{}).Where("sala = ?", aula.Sala).Count(&total).Error; err != nil {
+		return err
+	}
+	if total > 0 {
+		return ErrConflitoSala
+	}
+
+	if aula.InstrutorID != nil {
+		if err := sobrepostas.Session(&gorm.Session{}).Where("instrutor_id = ?", *aula.InstrutorID).Count(&total).Error; err != nil {
+			return err
+		}
+		if total > 0 {
+			return ErrConflitoInstrutor
+		}
+	}
+	return nil
+}
+
+// SalvarAula valida e grava a aula. Se a capacidade aumentar, as novas vagas
+// são oferecidas à lista de espera.
+func SalvarAula(aula *models.Aula) error {
+	var promovidas []models.Matricula
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ValidarAula(tx, aula); err != nil {
+			return err
+		}
+		if err := tx.Omit("Modalidade", "Instrutor").Save(aula).Error; err != nil {
+			return err
+		}
+
+		var err error
+		promovidas, err = promoverListaEspera(tx, aula)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	notificarPromocoes(promovidas)
+	return nil
+}
+
+// InicioSemana retorna a segunda-feira da semana da data informada
+func InicioSemana(data time.Time) time.Time {
+	dia := time.Date(data.Year(), data.Month(), data.Day(), 0, 0, 0, 0, data.Location())
+	deslocamento := (int(dia.Weekday()) + 6) % 7
+	return dia.AddDate(0, 0, -deslocamento)
+}
+
+// GradeSemanal monta a grade de segunda a domingo da semana da data
+// informada, marcando as aulas canceladas por exceções da agenda
+func GradeSemanal(data time.Time, filtro FiltroGrade) ([]DiaGrade, error) {
+	inicio := InicioSemana(data)
+	fim := inicio.AddDate(0, 0, 7)
+
+	query := config.DB.Preload("Modalidade").Preload("Instrutor").Where("ativa = ?", true)
+	if filtro.ModalidadeID != "" {
+		query = query.Where("modalidade_id = ?", filtro.ModalidadeID)
+	}
+	if filtro.InstrutorID != "" {
+		query = query.Where("instrutor_id = ?", filtro.InstrutorID)
+	}
+
+	var aulas []models.Aula
+	if err := query.Order("hora_inicio, nome").Find(&aulas).Error; err != nil {
+		return nil, err
+	}
+
+	var excecoes []models.ExcecaoAgenda
+	if err := config.DB.Where("data >= ? AND data < ?", inicio, fim).Find(&excecoes).Error; err != nil {
+		return nil, err
+	}
+
+	grade := make([]DiaGrade, 7)
+	for i := range grade {
+		dia := inicio.AddDate(0, 0, i)
+		grade[i] = DiaGrade{Data: dia.Format("2006-01-02"), DiaSemana: int(dia.Weekday()), Aulas: []OcorrenciaAula{}}
+
+		for _, aula := range aulas {
+			if aula.DiaSemana != int(dia.Weekday()) {
+				continue
+			}
+			ocorrencia := OcorrenciaAula{Aula: aula, Data: grade[i].Data}
+			ocorrencia.Inicio, ocorrencia.Fim = aula.Horario(dia)
+			if excecao := excecaoDaAula(excecoes, aula.ID, dia); excecao != nil {
+				ocorrencia.Cancelada = true
+				ocorrencia.Motivo = excecao.Motivo
+			}
+			grade[i].Aulas = append(grade[i].Aulas, ocorrencia)
+		}
+	}
+	return grade, nil
+}
+
+// AulaCancelada indica se há exceção na agenda para a aula na data informada
+func AulaCancelada(tx *gorm.DB, aulaID string, data time.Time) (*models.ExcecaoAgenda, error) {
+	dia := time.Date(data.Year(), data.Month(), data.Day(), 0, 0, 0, 0, data.Location())
+
+	var excecoes []models.ExcecaoAgenda
+	if err := tx.Where("data = ? AND (aula_id IS NULL OR aula_id = ?)", dia.Format("2006-01-02"), aulaID).
+		Find(&excecoes).Error; err != nil {
+		return nil, err
+	}
+	return excecaoDaAula(excecoes, aulaID, dia), nil
+}
+
+func excecaoDaAula(excecoes []models.ExcecaoAgenda, aulaID string, dia time.Time) *models.ExcecaoAgenda {
+	data := dia.Format("2006-01-02")
+	for i, excecao := range excecoes {
+		if excecao.Data.Format("2006-01-02") != data {
+			continue
+		}
+		if excecao.AulaID == nil || *excecao.AulaID == aulaID {
+			return &excecoes[i]
+		}
+	}
+	return nil
+}
+
+// DefinirModalidadesPlano substitui as modalidades às quais o plano dá acesso
+func DefinirModalidadesPlano(produtoID string, modalidadeIDs []string) (*models.ProdutoServico, error) {
+	var plano models.ProdutoServico
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&plano, "id = ?", produtoID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPlanoNaoEncontrado
+			}
+			return err
+		}
+
+		modalidades := []models.Modalidade{}
+		if len(modalidadeIDs) > 0 {
+			if err := tx.Where("id IN ?", modalidadeIDs).Find(&modalidades).Error; err != nil {
+				return err
+			}
+			if len(modalidades) != len(modalidadeIDs) {
+				return ErrModalidadeInvalida
+			}
+		}
+
+		if err := tx.Model(&plano).Association("Modalidades").Replace(modalidades); err != nil {
+			return err
+		}
+		plano.Modalidades = modalidades
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &plano, nil
+}
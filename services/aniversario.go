@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+)
+
+// Janela de tempo usada para considerar um cliente aniversariante
+type JanelaAniversario string
+
+const (
+	JanelaHoje   JanelaAniversario = "hoje"
+	JanelaSemana JanelaAniversario = "semana"
+	JanelaMes    JanelaAniversario = "mes"
+)
+
+var ErrJanelaInvalida = errors.New("janela de aniversário inválida, use hoje, semana ou mes")
+
+// JanelaPadrao retorna a janela usada para calcular a flag de aniversariante,
+// configurável pela variável ANIVERSARIO_JANELA
+func JanelaPadrao() JanelaAniversario {
+	janela := JanelaAniversario(os.Getenv("ANIVERSARIO_JANELA"))
+	if _, _, err := intervaloJanela(janela, time.Now()); err != nil {
+		return JanelaHoje
+	}
+	return janela
+}
+
+// AniversarioNoAno retorna a data do aniversário no ano informado.
+// Quem nasceu em 29 de fevereiro comemora em 28 de fevereiro nos anos não bissextos.
+func AniversarioNoAno(nascimento time.Time, ano int, loc *time.Location) time.Time {
+	dia := nascimento.Day()
+	if nascimento.Month() == time.February && dia == 29 && !bissexto(ano) {
+		dia = 28
+	}
+	return time.Date(ano, nascimento.Month(), dia, 0, 0, 0, 0, loc)
+}
+
+// FazAniversarioNaJanela indica se o aniversário cai dentro da janela que contém ref
+func FazAniversarioNaJanela(nascimento time.Time, janela JanelaAniversario, ref time.Time) (bool, error) {
+	inicio, fim, err := intervaloJanela(janela, ref)
+	if err != nil {
+		return false, err
+	}
+
+	// A janela da semana pode atravessar a virada do ano
+	for ano := inicio.Year(); ano <= fim.Year(); ano++ {
+		aniversario := AniversarioNoAno(nascimento, ano, ref.Location())
+		if !aniversario.Before(inicio) && !aniversario.After(fim) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListarAniversariantes busca os clientes que fazem aniversário na janela.
+// O banco filtra pelos meses da janela e o restante do cálculo é feito aqui,
+// para tratar corretamente os nascidos em 29 de fevereiro.
+func ListarAniversariantes(janela JanelaAniversario, ref time.Time) ([]models.Cliente, error) {
+	inicio, fim, err := intervaloJanela(janela, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	meses := []int{int(inicio.Month())}
+	if fim.Month() != inicio.Month() {
+		meses = append(meses, int(fim.Month()))
+	}
+
+	var candidatos []models.Cliente
+	if err := config.DB.Scopes(ApenasAtivos).
+		Where("EXTRACT(MONTH FROM data_nascimento) IN ?", meses).
+		Order("EXTRACT(MONTH FROM data_nascimento), EXTRACT(DAY FROM data_nascimento)").
+		Find(&candidatos).Error; err != nil {
+		return nil, err
+	}
+
+	aniversariantes := []models.Cliente{}
+	for _, cliente := range candidatos {
+		if ok, _ := FazAniversarioNaJanela(cliente.DataNascimento, janela, ref); ok {
+			aniversariantes = append(aniversariantes, cliente)
+		}
+	}
+	return aniversariantes, nil
+}
+
+// AtualizarFlagsAniversariante recalcula a flag de todos os clientes usando a janela padrão
+func AtualizarFlagsAniversariante(ref time.Time) ([]models.Cliente, error) {
+	aniversariantes, err := ListarAniversariantes(JanelaPadrao(), ref)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(aniversariantes))
+	for _, cliente := range aniversariantes {
+		ids = append(ids, cliente.ID)
+	}
+
+	query := config.DB.Model(&models.Cliente{}).Where("flag_aniversariante = ?", true)
+	if len(ids) > 0 {
+		query = query.Where("id NOT IN ?", ids)
+	}
+	if err := query.Update("flag_aniversariante", false).Error; err != nil {
+		return nil, err
+	}
+
+	if len(ids) > 0 {
+		if err := config.DB.Model(&models.Cliente{}).Where("id IN ?", ids).
+			Update("flag_aniversariante", true).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return aniversariantes, nil
+}
+
+// Calcula o primeiro e o último dia da janela que contém ref
+func intervaloJanela(janela JanelaAniversario, ref time.Time) (time.Time, time.Time, error) {
+	hoje := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+
+	switch janela {
+	case JanelaHoje:
+		return hoje, hoje, nil
+	case JanelaSemana:
+		// Semana de segunda a domingo
+		deslocamento := (int(hoje.Weekday()) + 6) % 7
+		inicio := hoje.AddDate(0, 0, -deslocamento)
+		return inicio, inicio.AddDate(0, 0, 6), nil
+	case JanelaMes:
+		inicio := time.Date(hoje.Year(), hoje.Month(), 1, 0, 0, 0, 0, ref.Location())
+		return inicio, inicio.AddDate(0, 1, -1), nil
+	}
+	return time.Time{}, time.Time{}, ErrJanelaInvalida
+}
+
+func bissexto(ano int) bool {
+	return ano%4 == 0 && (ano%100 != 0 || ano%400 == 0)
+}
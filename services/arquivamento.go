@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMotivoArquivamentoInvalido     = errors.New("motivo de arquivamento inválido, use mudou, desistiu, falecido ou duplicado")
+	ErrClienteArquivado               = errors.New("cliente já está arquivado")
+	ErrClienteNaoArquivado            = errors.New("cliente não está arquivado")
+	ErrClienteComRegistrosFinanceiros = errors.New("cliente possui vendas ou assinaturas e não pode ser excluído")
+)
+
+// ApenasAtivos restringe a consulta aos clientes não arquivados
+func ApenasAtivos(tx *gorm.DB) *gorm.DB {
+	return tx.Where("clientes.arquivado_em IS NULL")
+}
+
+// ArquivarCliente tira o cliente das listagens mantendo todo o histórico,
+// inclusive vendas e assinaturas
+func ArquivarCliente(clienteID string, motivo models.MotivoArquivamento, autor string) error {
+	switch motivo {
+	case models.MotivoMudanca, models.MotivoDesistencia, models.MotivoFalecimento, models.MotivoDuplicado:
+	default:
+		return ErrMotivoArquivamentoInvalido
+	}
+
+	var promovidas []models.Matricula
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var cliente models.Cliente
+		if err := tx.First(&cliente, "id = ?", clienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+		if cliente.ArquivadoEm != nil {
+			return ErrClienteArquivado
+		}
+
+		if err := tx.Model(&models.Cliente{}).Where("id = ?", clienteID).Updates(map[string]interface{}{
+			"arquivado_em":        time.Now(),
+			"motivo_arquivamento": motivo,
+		}).Error; err != nil {
+			return err
+		}
+
+		// As vagas e os horários reservados do cliente arquivado são liberados
+		var err error
+		if promovidas, err = CancelarMatriculasCliente(tx, clienteID, "cliente arquivado"); err != nil {
+			return err
+		}
+		if err := CancelarReservasCliente(tx, clienteID, "cliente arquivado"); err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "cliente", clienteID, &clienteID, "cliente.arquivamento", autor,
+			map[string]interface{}{"motivo": motivo})
+	})
+	if err != nil {
+		return err
+	}
+
+	notificarPromocoes(promovidas)
+	return nil
+}
+
+// RestaurarCliente devolve um cliente arquivado às listagens
+func RestaurarCliente(clienteID, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var cliente models.Cliente
+		if err := tx.First(&cliente, "id = ?", clienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+		if cliente.ArquivadoEm == nil {
+			return ErrClienteNaoArquivado
+		}
+		if cliente.AnonimizadoEm != nil {
+			return ErrClienteAnonimizado
+		}
+
+		// Um duplicado mesclado volta a ocupar o CPF e o e-mail nos índices únicos
+		if err := verificarDuplicidade(tx, &cliente); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Cliente{}).Where("id = ?", clienteID).Updates(map[string]interface{}{
+			"arquivado_em":        nil,
+			"motivo_arquivamento": "",
+		}).Error; err != nil {
+			return traduzirErroCliente(err)
+		}
+
+		return RegistrarAuditoria(tx, "cliente", clienteID, &clienteID, "cliente.restauracao", autor,
+			map[string]interface{}{"motivo_anterior": cliente.MotivoArquivamento})
+	})
+}
+
+// Verifica se o cliente tem vendas ou assinaturas, que impedem a exclusão definitiva
+func possuiRegistrosFinanceiros(tx *gorm.DB, clienteID string) (bool, error) {
+	var vendas, assinaturas int64
+	if err := tx.Model(&models.Sale{}).Where("cliente_id = ?", clienteID).Count(&vendas).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.Subscription{}).Where("cliente_id = ?", clienteID).Count(&assinaturas).Error; err != nil {
+		return false, err
+	}
+	return vendas > 0 || assinaturas > 0, nil
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+	"go-api/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CriarAssinatura criptografa os dados do cartão e grava a assinatura. Se o
+// cliente pertence a uma família, aplica o dia de cobrança unificado e a
+// regra de desconto familiar correspondente à posição dele na família.
+func CriarAssinatura(assinatura *models.Subscription, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return criarAssinatura(tx, assinatura, autor)
+	})
+}
+
+// CancelarAssinatura marca a assinatura como cancelada e inativa e, sem outra
+// assinatura ativa, revoga o QR code de check-in do cliente na mesma transação
+func CancelarAssinatura(subscriptionID, autor string) (*models.Subscription, error) {
+	var assinatura *models.Subscription
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if assinatura, err = bloquearAssinatura(tx, subscriptionID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(assinatura).Updates(map[string]interface{}{
+			"payment_status": models.Cancelled,
+			"active":         false,
+		}).Error; err != nil {
+			return err
+		}
+		assinatura.PaymentStatus = models.Cancelled
+		assinatura.Active = false
+
+		if err := RegistrarAuditoria(tx, "subscription", assinatura.ID, &assinatura.ClienteID, "assinatura.cancelamento", autor, nil); err != nil {
+			return err
+		}
+		return RevogarCredencialSemAssinatura(tx, assinatura.ClienteID, autor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assinatura, nil
+}
+
+// Grava a assinatura dentro da transação recebida
+func criarAssinatura(tx *gorm.DB, assinatura *models.Subscription, autor string) error {
+	// Criptografar dados do cartão se fornecidos
+	if assinatura.CardNumber != nil {
+		numero, err := utils.Encrypt([]byte(*assinatura.CardNumber))
+		if err != nil {
+			return err
+		}
+		assinatura.CardNumber = &numero
+	}
+	if assinatura.CardCVV != nil {
+		cvv, err := utils.Encrypt([]byte(*assinatura.CardCVV))
+		if err != nil {
+			return err
+		}
+		assinatura.CardCVV = &cvv
+	}
+
+	// Configurar status inicial
+	assinatura.PaymentStatus = models.Pending
+	assinatura.ValorOriginal = assinatura.Amount
+	assinatura.DescontoFamilia = 0
+
+	var cliente models.Cliente
+	if err := tx.Select("id", "familia_id").First(&cliente, "id = ?", assinatura.ClienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrClienteNaoEncontrado
+		}
+		return err
+	}
+
+	if assinatura.ProdutoID != nil {
+		var plano models.ProdutoServico
+		if err := tx.Select("id", "duracao_meses", "recorrente").First(&plano, "id = ?", *assinatura.ProdutoID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPlanoNaoEncontrado
+			}
+			return err
+		}
+
+		// Planos não recorrentes terminam após a duração contratada
+		if assinatura.DataFimContrato == nil && !plano.Recorrente && plano.DuracaoMeses > 0 {
+			agora := time.Now()
+			fim := time.Date(agora.Year(), agora.Month()+time.Month(plano.DuracaoMeses), agora.Day(), 0, 0, 0, 0, time.Local)
+			assinatura.DataFimContrato = &fim
+		}
+	}
+
+	if err := ValidarVendedor(tx, assinatura.VendedorID); err != nil {
+		return err
+	}
+
+	if cliente.FamiliaID != nil {
+		// Bloqueia a família para que assinaturas simultâneas não recebam a mesma posição
+		var familia models.Familia
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&familia, "id = ?", *cliente.FamiliaID).Error; err != nil {
+			return err
+		}
+		if familia.DiaCobranca != nil {
+			assinatura.BillingDay = *familia.DiaCobranca
+		}
+
+		percentual, err := descontoFamiliar(tx, familia.ID, cliente.ID)
+		if err != nil {
+			return err
+		}
+		if percentual > 0 {
+			assinatura.DescontoFamilia = percentual
+			assinatura.Amount = math.Round(assinatura.ValorOriginal*(100-percentual)) / 100
+		}
+	}
+
+	if err := tx.Create(assinatura).Error; err != nil {
+		return err
+	}
+
+	// Uma nova assinatura reativa a credencial de check-in revogada no cancelamento
+	var revogadas int64
+	if err := tx.Model(&models.CredencialCheckin{}).Where("cliente_id = ? AND revogada_em IS NOT NULL", cliente.ID).
+		Count(&revogadas).Error; err != nil {
+		return err
+	}
+	if revogadas > 0 {
+		if err := ReemitirCredencial(tx, cliente.ID, autor); err != nil {
+			return err
+		}
+	}
+
+	return RegistrarAuditoria(tx, "subscription", assinatura.ID, &assinatura.ClienteID, "assinatura.criacao", autor,
+		map[string]interface{}{"valor": assinatura.Amount, "forma_pagamento": assinatura.PaymentMethod, "desconto_familia": assinatura.DescontoFamilia})
+}
+
+// Calcula o desconto do cliente pela quantidade de outros membros da família
+// que já têm assinatura ativa; o cliente seria o membro de número total+1
+func descontoFamiliar(tx *gorm.DB, familiaID, clienteID string) (float64, error) {
+	var membrosComAssinatura int64
+	if err := tx.Model(&models.Subscription{}).
+		Joins("JOIN clientes ON clientes.id = subscriptions.cliente_id").
+		Where("clientes.familia_id = ? AND subscriptions.active = ? AND subscriptions.cliente_id <> ?", familiaID, true, clienteID).
+		Distinct("subscriptions.cliente_id").
+		Count(&membrosComAssinatura).Error; err != nil {
+		return 0, err
+	}
+
+	var regra models.RegraDescontoFamilia
+	err := tx.Where("ativa = ? AND a_partir_de <= ?", true, membrosComAssinatura+1).
+		Order("a_partir_de DESC").First(&regra).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return regra.Percentual, nil
+}
+
+// Próxima data de cobrança para o dia informado, seguindo a mesma regra da criação da assinatura
+func proximaCobranca(dia int, agora time.Time) time.Time {
+	proxima := time.Date(agora.Year(), agora.Month(), dia, 0, 0, 0, 0, time.Local)
+	if proxima.Before(agora) {
+		proxima = proxima.AddDate(0, 1, 0)
+	}
+	return proxima
+}
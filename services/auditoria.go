@@ -0,0 +1,19 @@
+package services
+
+import (
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+// RegistrarAuditoria grava um registro de auditoria na transação informada
+func RegistrarAuditoria(tx *gorm.DB, entidade, entidadeID string, clienteID *string, acao, autor string, dados map[string]interface{}) error {
+	return tx.Create(&models.RegistroAuditoria{
+		Entidade:   entidade,
+		EntidadeID: entidadeID,
+		ClienteID:  clienteID,
+		Acao:       acao,
+		Autor:      autor,
+		Dados:      dados,
+	}).Error
+}
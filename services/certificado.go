@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"go-api/db"
+	"go-api/models"
+
+	"github.com/go-pdf/fpdf"
+	"gorm.io/gorm"
+)
+
+var ErrGraduacaoNaoEncontrada = errors.New("graduação não encontrada")
+
+// Meses por extenso para a data do certificado
+var mesesPorExtenso = [...]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho",
+	"julho", "agosto", "setembro", "outubro", "novembro", "dezembro"}
+
+// NomeAcademia é o nome da academia em certificados e inscrições, de ACADEMIA_NOME
+func NomeAcademia() string {
+	if nome := os.Getenv("ACADEMIA_NOME"); nome != "" {
+		return nome
+	}
+	return "Academia"
+}
+
+// GerarCertificado escreve em w o certificado em PDF da graduação. O nome da
+// academia no cabeçalho vem de ACADEMIA_NOME.
+func GerarCertificado(graduacaoID string, w io.Writer) error {
+	var graduacao models.Graduacao
+	if err := config.DB.Preload("Cliente").Preload("Modalidade").Preload("Faixa").Preload("Instrutor").
+		First(&graduacao, "id = ?", graduacaoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrGraduacaoNaoEncontrada
+		}
+		return err
+	}
+
+	academia := NomeAcademia()
+
+	nivel := "faixa " + graduacao.Faixa.Nome
+	if graduacao.Grau > 0 {
+		nivel += ", " + strconv.Itoa(graduacao.Grau) + "º grau"
+	}
+	data := fmt.Sprintf("%d de %s de %d", graduacao.Data.Day(), mesesPorExtenso[graduacao.Data.Month()-1], graduacao.Data.Year())
+
+	pdf := fpdf.New("L", "mm", "A4", "")
+	// As fontes padrão do PDF usam cp1252; o tradutor converte os acentos do UTF-8
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(tr("Certificado de Graduação"), false)
+	pdf.AddPage()
+
+	largura, altura := pdf.GetPageSize()
+	pdf.SetLineWidth(1.5)
+	pdf.Rect(10, 10, largura-20, altura-20, "D")
+
+	// Faixa colorida no topo, na cor da graduação
+	if r, g, b, ok := corHex(graduacao.Faixa.Cor); ok {
+		pdf.SetFillColor(r, g, b)
+		pdf.Rect(10, 10, largura-20, 8, "F")
+	}
+
+	pdf.SetY(35)
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(0, 10, tr(academia), "", 1, "C", false, 0, "")
+	pdf.Ln(8)
+	pdf.SetFont("Helvetica", "B", 32)
+	pdf.CellFormat(0, 16, tr("Certificado de Graduação"), "", 1, "C", false, 0, "")
+	pdf.Ln(12)
+
+	pdf.SetFont("Helvetica", "", 16)
+	pdf.SetX(30)
+	pdf.MultiCell(largura-60, 9, tr(fmt.Sprintf("Certificamos que %s foi promovido(a) à %s em %s, em %s.",
+		graduacao.Cliente.Nome, nivel, graduacao.Modalidade.Nome, data)), "", "C", false)
+
+	// Linha de assinatura do instrutor que promoveu
+	pdf.SetY(altura - 55)
+	pdf.Line(largura/2-50, pdf.GetY(), largura/2+50, pdf.GetY())
+	pdf.Ln(3)
+	pdf.SetFont("Helvetica", "", 12)
+	instrutor := "Instrutor responsável"
+	if graduacao.Instrutor != nil {
+		instrutor = graduacao.Instrutor.Email
+	}
+	pdf.CellFormat(0, 6, tr(instrutor), "", 1, "C", false, 0, "")
+
+	pdf.SetY(altura - 25)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(0, 5, tr("Código de verificação: "+graduacao.ID), "", 0, "R", false, 0, "")
+
+	return pdf.Output(w)
+}
+
+// Converte "#RRGGBB" em componentes RGB
+func corHex(cor string) (int, int, int, bool) {
+	if len(cor) != 7 || cor[0] != '#' {
+		return 0, 0, 0, false
+	}
+	valor, err := strconv.ParseUint(cor[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(valor >> 16 & 0xff), int(valor >> 8 & 0xff), int(valor & 0xff), true
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAulaNaoEncontrada    = errors.New("aula não encontrada ou inativa")
+	ErrAulaForaDoDia        = errors.New("a aula não acontece nesta data")
+	ErrAulaCancelada        = errors.New("a aula está cancelada nesta data")
+	ErrCheckinDuplicado     = errors.New("check-in já registrado para esta aula")
+	ErrIdentificacaoCheckin = errors.New("informe cliente_id, cpf ou qr_token")
+)
+
+// Pendências que tornam um check-in irregular
+const (
+	PendenciaSemAssinatura   = "sem assinatura ativa"
+	PendenciaAtraso          = "assinatura com pagamento em atraso"
+	PendenciaPlanoSemAula    = "plano não inclui a modalidade da aula"
+	PendenciaAtestadoVencido = "atestado médico ausente ou vencido"
+	PendenciaAulaLotada      = "aula acima da capacidade"
+	PendenciaCongelada       = "assinatura congelada"
+)
+
+// Pendências que impedem o check-in quando CHECKIN_MODO=bloquear
+var pendenciasBloqueantes = map[string]bool{
+	PendenciaSemAssinatura: true,
+	PendenciaAtraso:        true,
+	PendenciaPlanoSemAula:  true,
+	PendenciaCongelada:     true,
+}
+
+// CheckinRecusadoError informa por que o check-in foi recusado
+type CheckinRecusadoError struct {
+	Pendencias []string
+}
+
+func (e *CheckinRecusadoError) Error() string {
+	return "check-in recusado: " + strings.Join(e.Pendencias, ", ")
+}
+
+// SolicitacaoCheckin identifica o cliente por um dos meios aceitos e a aula
+type SolicitacaoCheckin struct {
+	ClienteID string `json:"cliente_id"`
+	CPF       string `json:"cpf"`
+	QRToken   string `json:"qr_token"`
+	AulaID    string `json:"aula_id" validate:"required"`
+	Data      string `json:"data"`   // AAAA-MM-DD, padrão: hoje
+	Forcar    bool   `json:"forcar"` // Registra mesmo com pendências bloqueantes
+}
+
+// BloquearCheckinIrregular indica se pendências bloqueantes recusam o check-in.
+// Com CHECKIN_MODO=sinalizar o check-in é aceito e apenas marcado como irregular.
+func BloquearCheckinIrregular() bool {
+	return os.Getenv("CHECKIN_MODO") != "sinalizar"
+}
+
+// RegistrarCheckin identifica o cliente, confere a aula e a situação da
+// assinatura e grava a presença
+func RegistrarCheckin(solicitacao SolicitacaoCheckin, autor string) (*models.Presenca, error) {
+	data := time.Now()
+	if solicitacao.Data != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", solicitacao.Data, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("data inválida: %w", err)
+		}
+		data = parsed
+	}
+	dia := time.Date(data.Year(), data.Month(), data.Day(), 0, 0, 0, 0, time.Local)
+
+	presenca := &models.Presenca{AulaID: solicitacao.AulaID, Data: dia, RegistradoPor: autor}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		cliente, metodo, err := identificarCliente(tx, solicitacao)
+		if err != nil {
+			return err
+		}
+		presenca.ClienteID = cliente.ID
+		presenca.Metodo = metodo
+
+		var aula models.Aula
+		if err := tx.First(&aula, "id = ? AND ativa = ?", solicitacao.AulaID, true).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAulaNaoEncontrada
+			}
+			return err
+		}
+		if aula.DiaSemana != int(dia.Weekday()) {
+			return ErrAulaForaDoDia
+		}
+		if excecao, err := AulaCancelada(tx, aula.ID, dia); err != nil {
+			return err
+		} else if excecao != nil {
+			return ErrAulaCancelada
+		}
+
+		var existente int64
+		if err := tx.Model(&models.Presenca{}).
+			Where("cliente_id = ? AND aula_id = ? AND data = ?", cliente.ID, aula.ID, dia.Format("2006-01-02")).
+			Count(&existente).Error; err != nil {
+			return err
+		}
+		if existente > 0 {
+			return ErrCheckinDuplicado
+		}
+
+		pendencias, err := PendenciasCheckin(tx, cliente, &aula, dia)
+		if err != nil {
+			return err
+		}
+		presenca.Pendencias = pendencias
+		presenca.Irregular = len(pendencias) > 0
+
+		if BloquearCheckinIrregular() && !solicitacao.Forcar {
+			bloqueantes := []string{}
+			for _, pendencia := range pendencias {
+				if pendenciasBloqueantes[pendencia] {
+					bloqueantes = append(bloqueantes, pendencia)
+				}
+			}
+			if len(bloqueantes) > 0 {
+				return &CheckinRecusadoError{Pendencias: bloqueantes}
+			}
+		}
+
+		// O primeiro check-in do dia registra a aula como ministrada; o instrutor
+		// vem do registro, que pode ter um substituto
+		ministrada, err := registrarAulaMinistrada(tx, &aula, dia, autor)
+		if err != nil {
+			return err
+		}
+		presenca.InstrutorID = ministrada.InstrutorID
+
+		return tx.Omit("Cliente", "Aula").Create(presenca).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return presenca, nil
+}
+
+// Localiza o cliente pelo ID, pelo CPF (via índice cego) ou pela credencial do QR
+func identificarCliente(tx *gorm.DB, solicitacao SolicitacaoCheckin) (*models.Cliente, models.MetodoCheckin, error) {
+	query := tx.Scopes(ApenasAtivos).Where("anonimizado_em IS NULL")
+
+	var metodo models.MetodoCheckin
+	switch {
+	case solicitacao.ClienteID != "":
+		metodo = models.CheckinPorID
+		query = query.Where("id = ?", solicitacao.ClienteID)
+	case solicitacao.CPF != "":
+		metodo = models.CheckinPorCPF
+		query = query.Where("cpf_hash = ?", models.IndiceCPF(solicitacao.CPF))
+	case solicitacao.QRToken != "":
+		metodo = models.CheckinPorQR
+		clienteID, err := ValidarTokenCheckin(tx, solicitacao.QRToken)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.Where("id = ?", clienteID)
+	default:
+		return nil, "", ErrIdentificacaoCheckin
+	}
+
+	var cliente models.Cliente
+	if err := query.First(&cliente).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrClienteNaoEncontrado
+		}
+		return nil, "", err
+	}
+	return &cliente, metodo, nil
+}
+
+// PendenciasCheckin lista o que impede ou torna irregular a presença do cliente na aula
+func PendenciasCheckin(tx *gorm.DB, cliente *models.Cliente, aula *models.Aula, dia time.Time) ([]string, error) {
+	pendencias, err := pendenciasAssinatura(tx, cliente.ID, aula, dia)
+	if err != nil {
+		return nil, err
+	}
+
+	var perfil models.PerfilSaude
+	if err := tx.Where("cliente_id = ?", cliente.ID).First(&perfil).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if perfil.AtestadoVencido(dia) {
+		pendencias = append(pendencias, PendenciaAtestadoVencido)
+	}
+
+	var presentes int64
+	if err := tx.Model(&models.Presenca{}).Where("aula_id = ? AND data = ?", aula.ID, dia.Format("2006-01-02")).
+		Count(&presentes).Error; err != nil {
+		return nil, err
+	}
+	if int(presentes) >= aula.Capacidade {
+		pendencias = append(pendencias, PendenciaAulaLotada)
+	}
+
+	return pendencias, nil
+}
+
+// Confere se o cliente tem assinatura ativa, fora de congelamento no dia, em
+// dia e com um plano que inclua a aula
+func pendenciasAssinatura(tx *gorm.DB, clienteID string, aula *models.Aula, dia time.Time) ([]string, error) {
+	pendencias := []string{}
+
+	var assinaturas []models.Subscription
+	if err := tx.Where("cliente_id = ? AND active = ?", clienteID, true).
+		Where("id NOT IN (?)", assinaturasCongeladas(tx, dia)).
+		Find(&assinaturas).Error; err != nil {
+		return nil, err
+	}
+	if len(assinaturas) == 0 {
+		var congeladas int64
+		if err := tx.Model(&models.Subscription{}).
+			Where("cliente_id = ? AND active = ? AND id IN (?)", clienteID, true, assinaturasCongeladas(tx, dia)).
+			Count(&congeladas).Error; err != nil {
+			return nil, err
+		}
+		if congeladas > 0 {
+			return append(pendencias, PendenciaCongelada), nil
+		}
+		return append(pendencias, PendenciaSemAssinatura), nil
+	}
+
+	emDia := []models.Subscription{}
+	for _, assinatura := range assinaturas {
+		if assinatura.PaymentStatus != models.Overdue {
+			emDia = append(emDia, assinatura)
+		}
+	}
+	if len(emDia) == 0 {
+		pendencias = append(pendencias, PendenciaAtraso)
+		emDia = assinaturas // O plano é conferido mesmo com atraso
+	}
+
+	permitida, err := algumPlanoPermiteAula(tx, emDia, aula)
+	if err != nil {
+		return nil, err
+	}
+	if !permitida {
+		pendencias = append(pendencias, PendenciaPlanoSemAula)
+	}
+	return pendencias, nil
+}
+
+// Assinaturas sem plano ou planos sem modalidades definidas liberam todas as aulas
+func algumPlanoPermiteAula(tx *gorm.DB, assinaturas []models.Subscription, aula *models.Aula) (bool, error) {
+	planos := []string{}
+	for _, assinatura := range assinaturas {
+		if assinatura.ProdutoID == nil {
+			return true, nil
+		}
+		planos = append(planos, *assinatura.ProdutoID)
+	}
+
+	var restritos []string
+	if err := tx.Table("plano_modalidades").Where("produto_id IN ?", planos).
+		Distinct().Pluck("produto_id", &restritos).Error; err != nil {
+		return false, err
+	}
+	if len(restritos) < len(unicos(planos)) {
+		return true, nil
+	}
+
+	var total int64
+	if err := tx.Table("plano_modalidades").
+		Where("produto_id IN ? AND modalidade_id = ?", planos, aula.ModalidadeID).
+		Count(&total).Error; err != nil {
+		return false, err
+	}
+	return total > 0, nil
+}
+
+func unicos(valores []string) []string {
+	vistos := map[string]bool{}
+	resultado := []string{}
+	for _, valor := range valores {
+		if !vistos[valor] {
+			vistos[valor] = true
+			resultado = append(resultado, valor)
+		}
+	}
+	return resultado
+}
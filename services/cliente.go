@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrClienteNaoEncontrado   = errors.New("cliente não encontrado")
+	ErrResponsavelObrigatorio = errors.New("cliente menor de idade precisa de pelo menos um responsável")
+	ErrClienteDuplicado       = errors.New("já existe um cliente com este CPF ou e-mail")
+)
+
+// Verifica pelos índices cegos se outro cliente já usa o CPF ou o e-mail
+func verificarDuplicidade(tx *gorm.DB, cliente *models.Cliente) error {
+	cpfHash := models.IndiceCPF(cliente.CPF)
+	emailHash := models.IndiceEmail(cliente.Email)
+
+	query := tx.Model(&models.Cliente{})
+	switch {
+	case cpfHash != "" && emailHash != "":
+		query = query.Where("cpf_hash = ? OR email_hash = ?", cpfHash, emailHash)
+	case cpfHash != "":
+		query = query.Where("cpf_hash = ?", cpfHash)
+	case emailHash != "":
+		query = query.Where("email_hash = ?", emailHash)
+	default:
+		return nil
+	}
+	if cliente.ID != "" {
+		query = query.Where("id <> ?", cliente.ID)
+	}
+	query = query.Scopes(OcupamIndicesUnicos)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return err
+	}
+	if total > 0 {
+		return ErrClienteDuplicado
+	}
+	return nil
+}
+
+// OcupamIndicesUnicos restringe a consulta aos clientes que contam nos índices
+// únicos de CPF e e-mail: anonimizados e duplicados mesclados ficam de fora
+func OcupamIndicesUnicos(tx *gorm.DB) *gorm.DB {
+	// Mesmo critério dos índices únicos criados em InitDB
+	return tx.Where("anonimizado_em IS NULL AND (motivo_arquivamento IS NULL OR motivo_arquivamento <> ?)", models.MotivoDuplicado)
+}
+
+// Índices únicos de CPF e e-mail criados em InitDB
+var indicesUnicosCliente = map[string]bool{
+	"idx_clientes_cpf_hash_unico":   true,
+	"idx_clientes_email_hash_unico": true,
+}
+
+// A verificação de duplicidade não impede dois cadastros simultâneos; o índice
+// único recusa o segundo, que é reportado como duplicado
+func traduzirErroCliente(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && indicesUnicosCliente[pgErr.ConstraintName] {
+		return ErrClienteDuplicado
+	}
+	return err
+}
+
+// CriarCliente cria o cliente, seus responsáveis e os dados legados dos pais
+// em uma única transação. Se qualquer etapa falhar nada é gravado.
+func CriarCliente(cliente *models.Cliente, pais *models.Pais, autor string) error {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return criarCliente(tx, cliente, pais, autor)
+	})
+	if err != nil {
+		cliente.ID = ""
+		return err
+	}
+	return nil
+}
+
+// Grava o cliente, os responsáveis e os pais dentro da transação recebida
+func criarCliente(tx *gorm.DB, cliente *models.Cliente, pais *models.Pais, autor string) error {
+	if cliente.MenorDeIdade() && len(cliente.Guardians) == 0 {
+		return ErrResponsavelObrigatorio
+	}
+
+	guardians := cliente.Guardians
+	cliente.Guardians = nil
+	cliente.ID = "" // Ignorar o ID enviado e gerar um novo
+	cliente.FlagAniversariante, _ = FazAniversarioNaJanela(cliente.DataNascimento, JanelaPadrao(), time.Now())
+	cliente.FlagInadimplente = false // Calculada a partir das vendas e assinaturas
+	cliente.PaisID = nil
+	cliente.FamiliaID = nil // Gerenciada pelas rotas /familias
+	// Arquivamento e anonimização são alterados apenas pelas rotas próprias
+	cliente.ArquivadoEm = nil
+	cliente.MotivoArquivamento = ""
+	cliente.AnonimizadoEm = nil
+
+	if err := verificarDuplicidade(tx, cliente); err != nil {
+		return err
+	}
+
+	if err := tx.Omit(clause.Associations).Create(cliente).Error; err != nil {
+		return fmt.Errorf("erro ao criar cliente: %w", traduzirErroCliente(err))
+	}
+
+	for i := range guardians {
+		guardians[i].ID = ""
+		guardians[i].ClienteID = cliente.ID
+	}
+	if len(guardians) > 0 {
+		if err := tx.Create(&guardians).Error; err != nil {
+			return fmt.Errorf("erro ao criar responsáveis: %w", err)
+		}
+	}
+
+	// Dados legados de pai e mãe continuam aceitos para menores de idade
+	if pais != nil && cliente.MenorDeIdade() {
+		pais.ID = ""
+		pais.ClienteID = cliente.ID
+		if err := tx.Create(pais).Error; err != nil {
+			return fmt.Errorf("erro ao criar pais: %w", err)
+		}
+
+		if err := tx.Model(cliente).Update("pais_id", pais.ID).Error; err != nil {
+			return fmt.Errorf("erro ao atualizar cliente: %w", err)
+		}
+		cliente.PaisID = &pais.ID
+		cliente.Pais = pais
+	}
+
+	cliente.Guardians = guardians
+	return RegistrarAuditoria(tx, "cliente", cliente.ID, &cliente.ID, "cliente.criacao", autor, nil)
+}
+
+// AtualizarCliente grava os dados do cliente e, opcionalmente, dos pais,
+// garantindo na mesma transação que menores continuem com um responsável.
+func AtualizarCliente(cliente *models.Cliente, pais *models.Pais, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var existente models.Cliente
+		if err := tx.First(&existente, "id = ?", cliente.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+		if existente.AnonimizadoEm != nil {
+			return ErrClienteAnonimizado
+		}
+		if err := verificarDuplicidade(tx, cliente); err != nil {
+			return err
+		}
+
+		if cliente.MenorDeIdade() {
+			var total int64
+			if err := tx.Model(&models.Guardian{}).Where("cliente_id = ? AND arquivado_em IS NULL", cliente.ID).Count(&total).Error; err != nil {
+				return err
+			}
+			if total == 0 {
+				return ErrResponsavelObrigatorio
+			}
+		}
+
+		// A flag de aniversariante é sempre calculada a partir da data de nascimento
+		cliente.FlagAniversariante, _ = FazAniversarioNaJanela(cliente.DataNascimento, JanelaPadrao(), time.Now())
+
+		// A inadimplência não pode ser alterada manualmente
+		cliente.FlagInadimplente = existente.FlagInadimplente
+
+		// A família é alterada apenas pelas rotas /familias
+		cliente.FamiliaID = existente.FamiliaID
+
+		// Arquivamento e anonimização têm rotas próprias, com auditoria
+		cliente.ArquivadoEm = existente.ArquivadoEm
+		cliente.MotivoArquivamento = existente.MotivoArquivamento
+		cliente.AnonimizadoEm = existente.AnonimizadoEm
+		cliente.CreatedAt = existente.CreatedAt
+
+		// Os responsáveis são gerenciados pelas rotas /clientes/:id/guardians
+		if err := tx.Omit(clause.Associations).Save(cliente).Error; err != nil {
+			return fmt.Errorf("erro ao atualizar cliente: %w", traduzirErroCliente(err))
+		}
+
+		if pais != nil {
+			pais.ClienteID = cliente.ID
+			var atual models.Pais
+			if err := tx.Where("cliente_id = ?", cliente.ID).First(&atual).Error; err == nil {
+				pais.ID = atual.ID
+				pais.CreatedAt = atual.CreatedAt
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			} else {
+				pais.ID = ""
+			}
+
+			if err := tx.Save(pais).Error; err != nil {
+				return fmt.Errorf("erro ao atualizar pais: %w", err)
+			}
+			if err := tx.Model(cliente).Update("pais_id", pais.ID).Error; err != nil {
+				return fmt.Errorf("erro ao atualizar cliente: %w", err)
+			}
+			cliente.PaisID = &pais.ID
+			cliente.Pais = pais
+		}
+
+		// Apenas os nomes dos campos alterados são registrados, sem os valores pessoais
+		dados := map[string]interface{}{"campos": camposAlterados(&existente, cliente)}
+		if pais != nil {
+			dados["pais"] = true
+		}
+		return RegistrarAuditoria(tx, "cliente", cliente.ID, &cliente.ID, "cliente.atualizacao", autor, dados)
+	})
+}
+
+// Lista os campos do cadastro que mudaram na atualização
+func camposAlterados(antes, depois *models.Cliente) []string {
+	campos := []string{}
+	comparar := func(nome string, a, b interface{}) {
+		if a != b {
+			campos = append(campos, nome)
+		}
+	}
+
+	comparar("nome", antes.Nome, depois.Nome)
+	comparar("data_nascimento", antes.DataNascimento.Format("2006-01-02"), depois.DataNascimento.Format("2006-01-02"))
+	comparar("genero", antes.Genero, depois.Genero)
+	comparar("email", antes.Email, depois.Email)
+	comparar("telefone", antes.Telefone, depois.Telefone)
+	comparar("cpf", antes.CPF, depois.CPF)
+	comparar("endereco", antes.Endereco, depois.Endereco)
+	comparar("cidade", antes.Cidade, depois.Cidade)
+	comparar("estado", antes.Estado, depois.Estado)
+	comparar("cep", antes.CEP, depois.CEP)
+	return campos
+}
+
+// DeletarCliente remove definitivamente um cliente sem registros financeiros.
+// Responsáveis e pais são removidos pelo ON DELETE CASCADE das chaves estrangeiras.
+// Clientes com vendas ou assinaturas devem ser arquivados ou anonimizados pela LGPD.
+func DeletarCliente(id, autor string) error {
+	var arquivos []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var cliente models.Cliente
+		if err := tx.First(&cliente, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+
+		financeiro, err := possuiRegistrosFinanceiros(tx, id)
+		if err != nil {
+			return err
+		}
+		if financeiro {
+			return ErrClienteComRegistrosFinanceiros
+		}
+
+		// Os documentos são apagados em cascata; os arquivos são removidos após o commit
+		if arquivos, err = chavesDocumentos(tx, id); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&cliente).Error; err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "cliente", id, nil, "cliente.exclusao", autor, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	removerArquivos(arquivos)
+	return nil
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAssinaturaNaoEncontrada   = errors.New("assinatura não encontrada")
+	ErrAssinaturaInativa         = errors.New("assinatura inativa")
+	ErrCongelamentoNaoEncontrado = errors.New("congelamento não encontrado")
+	ErrPeriodoCongelamento       = errors.New("período inválido: o início não pode ser passado nem posterior ao fim")
+	ErrCongelamentoForaContrato  = errors.New("o congelamento começa após o fim do contrato")
+	ErrCongelamentoSobreposto    = errors.New("já existe congelamento da assinatura neste período")
+	ErrCongelamentoNaoPermitido  = errors.New("o plano da assinatura não permite congelamento")
+	ErrLimiteCongelamento        = errors.New("limite de dias de congelamento do plano excedido")
+	ErrCongelamentoEncerrado     = errors.New("congelamento já encerrado ou cancelado")
+)
+
+// Congelamentos que ainda suspendem ou vão suspender a assinatura
+var congelamentosVigentes = []models.StatusCongelamento{models.CongelamentoAgendado, models.CongelamentoAtivo}
+
+// SolicitacaoCongelamento são os dados para congelar uma assinatura
+type SolicitacaoCongelamento struct {
+	Inicio string `json:"inicio" validate:"required,datetime=2006-01-02"`
+	Fim    string `json:"fim" validate:"required,datetime=2006-01-02"`
+	Motivo string `json:"motivo" validate:"required,min=3"`
+}
+
+// DiasCongelamentoPadrao é o limite, a cada 12 meses, das assinaturas sem
+// plano, configurável pela variável CONGELAMENTO_MAX_DIAS (padrão: 30)
+func DiasCongelamentoPadrao() int {
+	dias := 30
+	if valor := os.Getenv("CONGELAMENTO_MAX_DIAS"); valor != "" {
+		if n, err := strconv.Atoi(valor); err == nil && n >= 0 {
+			dias = n
+		}
+	}
+	return dias
+}
+
+// CongelarAssinatura suspende a assinatura no período informado, respeitando o
+// limite de dias do plano nos últimos 12 meses. A próxima cobrança e o fim do
+// contrato são adiados já no agendamento pela quantidade de dias congelados; uma
+// cobrança que só cair no período depois disso é adiada quando ele começa.
+func CongelarAssinatura(subscriptionID string, solicitacao SolicitacaoCongelamento, autor string) (*models.CongelamentoAssinatura, error) {
+	inicio, err := time.ParseInLocation("2006-01-02", solicitacao.Inicio, time.Local)
+	if err != nil {
+		return nil, ErrPeriodoCongelamento
+	}
+	fim, err := time.ParseInLocation("2006-01-02", solicitacao.Fim, time.Local)
+	if err != nil {
+		return nil, ErrPeriodoCongelamento
+	}
+	hoje := dataLocal(time.Now())
+	if inicio.Before(hoje) || fim.Before(inicio) {
+		return nil, ErrPeriodoCongelamento
+	}
+
+	congelamento := &models.CongelamentoAssinatura{
+		SubscriptionID: subscriptionID,
+		Inicio:         inicio,
+		Fim:            fim,
+		Dias:           diasEntre(inicio, fim) + 1, // Início e fim inclusive
+		Motivo:         solicitacao.Motivo,
+		Status:         models.CongelamentoAgendado,
+		CriadoPor:      autor,
+	}
+	if !inicio.After(hoje) {
+		congelamento.Status = models.CongelamentoAtivo
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		assinatura, err := bloquearAssinatura(tx, subscriptionID)
+		if err != nil {
+			return err
+		}
+		if !assinatura.Active {
+			return ErrAssinaturaInativa
+		}
+		if assinatura.DataFimContrato != nil && inicio.After(dataLocal(assinatura.DataFimContrato.In(time.Local))) {
+			return ErrCongelamentoForaContrato
+		}
+
+		var sobrepostos int64
+		if err := tx.Model(&models.CongelamentoAssinatura{}).
+			Where("subscription_id = ? AND status <> ? AND inicio <= ? AND fim >= ?", subscriptionID, models.CongelamentoCancelado,
+				fim.Format("2006-01-02"), inicio.Format("2006-01-02")).
+			Count(&sobrepostos).Error; err != nil {
+			return err
+		}
+		if sobrepostos > 0 {
+			return ErrCongelamentoSobreposto
+		}
+
+		limite, err := limiteCongelamento(tx, assinatura)
+		if err != nil {
+			return err
+		}
+		var usados int64
+		if err := tx.Model(&models.CongelamentoAssinatura{}).
+			Where("subscription_id = ? AND status <> ? AND inicio > ?", subscriptionID, models.CongelamentoCancelado,
+				inicioJanelaLimite(fim).Format("2006-01-02")).
+			Select("COALESCE(SUM(dias), 0)").Scan(&usados).Error; err != nil {
+			return err
+		}
+		if err := verificarLimiteCongelamento(limite, int(usados), congelamento.Dias); err != nil {
+			return err
+		}
+
+		congelamento.AdiouCobranca = adiaCobranca(assinatura.NextBillingDate, inicio)
+		if err := tx.Create(congelamento).Error; err != nil {
+			return err
+		}
+		if err := deslocarAssinatura(tx, assinatura, congelamento, congelamento.Dias); err != nil {
+			return err
+		}
+
+		congelamento.Subscription = assinatura
+		return RegistrarAuditoria(tx, "subscription", assinatura.ID, &assinatura.ClienteID, "assinatura.congelamento", autor,
+			map[string]interface{}{"congelamento_id": congelamento.ID, "inicio": solicitacao.Inicio, "fim": solicitacao.Fim,
+				"dias": congelamento.Dias, "motivo": congelamento.Motivo, "proxima_cobranca": assinatura.NextBillingDate})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return congelamento, nil
+}
+
+// EncerrarCongelamento reativa a assinatura antes do fim previsto. Um
+// congelamento ainda não iniciado é cancelado; um em andamento termina ontem.
+// Os dias não usados voltam a contar e são descontados dos adiamentos.
+func EncerrarCongelamento(subscriptionID, congelamentoID, autor string) (*models.CongelamentoAssinatura, error) {
+	var congelamento models.CongelamentoAssinatura
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		assinatura, err := bloquearAssinatura(tx, subscriptionID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&congelamento, "id = ? AND subscription_id = ?", congelamentoID, subscriptionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCongelamentoNaoEncontrado
+			}
+			return err
+		}
+		if congelamento.Status != models.CongelamentoAgendado && congelamento.Status != models.CongelamentoAtivo {
+			return ErrCongelamentoEncerrado
+		}
+
+		agora := time.Now()
+		naoUsados := encerrarNoDia(&congelamento, dataLocal(agora))
+		acao := "assinatura.descongelamento"
+		if congelamento.Status == models.CongelamentoCancelado {
+			acao = "assinatura.congelamento_cancelado"
+		}
+		congelamento.EncerradoEm = &agora
+		congelamento.EncerradoPor = autor
+		if err := tx.Save(&congelamento).Error; err != nil {
+			return err
+		}
+		if err := deslocarAssinatura(tx, assinatura, &congelamento, -naoUsados); err != nil {
+			return err
+		}
+
+		congelamento.Subscription = assinatura
+		return RegistrarAuditoria(tx, "subscription", assinatura.ID, &assinatura.ClienteID, acao, autor,
+			map[string]interface{}{"congelamento_id": congelamento.ID, "dias_usados": congelamento.Dias,
+				"dias_devolvidos": naoUsados, "proxima_cobranca": assinatura.NextBillingDate})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &congelamento, nil
+}
+
+// ListarCongelamentos retorna os congelamentos da assinatura, do mais recente ao mais antigo
+func ListarCongelamentos(subscriptionID string) ([]models.CongelamentoAssinatura, error) {
+	var total int64
+	if err := config.DB.Model(&models.Subscription{}).Where("id = ?", subscriptionID).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, ErrAssinaturaNaoEncontrada
+	}
+
+	var congelamentos []models.CongelamentoAssinatura
+	if err := config.DB.Where("subscription_id = ?", subscriptionID).Order("inicio DESC").Find(&congelamentos).Error; err != nil {
+		return nil, err
+	}
+	return congelamentos, nil
+}
+
+// AtualizarCongelamentos inicia os congelamentos agendados que começam até o
+// dia e reativa as assinaturas cujo congelamento já terminou. Retorna os
+// congelamentos encerrados, com a assinatura e o cliente, para o aviso de reativação.
+func AtualizarCongelamentos(dia time.Time, autor string) (iniciados int, encerrados []models.CongelamentoAssinatura, err error) {
+	data := dia.Format("2006-01-02")
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var agendados []models.CongelamentoAssinatura
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND inicio <= ? AND fim >= ?", models.CongelamentoAgendado, data, data).
+			Find(&agendados).Error; err != nil {
+			return err
+		}
+		for i := range agendados {
+			congelamento := &agendados[i]
+			assinatura, err := bloquearAssinatura(tx, congelamento.SubscriptionID)
+			if err != nil {
+				return err
+			}
+
+			// A cobrança pode ter avançado para dentro do período desde o agendamento
+			campos := map[string]interface{}{"status": models.CongelamentoAtivo}
+			if adiarCobrancaPendente(assinatura, congelamento) {
+				if err := tx.Model(assinatura).Update("next_billing_date", assinatura.NextBillingDate).Error; err != nil {
+					return err
+				}
+				campos["adiou_cobranca"] = true
+			}
+			if err := tx.Model(&models.CongelamentoAssinatura{}).Where("id = ?", congelamento.ID).
+				Updates(campos).Error; err != nil {
+				return err
+			}
+			if err := RegistrarAuditoria(tx, "subscription", congelamento.SubscriptionID, &assinatura.ClienteID,
+				"assinatura.congelamento_inicio", autor, map[string]interface{}{"congelamento_id": congelamento.ID,
+					"adiou_cobranca": congelamento.AdiouCobranca, "proxima_cobranca": assinatura.NextBillingDate}); err != nil {
+				return err
+			}
+		}
+		iniciados = len(agendados)
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Subscription.Cliente").
+			Where("status IN ? AND fim < ?", congelamentosVigentes, data).
+			Find(&encerrados).Error; err != nil {
+			return err
+		}
+		agora := time.Now()
+		for i := range encerrados {
+			congelamento := &encerrados[i]
+			congelamento.Status = models.CongelamentoEncerrado
+			congelamento.EncerradoEm = &agora
+			congelamento.EncerradoPor = autor
+			if err := tx.Model(&models.CongelamentoAssinatura{}).Where("id = ?", congelamento.ID).Updates(map[string]interface{}{
+				"status": congelamento.Status, "encerrado_em": agora, "encerrado_por": autor,
+			}).Error; err != nil {
+				return err
+			}
+			if err := RegistrarAuditoria(tx, "subscription", congelamento.SubscriptionID, &congelamento.Subscription.ClienteID,
+				"assinatura.reativacao", autor, map[string]interface{}{"congelamento_id": congelamento.ID, "dias": congelamento.Dias}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// Assinaturas com congelamento em vigor no dia, para uso como subconsulta
+func assinaturasCongeladas(tx *gorm.DB, dia time.Time) *gorm.DB {
+	data := dia.Format("2006-01-02")
+	return tx.Model(&models.CongelamentoAssinatura{}).Select("subscription_id").
+		Where("status IN ? AND inicio <= ? AND fim >= ?", congelamentosVigentes, data, data)
+}
+
+// Os dias usados contam a partir de 12 meses antes do fim do novo congelamento
+func inicioJanelaLimite(fim time.Time) time.Time {
+	return fim.AddDate(-1, 0, 0)
+}
+
+// Confere se os dias pedidos cabem no limite do plano, descontados os já usados
+func verificarLimiteCongelamento(limite, usados, dias int) error {
+	if limite == 0 {
+		return ErrCongelamentoNaoPermitido
+	}
+	if usados+dias > limite {
+		return fmt.Errorf("%w: restam %d de %d dias", ErrLimiteCongelamento, max(limite-usados, 0), limite)
+	}
+	return nil
+}
+
+// Cobranças a partir do início do congelamento são adiadas; as anteriores
+// acontecem normalmente
+func adiaCobranca(proximaCobranca, inicio time.Time) bool {
+	return !dataLocal(proximaCobranca.In(time.Local)).Before(inicio)
+}
+
+// Adia a próxima cobrança que caiu no congelamento depois do agendamento, pela
+// quantidade de dias congelados. Retorna se a assinatura foi alterada.
+func adiarCobrancaPendente(assinatura *models.Subscription, congelamento *models.CongelamentoAssinatura) bool {
+	if congelamento.AdiouCobranca || !adiaCobranca(assinatura.NextBillingDate, dataLocal(congelamento.Inicio)) {
+		return false
+	}
+	congelamento.AdiouCobranca = true
+	assinatura.NextBillingDate = assinatura.NextBillingDate.In(time.Local).AddDate(0, 0, congelamento.Dias)
+	return true
+}
+
+// Encerra o congelamento no dia e retorna os dias não usados. Um congelamento
+// que começa no dia ou depois é cancelado por inteiro; um em andamento termina
+// na véspera.
+func encerrarNoDia(congelamento *models.CongelamentoAssinatura, hoje time.Time) int {
+	inicio, fim := dataLocal(congelamento.Inicio), dataLocal(congelamento.Fim)
+	switch {
+	case !inicio.Before(hoje):
+		congelamento.Status = models.CongelamentoCancelado
+		return congelamento.Dias
+	case !fim.Before(hoje):
+		naoUsados := diasEntre(hoje, fim) + 1
+		congelamento.Dias -= naoUsados
+		congelamento.Fim = hoje.AddDate(0, 0, -1)
+		congelamento.Status = models.CongelamentoEncerrado
+		return naoUsados
+	}
+	// Já terminou e aguarda apenas a rotina de reativação
+	congelamento.Status = models.CongelamentoEncerrado
+	return 0
+}
+
+// Dias de congelamento permitidos a cada 12 meses pelo plano da assinatura
+func limiteCongelamento(tx *gorm.DB, assinatura *models.Subscription) (int, error) {
+	if assinatura.ProdutoID == nil {
+		return DiasCongelamentoPadrao(), nil
+	}
+	var plano models.ProdutoServico
+	if err := tx.Select("id", "max_dias_congelamento").First(&plano, "id = ?", *assinatura.ProdutoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrPlanoNaoEncontrado
+		}
+		return 0, err
+	}
+	return plano.MaxDiasCongelamento, nil
+}
+
+// Adia (dias > 0) ou antecipa (dias < 0) a próxima cobrança, quando o
+// congelamento a adiou, e o fim do contrato da assinatura
+func deslocarAssinatura(tx *gorm.DB, assinatura *models.Subscription, congelamento *models.CongelamentoAssinatura, dias int) error {
+	campos := deslocarDatas(assinatura, congelamento, dias)
+	if len(campos) == 0 {
+		return nil
+	}
+	return tx.Model(assinatura).Updates(campos).Error
+}
+
+// Desloca as datas da assinatura em dias corridos, preservando o horário local
+// mesmo com mudança de horário de verão, e retorna as colunas alteradas
+func deslocarDatas(assinatura *models.Subscription, congelamento *models.CongelamentoAssinatura, dias int) map[string]interface{} {
+	campos := map[string]interface{}{}
+	if dias == 0 {
+		return campos
+	}
+	if congelamento.AdiouCobranca {
+		assinatura.NextBillingDate = assinatura.NextBillingDate.In(time.Local).AddDate(0, 0, dias)
+		campos["next_billing_date"] = assinatura.NextBillingDate
+	}
+	if assinatura.DataFimContrato != nil {
+		fimContrato := assinatura.DataFimContrato.In(time.Local).AddDate(0, 0, dias)
+		assinatura.DataFimContrato = &fimContrato
+		campos["data_fim_contrato"] = fimContrato
+	}
+	return campos
+}
+
+// Carrega a assinatura com bloqueio de linha até o fim da transação
+func bloquearAssinatura(tx *gorm.DB, subscriptionID string) (*models.Subscription, error) {
+	var assinatura models.Subscription
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&assinatura, "id = ?", subscriptionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAssinaturaNaoEncontrada
+		}
+		return nil, err
+	}
+	return &assinatura, nil
+}
+
+// Datas do tipo date voltam do banco em UTC; compara sempre no fuso local
+func dataLocal(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// Dias corridos entre duas datas, imune a mudanças de horário de verão
+func diasEntre(inicio, fim time.Time) int {
+	return int(math.Round(fim.Sub(inicio).Hours() / 24))
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"go-api/models"
+)
+
+// Troca o fuso local durante o teste; New York tem horário de verão
+func usarFuso(t *testing.T, nome string) {
+	t.Helper()
+	fuso, err := time.LoadLocation(nome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anterior := time.Local
+	time.Local = fuso
+	t.Cleanup(func() { time.Local = anterior })
+}
+
+func diaLocal(ano int, mes time.Month, dia int) time.Time {
+	return time.Date(ano, mes, dia, 0, 0, 0, 0, time.Local)
+}
+
+// Colunas date voltam do banco à meia-noite UTC
+func dataBanco(ano int, mes time.Month, dia int) time.Time {
+	return time.Date(ano, mes, dia, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDiasEntre(t *testing.T) {
+	for _, nomeFuso := range []string{"America/Sao_Paulo", "America/New_York"} {
+		t.Run(nomeFuso, func(t *testing.T) {
+			usarFuso(t, nomeFuso)
+			casos := []struct {
+				nome        string
+				inicio, fim time.Time
+				dias        int
+			}{
+				{"mesmo dia", diaLocal(2026, 5, 10), diaLocal(2026, 5, 10), 0},
+				{"início do horário de verão", diaLocal(2026, 3, 7), diaLocal(2026, 3, 9), 2},
+				{"fim do horário de verão", diaLocal(2026, 10, 31), diaLocal(2026, 11, 2), 2},
+				{"fim de janeiro a março", diaLocal(2026, 1, 31), diaLocal(2026, 3, 1), 29},
+				{"ano bissexto", diaLocal(2028, 2, 28), diaLocal(2028, 3, 1), 2},
+				{"virada de ano", diaLocal(2026, 12, 31), diaLocal(2027, 1, 1), 1},
+			}
+			for _, caso := range casos {
+				if dias := diasEntre(caso.inicio, caso.fim); dias != caso.dias {
+					t.Errorf("%s: %d dias, esperado %d", caso.nome, dias, caso.dias)
+				}
+			}
+		})
+	}
+}
+
+func TestInicioJanelaLimite(t *testing.T) {
+	usarFuso(t, "America/Sao_Paulo")
+	casos := []struct {
+		fim, inicio time.Time
+	}{
+		{diaLocal(2026, 3, 31), diaLocal(2025, 3, 31)},
+		{diaLocal(2026, 12, 31), diaLocal(2025, 12, 31)},
+		{diaLocal(2028, 2, 29), diaLocal(2027, 3, 1)}, // 29/02 não existe no ano anterior
+	}
+	for _, caso := range casos {
+		if inicio := inicioJanelaLimite(caso.fim); !inicio.Equal(caso.inicio) {
+			t.Errorf("fim %s: janela a partir de %s, esperado %s", caso.fim.Format("2006-01-02"),
+				inicio.Format("2006-01-02"), caso.inicio.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestVerificarLimiteCongelamento(t *testing.T) {
+	casos := []struct {
+		limite, usados, dias int
+		erro                 error
+		mensagem             string
+	}{
+		{0, 0, 1, ErrCongelamentoNaoPermitido, ""},
+		{30, 0, 30, nil, ""},
+		{30, 10, 20, nil, ""},
+		{30, 10, 21, ErrLimiteCongelamento, "restam 20 de 30 dias"},
+		{30, 40, 1, ErrLimiteCongelamento, "restam 0 de 30 dias"},
+	}
+	for _, caso := range casos {
+		err := verificarLimiteCongelamento(caso.limite, caso.usados, caso.dias)
+		if !errors.Is(err, caso.erro) {
+			t.Errorf("limite %d, usados %d, dias %d: erro = %v, esperado %v", caso.limite, caso.usados, caso.dias, err, caso.erro)
+			continue
+		}
+		if caso.mensagem != "" && !strings.Contains(err.Error(), caso.mensagem) {
+			t.Errorf("mensagem %q não contém %q", err.Error(), caso.mensagem)
+		}
+	}
+}
+
+func TestAdiaCobranca(t *testing.T) {
+	usarFuso(t, "America/Sao_Paulo")
+	inicio := diaLocal(2026, 5, 10)
+	casos := []struct {
+		nome    string
+		proxima time.Time
+		adiavel bool
+	}{
+		{"no início", diaLocal(2026, 5, 10), true},
+		{"depois do início", diaLocal(2026, 6, 1), true},
+		{"na véspera", diaLocal(2026, 5, 9), false},
+		// Meia-noite local de 10/05 lida do banco em UTC
+		{"timestamptz em UTC", time.Date(2026, 5, 10, 3, 0, 0, 0, time.UTC), true},
+		// 01:00 UTC de 10/05 ainda é 09/05 no horário local
+		{"véspera no horário local", time.Date(2026, 5, 10, 1, 0, 0, 0, time.UTC), false},
+	}
+	for _, caso := range casos {
+		if adiavel := adiaCobranca(caso.proxima, inicio); adiavel != caso.adiavel {
+			t.Errorf("%s: adiaCobranca = %v, esperado %v", caso.nome, adiavel, caso.adiavel)
+		}
+	}
+}
+
+func TestEncerrarNoDia(t *testing.T) {
+	for _, nomeFuso := range []string{"America/Sao_Paulo", "America/New_York"} {
+		t.Run(nomeFuso, func(t *testing.T) {
+			usarFuso(t, nomeFuso)
+			casos := []struct {
+				nome        string
+				inicio, fim time.Time
+				hoje        time.Time
+				naoUsados   int
+				dias        int
+				novoFim     time.Time
+				status      models.StatusCongelamento
+			}{
+				{"agendado", dataBanco(2026, 2, 10), dataBanco(2026, 2, 19), diaLocal(2026, 2, 1), 10, 10, dataBanco(2026, 2, 19), models.CongelamentoCancelado},
+				{"começa hoje", dataBanco(2026, 2, 1), dataBanco(2026, 2, 10), diaLocal(2026, 2, 1), 10, 10, dataBanco(2026, 2, 10), models.CongelamentoCancelado},
+				{"virada de mês", dataBanco(2026, 1, 25), dataBanco(2026, 2, 5), diaLocal(2026, 2, 1), 5, 7, diaLocal(2026, 1, 31), models.CongelamentoEncerrado},
+				{"último dia", dataBanco(2026, 1, 25), dataBanco(2026, 2, 5), diaLocal(2026, 2, 5), 1, 11, diaLocal(2026, 2, 4), models.CongelamentoEncerrado},
+				{"horário de verão", dataBanco(2026, 3, 1), dataBanco(2026, 3, 15), diaLocal(2026, 3, 10), 6, 9, diaLocal(2026, 3, 9), models.CongelamentoEncerrado},
+				{"fevereiro bissexto", dataBanco(2028, 2, 20), dataBanco(2028, 3, 5), diaLocal(2028, 3, 1), 5, 10, diaLocal(2028, 2, 29), models.CongelamentoEncerrado},
+				{"já terminado", dataBanco(2026, 1, 1), dataBanco(2026, 1, 10), diaLocal(2026, 1, 11), 0, 10, dataBanco(2026, 1, 10), models.CongelamentoEncerrado},
+			}
+			for _, caso := range casos {
+				congelamento := models.CongelamentoAssinatura{
+					Inicio: caso.inicio,
+					Fim:    caso.fim,
+					Dias:   diasEntre(dataLocal(caso.inicio), dataLocal(caso.fim)) + 1,
+					Status: models.CongelamentoAtivo,
+				}
+				naoUsados := encerrarNoDia(&congelamento, caso.hoje)
+				if naoUsados != caso.naoUsados || congelamento.Dias != caso.dias || congelamento.Status != caso.status {
+					t.Errorf("%s: não usados %d, dias %d, status %s; esperado %d, %d, %s", caso.nome,
+						naoUsados, congelamento.Dias, congelamento.Status, caso.naoUsados, caso.dias, caso.status)
+				}
+				if !dataLocal(congelamento.Fim).Equal(dataLocal(caso.novoFim)) {
+					t.Errorf("%s: fim %s, esperado %s", caso.nome, congelamento.Fim.Format("2006-01-02"), caso.novoFim.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
+
+func TestDeslocarDatas(t *testing.T) {
+	usarFuso(t, "America/New_York")
+	casos := []struct {
+		nome            string
+		proxima         time.Time
+		fimContrato     *time.Time
+		adiou           bool
+		dias            int
+		novaProxima     time.Time
+		novoFimContrato *time.Time
+		colunas         int
+	}{
+		{"fim de janeiro", diaLocal(2026, 1, 31), nil, true, 30, diaLocal(2026, 3, 2), nil, 1},
+		{"devolução até o fim de janeiro", diaLocal(2026, 3, 2), nil, true, -30, diaLocal(2026, 1, 31), nil, 1},
+		{"horário de verão", diaLocal(2026, 3, 1), ptrData(diaLocal(2026, 10, 25)), true, 14, diaLocal(2026, 3, 15), ptrData(diaLocal(2026, 11, 8)), 2},
+		{"cobrança anterior ao congelamento", diaLocal(2026, 5, 5), ptrData(diaLocal(2026, 12, 31)), false, 10, diaLocal(2026, 5, 5), ptrData(diaLocal(2027, 1, 10)), 1},
+		{"sem deslocamento", diaLocal(2026, 5, 5), ptrData(diaLocal(2026, 12, 31)), true, 0, diaLocal(2026, 5, 5), ptrData(diaLocal(2026, 12, 31)), 0},
+		// Lida do banco em UTC, a data continua a do horário local
+		{"timestamptz em UTC", time.Date(2026, 3, 1, 5, 0, 0, 0, time.UTC), nil, true, 14, diaLocal(2026, 3, 15), nil, 1},
+	}
+	for _, caso := range casos {
+		assinatura := &models.Subscription{NextBillingDate: caso.proxima, DataFimContrato: caso.fimContrato}
+		campos := deslocarDatas(assinatura, &models.CongelamentoAssinatura{AdiouCobranca: caso.adiou}, caso.dias)
+		if len(campos) != caso.colunas {
+			t.Errorf("%s: %d colunas alteradas, esperado %d", caso.nome, len(campos), caso.colunas)
+		}
+		if !assinatura.NextBillingDate.Equal(caso.novaProxima) {
+			t.Errorf("%s: próxima cobrança %s, esperado %s", caso.nome, assinatura.NextBillingDate, caso.novaProxima)
+		}
+		if caso.novoFimContrato != nil && !assinatura.DataFimContrato.Equal(*caso.novoFimContrato) {
+			t.Errorf("%s: fim do contrato %s, esperado %s", caso.nome, assinatura.DataFimContrato, caso.novoFimContrato)
+		}
+	}
+}
+
+// Congelar e reativar antes do fim devolve exatamente os dias não usados
+func TestCongelarEReativarAntesDoFim(t *testing.T) {
+	usarFuso(t, "America/New_York")
+	inicio, fim := diaLocal(2026, 2, 25), diaLocal(2026, 3, 14)
+	assinatura := &models.Subscription{NextBillingDate: diaLocal(2026, 3, 5)}
+	congelamento := &models.CongelamentoAssinatura{
+		Inicio:        inicio,
+		Fim:           fim,
+		Dias:          diasEntre(inicio, fim) + 1,
+		AdiouCobranca: adiaCobranca(assinatura.NextBillingDate, inicio),
+	}
+	if congelamento.Dias != 18 || !congelamento.AdiouCobranca {
+		t.Fatalf("congelamento de %d dias, adiou cobrança %v", congelamento.Dias, congelamento.AdiouCobranca)
+	}
+
+	deslocarDatas(assinatura, congelamento, congelamento.Dias)
+	if !assinatura.NextBillingDate.Equal(diaLocal(2026, 3, 23)) {
+		t.Fatalf("próxima cobrança %s após congelar", assinatura.NextBillingDate)
+	}
+
+	naoUsados := encerrarNoDia(congelamento, diaLocal(2026, 3, 10))
+	deslocarDatas(assinatura, congelamento, -naoUsados)
+	if naoUsados != 5 || congelamento.Dias != 13 {
+		t.Errorf("não usados %d e dias usados %d, esperado 5 e 13", naoUsados, congelamento.Dias)
+	}
+	if !assinatura.NextBillingDate.Equal(diaLocal(2026, 3, 18)) {
+		t.Errorf("próxima cobrança %s após reativar, esperado 18/03", assinatura.NextBillingDate)
+	}
+}
+
+// A cobrança que avançou para dentro do congelamento é adiada quando ele começa
+func TestAdiarCobrancaPendente(t *testing.T) {
+	usarFuso(t, "America/Sao_Paulo")
+	casos := []struct {
+		nome        string
+		proxima     time.Time
+		adiou       bool
+		alterada    bool
+		novaProxima time.Time
+	}{
+		{"cobrança avançou para o congelamento", diaLocal(2026, 2, 10), false, true, diaLocal(2026, 3, 12)},
+		{"cobrança no primeiro dia", diaLocal(2026, 1, 20), false, true, diaLocal(2026, 2, 19)},
+		{"já adiada no agendamento", diaLocal(2026, 3, 12), true, false, diaLocal(2026, 3, 12)},
+		{"cobrança ainda anterior", diaLocal(2026, 1, 19), false, false, diaLocal(2026, 1, 19)},
+	}
+	for _, caso := range casos {
+		assinatura := &models.Subscription{NextBillingDate: caso.proxima}
+		congelamento := &models.CongelamentoAssinatura{
+			Inicio: dataBanco(2026, 1, 20), Fim: dataBanco(2026, 2, 18), Dias: 30, AdiouCobranca: caso.adiou,
+		}
+		if alterada := adiarCobrancaPendente(assinatura, congelamento); alterada != caso.alterada {
+			t.Errorf("%s: alterada = %v, esperado %v", caso.nome, alterada, caso.alterada)
+		}
+		if !congelamento.AdiouCobranca && caso.alterada {
+			t.Errorf("%s: congelamento não registrou o adiamento", caso.nome)
+		}
+		if !assinatura.NextBillingDate.Equal(caso.novaProxima) {
+			t.Errorf("%s: próxima cobrança %s, esperado %s", caso.nome, assinatura.NextBillingDate, caso.novaProxima)
+		}
+	}
+}
+
+func ptrData(t time.Time) *time.Time {
+	return &t
+}
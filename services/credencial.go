@@ -0,0 +1,204 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTokenInvalido      = errors.New("credencial inválida ou expirada")
+	ErrCredencialRevogada = errors.New("credencial revogada")
+)
+
+// Versão do formato do conteúdo do QR code
+const prefixoQR = "q1"
+
+// PeriodoQR retorna de quanto em quanto tempo o código do QR muda,
+// configurável em QR_PERIODO_SEGUNDOS (padrão: 30)
+func PeriodoQR() time.Duration {
+	if valor := os.Getenv("QR_PERIODO_SEGUNDOS"); valor != "" {
+		if segundos, err := strconv.Atoi(valor); err == nil && segundos >= 10 {
+			return time.Duration(segundos) * time.Second
+		}
+	}
+	return 30 * time.Second
+}
+
+// CodigoQR é o conteúdo atual do QR code de um cliente
+type CodigoQR struct {
+	Conteudo string        `json:"conteudo"`
+	ExpiraEm time.Time     `json:"expira_em"`
+	Periodo  time.Duration `json:"-"`
+}
+
+// GerarCodigoQR retorna o código vigente do cliente, criando a credencial no primeiro uso.
+// O código é um HMAC do segredo do cliente com a janela de tempo atual, como no TOTP.
+func GerarCodigoQR(clienteID string, agora time.Time) (*CodigoQR, error) {
+	var codigo *CodigoQR
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var cliente models.Cliente
+		if err := tx.Scopes(ApenasAtivos).Where("anonimizado_em IS NULL").Select("id").First(&cliente, "id = ?", clienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+
+		credencial, err := obterCredencial(tx, clienteID)
+		if err != nil {
+			return err
+		}
+		if credencial.RevogadaEm != nil {
+			return ErrCredencialRevogada
+		}
+
+		periodo := PeriodoQR()
+		janela := agora.Unix() / int64(periodo.Seconds())
+		codigo = &CodigoQR{
+			Conteudo: fmt.Sprintf("%s.%s.%d.%s", prefixoQR, clienteID, janela, assinaturaQR(credencial.Segredo, clienteID, janela)),
+			ExpiraEm: time.Unix((janela+1)*int64(periodo.Seconds()), 0),
+			Periodo:  periodo,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codigo, nil
+}
+
+// ValidarTokenCheckin confere o código lido do QR e retorna o ID do cliente.
+// Aceita a janela anterior e a seguinte para tolerar atraso na leitura e relógios
+// levemente dessincronizados.
+func ValidarTokenCheckin(tx *gorm.DB, token string) (string, error) {
+	partes := strings.Split(token, ".")
+	if len(partes) != 4 || partes[0] != prefixoQR {
+		return "", ErrTokenInvalido
+	}
+	clienteID, assinatura := partes[1], partes[3]
+	janela, err := strconv.ParseInt(partes[2], 10, 64)
+	if err != nil {
+		return "", ErrTokenInvalido
+	}
+
+	atual := time.Now().Unix() / int64(PeriodoQR().Seconds())
+	if janela < atual-1 || janela > atual+1 {
+		return "", ErrTokenInvalido
+	}
+
+	var credencial models.CredencialCheckin
+	if err := tx.First(&credencial, "cliente_id = ?", clienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrTokenInvalido
+		}
+		return "", err
+	}
+	if credencial.RevogadaEm != nil {
+		return "", ErrCredencialRevogada
+	}
+
+	if !hmac.Equal([]byte(assinaturaQR(credencial.Segredo, clienteID, janela)), []byte(assinatura)) {
+		return "", ErrTokenInvalido
+	}
+	return clienteID, nil
+}
+
+// RevogarCredencial impede que os QR codes do cliente sejam aceitos
+func RevogarCredencial(tx *gorm.DB, clienteID, motivo string) error {
+	return tx.Model(&models.CredencialCheckin{}).
+		Where("cliente_id = ? AND revogada_em IS NULL", clienteID).
+		Updates(map[string]interface{}{"revogada_em": time.Now(), "motivo_revogacao": motivo}).Error
+}
+
+// RevogarCredencialSemAssinatura revoga a credencial, dentro da transação
+// recebida, se o cliente não tiver mais nenhuma assinatura ativa
+func RevogarCredencialSemAssinatura(tx *gorm.DB, clienteID, autor string) error {
+	var ativas int64
+	if err := tx.Model(&models.Subscription{}).Where("cliente_id = ? AND active = ?", clienteID, true).Count(&ativas).Error; err != nil {
+		return err
+	}
+	if ativas > 0 {
+		return nil
+	}
+
+	if err := RevogarCredencial(tx, clienteID, "assinatura cancelada"); err != nil {
+		return err
+	}
+	return RegistrarAuditoria(tx, "credencial", clienteID, &clienteID, "credencial.revogacao", autor,
+		map[string]interface{}{"motivo": "assinatura cancelada"})
+}
+
+// ReemitirCredencial troca o segredo do cliente, invalidando os códigos
+// anteriores, e desfaz uma revogação
+func ReemitirCredencial(tx *gorm.DB, clienteID, autor string) error {
+	segredo, err := novoSegredo()
+	if err != nil {
+		return err
+	}
+
+	credencial := models.CredencialCheckin{ClienteID: clienteID, Segredo: segredo}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "cliente_id"}},
+		// Usa os valores da linha inserida para que o segredo passe pelo serializer de criptografia
+		DoUpdates: clause.AssignmentColumns([]string{"segredo", "revogada_em", "motivo_revogacao", "updated_at"}),
+	}).Create(&credencial).Error; err != nil {
+		return err
+	}
+	return RegistrarAuditoria(tx, "credencial", clienteID, &clienteID, "credencial.emissao", autor, nil)
+}
+
+// Retorna a credencial do cliente, criando uma nova se ainda não existir
+func obterCredencial(tx *gorm.DB, clienteID string) (*models.CredencialCheckin, error) {
+	var credencial models.CredencialCheckin
+	err := tx.First(&credencial, "cliente_id = ?", clienteID).Error
+	if err == nil {
+		return &credencial, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	segredo, err := novoSegredo()
+	if err != nil {
+		return nil, err
+	}
+	credencial = models.CredencialCheckin{ClienteID: clienteID, Segredo: segredo}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&credencial).Error; err != nil {
+		return nil, err
+	}
+
+	// Outra requisição pode ter criado a credencial ao mesmo tempo
+	if err := tx.First(&credencial, "cliente_id = ?", clienteID).Error; err != nil {
+		return nil, err
+	}
+	return &credencial, nil
+}
+
+func novoSegredo() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Assinatura truncada em 80 bits para manter o QR code pequeno
+func assinaturaQR(segredo, clienteID string, janela int64) string {
+	mac := hmac.New(sha256.New, []byte(segredo))
+	fmt.Fprintf(mac, "%s:%d", clienteID, janela)
+	return hex.EncodeToString(mac.Sum(nil))[:20]
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Condição SQL para colunas que ainda guardam texto puro
+func textoPuro(colunas ...string) string {
+	condicao := ""
+	for i, coluna := range colunas {
+		if i > 0 {
+			condicao += " OR "
+		}
+		condicao += "(" + coluna + " <> '' AND " + coluna + " NOT LIKE 'enc:%')"
+	}
+	return condicao
+}
+
+// CriptografarDadosLegados regrava os registros anteriores à criptografia de
+// campos, criptografando os dados pessoais e preenchendo os índices cegos
+func CriptografarDadosLegados() error {
+	var clientes []models.Cliente
+	if err := config.DB.Where(textoPuro("cpf", "email", "telefone")).
+		FindInBatches(&clientes, 200, func(tx *gorm.DB, _ int) error {
+			for i := range clientes {
+				if err := config.DB.Omit(clause.Associations).Save(&clientes[i]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error; err != nil {
+		return err
+	}
+
+	var guardians []models.Guardian
+	if err := config.DB.Where(textoPuro("cpf", "email", "telefone")).
+		FindInBatches(&guardians, 200, func(tx *gorm.DB, _ int) error {
+			for i := range guardians {
+				if err := config.DB.Save(&guardians[i]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error; err != nil {
+		return err
+	}
+
+	var pais []models.Pais
+	return config.DB.Where(textoPuro("cpf_pai", "cpf_mae", "telefone_pai", "email_pai", "telefone_mae", "email_mae")).
+		FindInBatches(&pais, 200, func(tx *gorm.DB, _ int) error {
+			for i := range pais {
+				if err := config.DB.Save(&pais[i]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
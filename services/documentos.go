@@ -0,0 +1,258 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+	"go-api/storage"
+	"go-api/utils"
+
+	"github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDocumentoNaoEncontrado   = errors.New("documento não encontrado")
+	ErrArquivoMuitoGrande       = errors.New("arquivo excede o tamanho máximo permitido")
+	ErrTipoArquivoNaoPermitido  = errors.New("tipo de arquivo não permitido para este documento")
+	ErrResponsavelNaoEncontrado = errors.New("responsável não encontrado para este cliente")
+	ErrLinkInvalido             = errors.New("link de download inválido ou expirado")
+)
+
+// Tipos de conteúdo aceitos por tipo de documento, detectados pelo conteúdo e não pela extensão
+var tiposConteudoPermitidos = map[models.TipoDocumento][]string{
+	models.DocumentoFoto:                   {"image/jpeg", "image/png", "image/webp"},
+	models.DocumentoTermoResponsabilidade:  {"application/pdf", "image/jpeg", "image/png"},
+	models.DocumentoAutorizacaoResponsavel: {"application/pdf", "image/jpeg", "image/png"},
+	models.DocumentoAtestado:               {"application/pdf", "image/jpeg", "image/png"},
+	models.DocumentoOutro:                  {"application/pdf", "image/jpeg", "image/png", "image/webp"},
+}
+
+// TamanhoMaximoDocumento retorna o tamanho máximo de um upload em bytes,
+// configurável em DOCUMENTO_TAMANHO_MAX_MB (padrão: 10)
+func TamanhoMaximoDocumento() int64 {
+	if valor := os.Getenv("DOCUMENTO_TAMANHO_MAX_MB"); valor != "" {
+		if mb, err := strconv.Atoi(valor); err == nil && mb > 0 {
+			return int64(mb) << 20
+		}
+	}
+	return 10 << 20
+}
+
+// ValidadeLinkDocumento retorna por quanto tempo um link de download vale,
+// configurável em DOCUMENTO_LINK_VALIDADE_MIN (padrão: 15 minutos)
+func ValidadeLinkDocumento() time.Duration {
+	if valor := os.Getenv("DOCUMENTO_LINK_VALIDADE_MIN"); valor != "" {
+		if minutos, err := strconv.Atoi(valor); err == nil && minutos > 0 {
+			return time.Duration(minutos) * time.Minute
+		}
+	}
+	return 15 * time.Minute
+}
+
+// SalvarDocumento verifica o tipo real do arquivo, grava o conteúdo no
+// armazenamento calculando o SHA-256 e registra os metadados
+func SalvarDocumento(documento *models.Documento, conteudo io.Reader, tamanho int64, autor string) error {
+	var cliente models.Cliente
+	if err := config.DB.Select("id").First(&cliente, "id = ?", documento.ClienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrClienteNaoEncontrado
+		}
+		return err
+	}
+	if documento.GuardianID != nil {
+		var guardian models.Guardian
+		if err := config.DB.Select("id").First(&guardian, "id = ? AND cliente_id = ?", *documento.GuardianID, documento.ClienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrResponsavelNaoEncontrado
+			}
+			return err
+		}
+	}
+
+	documento.EnviadoPor = autor
+	if err := armazenarConteudo(storage.Padrao(), documento, conteudo, tamanho); err != nil {
+		return err
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(documento).Error; err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "documento", documento.ID, &documento.ClienteID, "documento.envio", autor,
+			map[string]interface{}{"tipo": documento.Tipo, "nome_arquivo": documento.NomeArquivo, "sha256": documento.SHA256})
+	})
+	if err != nil {
+		// Sem os metadados o arquivo ficaria órfão no armazenamento
+		if errRemocao := storage.Padrao().Remover(documento.Chave); errRemocao != nil {
+			log.Printf("Erro ao remover arquivo órfão %s: %v", documento.Chave, errRemocao)
+		}
+		return err
+	}
+	return nil
+}
+
+// Confere o tamanho e o tipo real do conteúdo e o grava no armazenamento,
+// preenchendo a chave, o tipo, o tamanho e o SHA-256 do documento
+func armazenarConteudo(armazenamento storage.Armazenamento, documento *models.Documento, conteudo io.Reader, tamanho int64) error {
+	if tamanho > TamanhoMaximoDocumento() {
+		return ErrArquivoMuitoGrande
+	}
+
+	// Os primeiros 512 bytes bastam para identificar o tipo do conteúdo
+	cabecalho := make([]byte, 512)
+	n, err := io.ReadFull(conteudo, cabecalho)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	cabecalho = cabecalho[:n]
+
+	contentType := http.DetectContentType(cabecalho)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if !contentTypePermitido(documento.Tipo, contentType) {
+		return ErrTipoArquivoNaoPermitido
+	}
+
+	id, err := gonanoid.New()
+	if err != nil {
+		return err
+	}
+	documento.ID = id
+	documento.ContentType = contentType
+	documento.Tamanho = tamanho
+	documento.NomeArquivo = nomeArquivoSeguro(documento.NomeArquivo)
+	documento.Chave = fmt.Sprintf("clientes/%s/%s", documento.ClienteID, id)
+
+	hash := sha256.New()
+	leitor := io.TeeReader(io.MultiReader(bytes.NewReader(cabecalho), conteudo), hash)
+	if err := armazenamento.Salvar(documento.Chave, leitor, tamanho, contentType); err != nil {
+		return err
+	}
+	documento.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// BuscarDocumento retorna os metadados de um documento do cliente
+func BuscarDocumento(clienteID, documentoID string) (*models.Documento, error) {
+	var documento models.Documento
+	if err := config.DB.First(&documento, "id = ? AND cliente_id = ?", documentoID, clienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentoNaoEncontrado
+		}
+		return nil, err
+	}
+	return &documento, nil
+}
+
+// AbrirDocumento retorna o conteúdo do documento no armazenamento
+func AbrirDocumento(documento *models.Documento) (io.ReadCloser, error) {
+	conteudo, err := storage.Padrao().Abrir(documento.Chave)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		return nil, ErrDocumentoNaoEncontrado
+	}
+	return conteudo, err
+}
+
+// RemoverDocumento apaga os metadados e, depois de confirmada a exclusão, o arquivo
+func RemoverDocumento(documento *models.Documento, autor string) error {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(documento).Error; err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "documento", documento.ID, &documento.ClienteID, "documento.exclusao", autor,
+			map[string]interface{}{"tipo": documento.Tipo, "nome_arquivo": documento.NomeArquivo})
+	})
+	if err != nil {
+		return err
+	}
+
+	removerArquivos([]string{documento.Chave})
+	return nil
+}
+
+// LinkDownload gera o caminho de download assinado do documento, válido até expiraEm
+func LinkDownload(documento *models.Documento, expiraEm time.Time) string {
+	expira := strconv.FormatInt(expiraEm.Unix(), 10)
+	valores := url.Values{
+		"expira":     {expira},
+		"assinatura": {utils.AssinarURL(documento.ID + ":" + expira)},
+	}
+	return fmt.Sprintf("/documentos/%s/download?%s", documento.ID, valores.Encode())
+}
+
+// ValidarLinkDownload confere a assinatura e a validade do link e retorna o documento
+func ValidarLinkDownload(documentoID, expira, assinatura string) (*models.Documento, error) {
+	expiraEm, err := strconv.ParseInt(expira, 10, 64)
+	if err != nil || time.Now().Unix() > expiraEm {
+		return nil, ErrLinkInvalido
+	}
+	if !utils.VerificarAssinaturaURL(documentoID+":"+expira, assinatura) {
+		return nil, ErrLinkInvalido
+	}
+
+	var documento models.Documento
+	if err := config.DB.First(&documento, "id = ?", documentoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentoNaoEncontrado
+		}
+		return nil, err
+	}
+	return &documento, nil
+}
+
+// Chaves dos arquivos do cliente, para removê-los após apagar os metadados
+func chavesDocumentos(tx *gorm.DB, clienteID string) ([]string, error) {
+	var chaves []string
+	err := tx.Model(&models.Documento{}).Where("cliente_id = ?", clienteID).Pluck("chave", &chaves).Error
+	return chaves, err
+}
+
+// Remove arquivos do armazenamento; falhas apenas são registradas no log,
+// pois os metadados já foram apagados
+func removerArquivos(chaves []string) {
+	for _, chave := range chaves {
+		if err := storage.Padrao().Remover(chave); err != nil {
+			log.Printf("Erro ao remover arquivo %s do armazenamento: %v", chave, err)
+		}
+	}
+}
+
+func contentTypePermitido(tipo models.TipoDocumento, contentType string) bool {
+	for _, permitido := range tiposConteudoPermitidos[tipo] {
+		if permitido == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove diretórios e caracteres que quebrariam o Content-Disposition
+func nomeArquivoSeguro(nome string) string {
+	nome = filepath.Base(strings.ReplaceAll(nome, "\\", "/"))
+	nome = strings.Map(func(r rune) rune {
+		if r == '"' || r < 32 {
+			return -1
+		}
+		return r
+	}, nome)
+	if nome == "" || nome == "." || nome == "/" {
+		return "documento"
+	}
+	return nome
+}
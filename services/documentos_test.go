@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go-api/models"
+	"go-api/storage"
+	"go-api/utils"
+)
+
+var (
+	cabecalhoPNG  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	cabecalhoPDF  = []byte("%PDF-1.4\n%âãÏÓ\n")
+	cabecalhoJPEG = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+)
+
+func TestArmazenarConteudoTipoReal(t *testing.T) {
+	casos := []struct {
+		nome        string
+		tipo        models.TipoDocumento
+		conteudo    []byte
+		contentType string
+		erro        error
+	}{
+		{"foto png", models.DocumentoFoto, cabecalhoPNG, "image/png", nil},
+		{"foto jpeg", models.DocumentoFoto, cabecalhoJPEG, "image/jpeg", nil},
+		{"atestado pdf", models.DocumentoAtestado, cabecalhoPDF, "application/pdf", nil},
+		{"foto pdf", models.DocumentoFoto, cabecalhoPDF, "", ErrTipoArquivoNaoPermitido},
+		{"texto", models.DocumentoOutro, []byte("apenas texto"), "", ErrTipoArquivoNaoPermitido},
+		{"html", models.DocumentoOutro, []byte("<html><script>alert(1)</script>"), "", ErrTipoArquivoNaoPermitido},
+		{"vazio", models.DocumentoOutro, nil, "", ErrTipoArquivoNaoPermitido},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			// A extensão do nome não influencia a detecção
+			documento := &models.Documento{ClienteID: "cliente", Tipo: caso.tipo, NomeArquivo: "arquivo.pdf"}
+			err := armazenarConteudo(storage.NovoLocal(t.TempDir()), documento, bytes.NewReader(caso.conteudo), int64(len(caso.conteudo)))
+			if !errors.Is(err, caso.erro) {
+				t.Fatalf("erro = %v, esperado %v", err, caso.erro)
+			}
+			if err == nil && documento.ContentType != caso.contentType {
+				t.Errorf("content type = %q, esperado %q", documento.ContentType, caso.contentType)
+			}
+		})
+	}
+}
+
+func TestArmazenarConteudoSHA256(t *testing.T) {
+	local := storage.NovoLocal(t.TempDir())
+
+	// Maior que o cabeçalho de 512 bytes lido para detectar o tipo
+	conteudo := append(append([]byte{}, cabecalhoPNG...), bytes.Repeat([]byte("0123456789"), 300)...)
+	documento := &models.Documento{ClienteID: "cliente", Tipo: models.DocumentoFoto, NomeArquivo: `..\\pasta/"foto".png`}
+	if err := armazenarConteudo(local, documento, bytes.NewReader(conteudo), int64(len(conteudo))); err != nil {
+		t.Fatalf("armazenarConteudo: %v", err)
+	}
+
+	esperado := sha256.Sum256(conteudo)
+	if documento.SHA256 != hex.EncodeToString(esperado[:]) {
+		t.Errorf("SHA256 = %s, esperado %x", documento.SHA256, esperado)
+	}
+	if documento.Tamanho != int64(len(conteudo)) {
+		t.Errorf("tamanho = %d, esperado %d", documento.Tamanho, len(conteudo))
+	}
+	if !strings.HasPrefix(documento.Chave, "clientes/cliente/") {
+		t.Errorf("chave %q fora do prefixo do cliente", documento.Chave)
+	}
+	if documento.NomeArquivo != "foto.png" {
+		t.Errorf("nome do arquivo = %q, esperado %q", documento.NomeArquivo, "foto.png")
+	}
+
+	arquivo, err := local.Abrir(documento.Chave)
+	if err != nil {
+		t.Fatalf("Abrir: %v", err)
+	}
+	defer arquivo.Close()
+	gravado, err := io.ReadAll(arquivo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gravado, conteudo) {
+		t.Error("conteúdo gravado difere do enviado")
+	}
+}
+
+func TestArmazenarConteudoTamanhoMaximo(t *testing.T) {
+	t.Setenv("DOCUMENTO_TAMANHO_MAX_MB", "1")
+	local := storage.NovoLocal(t.TempDir())
+
+	documento := &models.Documento{ClienteID: "cliente", Tipo: models.DocumentoFoto}
+	err := armazenarConteudo(local, documento, bytes.NewReader(cabecalhoPNG), 1<<20+1)
+	if !errors.Is(err, ErrArquivoMuitoGrande) {
+		t.Fatalf("erro = %v, esperado ErrArquivoMuitoGrande", err)
+	}
+	if documento.Chave != "" {
+		t.Error("arquivo acima do limite não deveria ser gravado")
+	}
+
+	if err := armazenarConteudo(local, documento, bytes.NewReader(cabecalhoPNG), 1<<20); err != nil {
+		t.Errorf("arquivo no limite deveria ser aceito: %v", err)
+	}
+}
+
+func TestTamanhoMaximoDocumento(t *testing.T) {
+	casos := map[string]int64{"": 10 << 20, "25": 25 << 20, "0": 10 << 20, "-3": 10 << 20, "abc": 10 << 20}
+	for valor, esperado := range casos {
+		t.Setenv("DOCUMENTO_TAMANHO_MAX_MB", valor)
+		if obtido := TamanhoMaximoDocumento(); obtido != esperado {
+			t.Errorf("DOCUMENTO_TAMANHO_MAX_MB=%q: %d, esperado %d", valor, obtido, esperado)
+		}
+	}
+}
+
+func TestLinkDownload(t *testing.T) {
+	t.Setenv("URL_SIGNING_KEY", "chave-de-teste")
+	documento := &models.Documento{ID: "doc1"}
+
+	link, err := url.Parse(LinkDownload(documento, time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Path != "/documentos/doc1/download" {
+		t.Errorf("caminho = %q", link.Path)
+	}
+	expira, assinatura := link.Query().Get("expira"), link.Query().Get("assinatura")
+	if !utils.VerificarAssinaturaURL("doc1:"+expira, assinatura) {
+		t.Error("assinatura do link não confere")
+	}
+
+	vencido, _ := url.Parse(LinkDownload(documento, time.Now().Add(-time.Second)))
+	adulterada := []byte(assinatura)
+	adulterada[0] ^= 1
+
+	// Os links válidos seguem para o banco; aqui só os recusados antes dele
+	casos := []struct {
+		nome, documentoID, expira, assinatura string
+	}{
+		{"expirado", "doc1", vencido.Query().Get("expira"), vencido.Query().Get("assinatura")},
+		{"assinatura adulterada", "doc1", expira, string(adulterada)},
+		{"outro documento", "doc2", expira, assinatura},
+		{"validade estendida", "doc1", expira + "0", assinatura},
+		{"validade inválida", "doc1", "amanhã", assinatura},
+		{"sem assinatura", "doc1", expira, ""},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			if _, err := ValidarLinkDownload(caso.documentoID, caso.expira, caso.assinatura); !errors.Is(err, ErrLinkInvalido) {
+				t.Errorf("erro = %v, esperado ErrLinkInvalido", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go-api/db"
+	"go-api/models"
+	"go-api/utils"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Similaridade mínima entre nomes para considerar duplicidade quando a data de nascimento coincide
+const similaridadeMinimaNome = 0.85
+
+var (
+	ErrMesclagemMesmoCliente = errors.New("sobrevivente e duplicado devem ser clientes diferentes")
+	ErrMesclagemAnonimizado  = errors.New("clientes anonimizados não podem ser mesclados")
+	ErrMesclagemArquivado    = errors.New("o sobrevivente da mesclagem não pode estar arquivado")
+)
+
+// Tabelas com referência a cliente_id que são transferidas na mesclagem
+var tabelasMesclagem = []interface{}{
+	&models.Sale{},
+	&models.Subscription{},
+	&models.Guardian{},
+	&models.PeriodoInadimplencia{},
+	&models.RegistroAuditoria{},
+	&models.Nota{},
+	&models.ContatoEmergencia{},
+	&models.Documento{},
+	&models.Presenca{},
+	&models.Graduacao{},
+	&models.CandidatoExame{},
+	&models.Matricula{},
+	&models.Lead{},
+	&models.InscricaoEvento{},
+	&models.Reserva{},
+}
+
+// ParDuplicado é um par de clientes que provavelmente são a mesma pessoa
+type ParDuplicado struct {
+	Clientes     [2]models.Cliente `json:"clientes"`
+	Criterios    []string          `json:"criterios"`
+	Similaridade float64           `json:"similaridade_nome"`
+}
+
+// BuscarDuplicados compara os clientes ativos por CPF, e-mail e telefone
+// normalizados, e por nome semelhante com a mesma data de nascimento
+func BuscarDuplicados() ([]ParDuplicado, error) {
+	var clientes []models.Cliente
+	if err := config.DB.Scopes(ApenasAtivos).Order("created_at").Find(&clientes).Error; err != nil {
+		return nil, err
+	}
+
+	pares := map[[2]int]*ParDuplicado{}
+	registrar := func(i, j int, criterio string) {
+		if i > j {
+			i, j = j, i
+		}
+		chave := [2]int{i, j}
+		par, ok := pares[chave]
+		if !ok {
+			par = &ParDuplicado{Clientes: [2]models.Cliente{clientes[i], clientes[j]}}
+			pares[chave] = par
+		}
+		for _, existente := range par.Criterios {
+			if existente == criterio {
+				return
+			}
+		}
+		par.Criterios = append(par.Criterios, criterio)
+	}
+
+	// Agrupa os clientes por chave normalizada e registra todos os pares do grupo
+	agrupar := func(criterio string, chave func(c models.Cliente) string) {
+		grupos := map[string][]int{}
+		for i, cliente := range clientes {
+			if k := chave(cliente); k != "" {
+				grupos[k] = append(grupos[k], i)
+			}
+		}
+		for _, indices := range grupos {
+			for a := 0; a < len(indices); a++ {
+				for b := a + 1; b < len(indices); b++ {
+					registrar(indices[a], indices[b], criterio)
+				}
+			}
+		}
+	}
+
+	agrupar("cpf", func(c models.Cliente) string { return utils.NormalizarCPF(c.CPF) })
+	agrupar("email", func(c models.Cliente) string { return strings.ToLower(strings.TrimSpace(c.Email)) })
+	agrupar("telefone", func(c models.Cliente) string { return normalizarTelefone(c.Telefone) })
+
+	// Nomes parecidos só são comparados entre clientes com a mesma data de nascimento
+	porNascimento := map[string][]int{}
+	for i, cliente := range clientes {
+		data := cliente.DataNascimento.Format("2006-01-02")
+		porNascimento[data] = append(porNascimento[data], i)
+	}
+	for _, indices := range porNascimento {
+		for a := 0; a < len(indices); a++ {
+			for b := a + 1; b < len(indices); b++ {
+				i, j := indices[a], indices[b]
+				if similaridadeNomes(clientes[i].Nome, clientes[j].Nome) >= similaridadeMinimaNome {
+					registrar(i, j, "nome_e_nascimento")
+				}
+			}
+		}
+	}
+
+	resultado := make([]ParDuplicado, 0, len(pares))
+	for _, par := range pares {
+		par.Similaridade = similaridadeNomes(par.Clientes[0].Nome, par.Clientes[1].Nome)
+		resultado = append(resultado, *par)
+	}
+
+	// Pares com mais critérios em comum aparecem primeiro
+	sort.Slice(resultado, func(a, b int) bool {
+		if len(resultado[a].Criterios) != len(resultado[b].Criterios) {
+			return len(resultado[a].Criterios) > len(resultado[b].Criterios)
+		}
+		return resultado[a].Similaridade > resultado[b].Similaridade
+	})
+
+	return resultado, nil
+}
+
+// MesclarClientes transfere vendas, assinaturas, responsáveis e históricos do
+// duplicado para o sobrevivente e arquiva o duplicado, tudo em uma transação
+func MesclarClientes(sobreviventeID, duplicadoID, autor string) error {
+	if sobreviventeID == duplicadoID {
+		return ErrMesclagemMesmoCliente
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var clientes []models.Cliente
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []string{sobreviventeID, duplicadoID}).Find(&clientes).Error; err != nil {
+			return err
+		}
+		if len(clientes) != 2 {
+			return ErrClienteNaoEncontrado
+		}
+		for _, cliente := range clientes {
+			if cliente.AnonimizadoEm != nil {
+				return ErrMesclagemAnonimizado
+			}
+			if cliente.ID == sobreviventeID && cliente.ArquivadoEm != nil {
+				return ErrMesclagemArquivado
+			}
+		}
+
+		// Check-ins na mesma aula e data, inscrições no mesmo exame ou evento e matrículas
+		// vigentes na mesma aula violariam a unicidade
+		if err := tx.Where(`cliente_id = ? AND EXISTS (SELECT 1 FROM presencas p
+			WHERE p.cliente_id = ? AND p.aula_id = presencas.aula_id AND p.data = presencas.data)`, duplicadoID, sobreviventeID).
+			Delete(&models.Presenca{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where(`cliente_id = ? AND exame_id IN (SELECT exame_id FROM candidato_exames WHERE cliente_id = ?)`,
+			duplicadoID, sobreviventeID).Delete(&models.CandidatoExame{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Matricula{}).Where(`cliente_id = ? AND status <> ? AND aula_id IN
+			(SELECT aula_id FROM matriculas WHERE cliente_id = ? AND status <> ?)`,
+			duplicadoID, models.MatriculaCancelada, sobreviventeID, models.MatriculaCancelada).
+			Updates(map[string]interface{}{
+				"status":              models.MatriculaCancelada,
+				"cancelada_em":        gorm.Expr("CURRENT_TIMESTAMP"),
+				"motivo_cancelamento": "mesclagem de cadastros",
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.InscricaoEvento{}).Where(`cliente_id = ? AND status <> ? AND evento_id IN
+			(SELECT evento_id FROM inscricao_eventos WHERE cliente_id = ? AND status <> ?)`,
+			duplicadoID, models.InscricaoCancelada, sobreviventeID, models.InscricaoCancelada).
+			Update("status", models.InscricaoCancelada).Error; err != nil {
+			return err
+		}
+
+		for _, modelo := range tabelasMesclagem {
+			if err := tx.Model(modelo).Where("cliente_id = ?", duplicadoID).
+				Update("cliente_id", sobreviventeID).Error; err != nil {
+				return err
+			}
+		}
+
+		// As tags do duplicado passam a valer também para o sobrevivente
+		if err := tx.Exec(`INSERT INTO cliente_tags (cliente_id, tag_id)
+			SELECT ?, tag_id FROM cliente_tags WHERE cliente_id = ?
+			ON CONFLICT DO NOTHING`, sobreviventeID, duplicadoID).Error; err != nil {
+			return err
+		}
+
+		// Os dados legados dos pais só podem ser transferidos se o sobrevivente não tiver
+		var paisSobrevivente int64
+		if err := tx.Model(&models.Pais{}).Where("cliente_id = ?", sobreviventeID).Count(&paisSobrevivente).Error; err != nil {
+			return err
+		}
+		if paisSobrevivente == 0 {
+			if err := tx.Model(&models.Pais{}).Where("cliente_id = ?", duplicadoID).
+				Update("cliente_id", sobreviventeID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Cliente{}).Where("id = ?", sobreviventeID).
+				Update("pais_id", tx.Model(&models.Pais{}).Select("id").Where("cliente_id = ?", sobreviventeID)).Error; err != nil {
+				return err
+			}
+		}
+
+		// O sobrevivente herda a família do duplicado se não tiver uma
+		if err := tx.Model(&models.Cliente{}).Where("id = ? AND familia_id IS NULL", sobreviventeID).
+			Update("familia_id", gorm.Expr("(SELECT familia_id FROM clientes WHERE id = ?)", duplicadoID)).Error; err != nil {
+			return err
+		}
+		// A responsabilidade financeira só passa ao sobrevivente se ele for da mesma família
+		if err := tx.Model(&models.Familia{}).
+			Where("responsavel_financeiro_id = ? AND id = (SELECT familia_id FROM clientes WHERE id = ?)", duplicadoID, sobreviventeID).
+			Update("responsavel_financeiro_id", sobreviventeID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Familia{}).Where("responsavel_financeiro_id = ?", duplicadoID).
+			Update("responsavel_financeiro_id", nil).Error; err != nil {
+			return err
+		}
+
+		// O perfil de saúde é transferido apenas se o sobrevivente ainda não tiver um
+		var saudeSobrevivente int64
+		if err := tx.Model(&models.PerfilSaude{}).Where("cliente_id = ?", sobreviventeID).Count(&saudeSobrevivente).Error; err != nil {
+			return err
+		}
+		if saudeSobrevivente == 0 {
+			if err := tx.Model(&models.PerfilSaude{}).Where("cliente_id = ?", duplicadoID).
+				Update("cliente_id", sobreviventeID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.Cliente{}).Where("id = ?", duplicadoID).Updates(map[string]interface{}{
+			"arquivado_em":        gorm.Expr("CURRENT_TIMESTAMP"),
+			"motivo_arquivamento": models.MotivoDuplicado,
+			"pais_id":             nil,
+			"familia_id":          nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		dados := map[string]interface{}{"sobrevivente_id": sobreviventeID, "duplicado_id": duplicadoID}
+		if err := RegistrarAuditoria(tx, "cliente", sobreviventeID, &sobreviventeID, "cliente.mesclagem", autor, dados); err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "cliente", duplicadoID, &duplicadoID, "cliente.mesclado", autor, dados)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Vendas e assinaturas transferidas podem mudar a situação do sobrevivente
+	if err := RecalcularInadimplencia(duplicadoID); err != nil {
+		return err
+	}
+	return RecalcularInadimplencia(sobreviventeID)
+}
+
+// Mantém os dígitos do telefone, desconsiderando o código do país
+func normalizarTelefone(telefone string) string {
+	digitos := utils.NormalizarCPF(telefone)
+	if len(digitos) > 11 && strings.HasPrefix(digitos, "55") {
+		digitos = digitos[2:]
+	}
+	if len(digitos) < 8 {
+		return ""
+	}
+	return digitos
+}
+
+// Remove acentos, pontuação e espaços repetidos do nome
+func normalizarNome(nome string) string {
+	semAcento, _, _ := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), nome)
+	campos := strings.FieldsFunc(strings.ToLower(semAcento), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return strings.Join(campos, " ")
+}
+
+// Similaridade entre 0 e 1 baseada na distância de Levenshtein dos nomes normalizados
+func similaridadeNomes(a, b string) float64 {
+	ra, rb := []rune(normalizarNome(a)), []rune(normalizarNome(b))
+	maior := len(ra)
+	if len(rb) > maior {
+		maior = len(rb)
+	}
+	if maior == 0 {
+		return 0
+	}
+
+	anterior := make([]int, len(rb)+1)
+	atual := make([]int, len(rb)+1)
+	for j := range anterior {
+		anterior[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		atual[0] = i
+		for j := 1; j <= len(rb); j++ {
+			custo := 1
+			if ra[i-1] == rb[j-1] {
+				custo = 0
+			}
+			atual[j] = min(anterior[j]+1, atual[j-1]+1, anterior[j-1]+custo)
+		}
+		anterior, atual = atual, anterior
+	}
+
+	return 1 - float64(anterior[len(rb)])/float64(maior)
+}
@@ -0,0 +1,753 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrEventoNaoEncontrado    = errors.New("evento não encontrado")
+	ErrPeriodoEventoInvalido  = errors.New("o fim do evento deve ser posterior ao início")
+	ErrEventoFechado          = errors.New("o evento não está com inscrições abertas")
+	ErrEventoLotado           = errors.New("o evento atingiu a capacidade")
+	ErrLoteIndisponivel       = errors.New("nenhum lote de inscrição disponível")
+	ErrLoteEmUso              = errors.New("o lote já foi usado em inscrições")
+	ErrCategoriaNaoEncontrada = errors.New("categoria não encontrada")
+	ErrCategoriaEmUso         = errors.New("a categoria já tem inscrições")
+	ErrCategoriaObrigatoria   = errors.New("informe a categoria do participante")
+	ErrCategoriaIncompativel  = errors.New("o participante não se enquadra na categoria")
+	ErrFaixaCategoriaInvalida = errors.New("as faixas da categoria devem ser da modalidade do evento")
+	ErrParticipanteIncompleto = errors.New("informe nome, e-mail ou telefone e data de nascimento do participante externo")
+	ErrInscricaoDuplicada     = errors.New("o cliente já está inscrito neste evento")
+	ErrInscricaoNaoEncontrada = errors.New("inscrição não encontrada")
+	ErrInscricaoNaoPaga       = errors.New("a inscrição ainda não foi paga")
+	ErrCheckinEventoDuplicado = errors.New("check-in já registrado para esta inscrição")
+)
+
+// SolicitacaoInscricao identifica um cliente ou traz os dados de um participante externo
+type SolicitacaoInscricao struct {
+	ClienteID      string               `json:"cliente_id"`
+	Nome           string               `json:"nome"`
+	Email          string               `json:"email" validate:"omitempty,email"`
+	Telefone       string               `json:"telefone"`
+	DataNascimento string               `json:"data_nascimento" validate:"omitempty,datetime=2006-01-02"`
+	Genero         string               `json:"genero" validate:"omitempty,oneof=Masculino Feminino Outro"`
+	Peso           *float64             `json:"peso" validate:"omitempty,gt=0"`
+	FaixaID        string               `json:"faixa_id"` // Apenas para externos; a do cliente vem da graduação
+	Academia       string               `json:"academia"`
+	CategoriaID    string               `json:"categoria_id"`
+	FormaPagamento models.PaymentMethod `json:"forma_pagamento" validate:"omitempty,oneof=boleto pix debit_card credit_card"`
+	Pago           bool                 `json:"pago"`
+}
+
+// ParticipanteChave é um atleta na chave de lutas
+type ParticipanteChave struct {
+	InscricaoID string `json:"inscricao_id"`
+	Nome        string `json:"nome"`
+	Academia    string `json:"academia"`
+	Faixa       string `json:"faixa,omitempty"`
+}
+
+// LutaChave é um confronto da primeira rodada. Sem oponente, o atleta avança direto.
+type LutaChave struct {
+	Numero  int                `json:"numero"`
+	AtletaA *ParticipanteChave `json:"atleta_a"`
+	AtletaB *ParticipanteChave `json:"atleta_b"`
+}
+
+// ChaveCategoria é a chave de eliminação simples de uma categoria
+type ChaveCategoria struct {
+	Categoria     models.CategoriaEvento `json:"categoria"`
+	Participantes int                    `json:"participantes"`
+	Lutas         []LutaChave            `json:"lutas"`
+}
+
+// CriarEvento grava o evento com seus lotes e categorias e cria o produto
+// pelo qual as inscrições são vendidas
+func CriarEvento(evento *models.Evento, autor string) error {
+	if !evento.Fim.After(evento.Inicio) {
+		return ErrPeriodoEventoInvalido
+	}
+	evento.ID = "" // Ignorar o ID enviado e gerar um novo
+	evento.Status = models.EventoAberto
+	for i := range evento.Lotes {
+		evento.Lotes[i].ID = ""
+	}
+	for i := range evento.Categorias {
+		evento.Categorias[i].ID = ""
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := validarModalidadeEvento(tx, evento.ModalidadeID); err != nil {
+			return err
+		}
+		for i := range evento.Categorias {
+			if err := validarCategoriaEvento(tx, evento, &evento.Categorias[i]); err != nil {
+				return err
+			}
+		}
+
+		produto := models.Produto{
+			Nome:      "Inscrição - " + evento.Nome,
+			Descricao: evento.Descricao,
+			Preco:     menorPrecoLotes(evento.Lotes),
+			Tipo:      models.Inscricao,
+			Status:    true,
+		}
+		if produto.Descricao == "" {
+			produto.Descricao = evento.Nome
+		}
+		if err := tx.Create(&produto).Error; err != nil {
+			return err
+		}
+		evento.ProdutoID = produto.ID
+
+		if err := tx.Omit("Modalidade").Create(evento).Error; err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "evento", evento.ID, nil, "evento.criacao", autor, nil)
+	})
+}
+
+// AtualizarEvento grava os dados gerais do evento; lotes e categorias têm operações próprias
+func AtualizarEvento(evento *models.Evento, autor string) error {
+	if !evento.Fim.After(evento.Inicio) {
+		return ErrPeriodoEventoInvalido
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		existente, err := bloquearEvento(tx, evento.ID)
+		if err != nil {
+			return err
+		}
+		if err := validarModalidadeEvento(tx, evento.ModalidadeID); err != nil {
+			return err
+		}
+
+		evento.ProdutoID = existente.ProdutoID
+		evento.Status = existente.Status
+		evento.CreatedAt = existente.CreatedAt
+		evento.Lotes = nil
+		evento.Categorias = nil
+		if err := tx.Omit(clause.Associations).Save(evento).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Produto{}).Where("id = ?", evento.ProdutoID).
+			Update("nome", "Inscrição - "+evento.Nome).Error; err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "evento", evento.ID, nil, "evento.atualizacao", autor, nil)
+	})
+}
+
+// AlterarStatusEvento encerra ou reabre as inscrições. O cancelamento cancela
+// as inscrições e as vendas ainda não pagas; as pagas ficam para reembolso.
+func AlterarStatusEvento(eventoID string, status models.StatusEvento, autor string) ([]string, error) {
+	clientes := []string{}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		evento, err := bloquearEvento(tx, eventoID)
+		if err != nil {
+			return err
+		}
+		if evento.Status == models.EventoCancelado {
+			return ErrEventoFechado
+		}
+
+		if err := tx.Model(evento).Update("status", status).Error; err != nil {
+			return err
+		}
+
+		if status == models.EventoCancelado {
+			var inscricoes []models.InscricaoEvento
+			if err := tx.Where("evento_id = ? AND status <> ?", eventoID, models.InscricaoCancelada).
+				Find(&inscricoes).Error; err != nil {
+				return err
+			}
+			for i := range inscricoes {
+				if err := cancelarInscricao(tx, &inscricoes[i]); err != nil {
+					return err
+				}
+				if inscricoes[i].ClienteID != nil {
+					clientes = append(clientes, *inscricoes[i].ClienteID)
+				}
+			}
+		}
+
+		return RegistrarAuditoria(tx, "evento", eventoID, nil, "evento.status", autor,
+			map[string]interface{}{"de": evento.Status, "para": status})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clientes, nil
+}
+
+// AdicionarLote inclui uma faixa de preço no evento
+func AdicionarLote(eventoID string, lote *models.LoteEvento) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := bloquearEvento(tx, eventoID); err != nil {
+			return err
+		}
+		lote.ID = ""
+		lote.EventoID = eventoID
+		if err := tx.Create(lote).Error; err != nil {
+			return err
+		}
+		return atualizarPrecoProdutoEvento(tx, eventoID)
+	})
+}
+
+// RemoverLote exclui um lote que ainda não foi usado em inscrições
+func RemoverLote(eventoID, loteID string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := bloquearEvento(tx, eventoID); err != nil {
+			return err
+		}
+
+		var usado int64
+		if err := tx.Model(&models.InscricaoEvento{}).Where("lote_id = ?", loteID).Count(&usado).Error; err != nil {
+			return err
+		}
+		if usado > 0 {
+			return ErrLoteEmUso
+		}
+
+		result := tx.Where("id = ? AND evento_id = ?", loteID, eventoID).Delete(&models.LoteEvento{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrLoteIndisponivel
+		}
+		return atualizarPrecoProdutoEvento(tx, eventoID)
+	})
+}
+
+// AdicionarCategoria inclui uma categoria no evento
+func AdicionarCategoria(eventoID string, categoria *models.CategoriaEvento) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		evento, err := bloquearEvento(tx, eventoID)
+		if err != nil {
+			return err
+		}
+		categoria.ID = ""
+		categoria.EventoID = eventoID
+		if err := validarCategoriaEvento(tx, evento, categoria); err != nil {
+			return err
+		}
+		return tx.Omit("FaixaMinima", "FaixaMaxima").Create(categoria).Error
+	})
+}
+
+// RemoverCategoria exclui uma categoria sem inscrições
+func RemoverCategoria(eventoID, categoriaID string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := bloquearEvento(tx, eventoID); err != nil {
+			return err
+		}
+
+		var usada int64
+		if err := tx.Model(&models.InscricaoEvento{}).Where("categoria_id = ?", categoriaID).Count(&usada).Error; err != nil {
+			return err
+		}
+		if usada > 0 {
+			return ErrCategoriaEmUso
+		}
+
+		result := tx.Where("id = ? AND evento_id = ?", categoriaID, eventoID).Delete(&models.CategoriaEvento{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCategoriaNaoEncontrada
+		}
+		return nil
+	})
+}
+
+// LoteVigente retorna o lote mais barato válido na data. Alunos também podem
+// usar os lotes exclusivos.
+func LoteVigente(tx *gorm.DB, eventoID string, aluno bool, ref time.Time) (*models.LoteEvento, error) {
+	query := tx.Where("evento_id = ? AND (valido_ate IS NULL OR valido_ate >= ?)", eventoID, ref)
+	if !aluno {
+		query = query.Where("apenas_alunos = ?", false)
+	}
+
+	var lote models.LoteEvento
+	if err := query.Order("preco, valido_ate").First(&lote).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLoteIndisponivel
+		}
+		return nil, err
+	}
+	return &lote, nil
+}
+
+// InscreverParticipante inscreve um cliente ou participante externo, confere a
+// categoria e gera a venda da inscrição pelo lote vigente. Eventos sem lotes
+// e lotes gratuitos não geram venda.
+func InscreverParticipante(eventoID string, solicitacao SolicitacaoInscricao, autor string) (*models.InscricaoEvento, error) {
+	inscricao := &models.InscricaoEvento{
+		EventoID:    eventoID,
+		Status:      models.InscricaoConfirmada,
+		Peso:        solicitacao.Peso,
+		Academia:    strings.TrimSpace(solicitacao.Academia),
+		InscritoPor: autor,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// O bloqueio do evento serializa as inscrições na contagem de vagas
+		evento, err := bloquearEvento(tx, eventoID)
+		if err != nil {
+			return err
+		}
+		agora := time.Now()
+		prazo := evento.Inicio
+		if evento.InscricoesAte != nil {
+			prazo = *evento.InscricoesAte
+		}
+		if evento.Status != models.EventoAberto || agora.After(prazo) {
+			return ErrEventoFechado
+		}
+
+		if evento.Capacidade > 0 {
+			var inscritos int64
+			if err := tx.Model(&models.InscricaoEvento{}).Where("evento_id = ? AND status <> ?", eventoID, models.InscricaoCancelada).
+				Count(&inscritos).Error; err != nil {
+				return err
+			}
+			if int(inscritos) >= evento.Capacidade {
+				return ErrEventoLotado
+			}
+		}
+
+		if solicitacao.ClienteID != "" {
+			if err := preencherInscricaoCliente(tx, evento, inscricao, solicitacao.ClienteID); err != nil {
+				return err
+			}
+		} else if err := preencherInscricaoExterno(inscricao, solicitacao); err != nil {
+			return err
+		}
+
+		if err := enquadrarCategoria(tx, evento, inscricao, solicitacao.CategoriaID); err != nil {
+			return err
+		}
+
+		var lotes int64
+		if err := tx.Model(&models.LoteEvento{}).Where("evento_id = ?", eventoID).Count(&lotes).Error; err != nil {
+			return err
+		}
+		if lotes > 0 {
+			lote, err := LoteVigente(tx, eventoID, inscricao.ClienteID != nil, agora)
+			if err != nil {
+				return err
+			}
+			inscricao.LoteID = &lote.ID
+			inscricao.Valor = lote.Preco
+		}
+		if inscricao.Valor > 0 {
+			formaPagamento := solicitacao.FormaPagamento
+			if formaPagamento == "" {
+				formaPagamento = models.Pix
+			}
+			venda := models.Sale{
+				ProdutoID:      evento.ProdutoID,
+				Valor:          inscricao.Valor,
+				Quantidade:     1,
+				ClienteID:      inscricao.ClienteID,
+				FormaPagamento: formaPagamento,
+				Pago:           solicitacao.Pago,
+				Vencimento:     &prazo,
+			}
+			if err := tx.Omit(clause.Associations).Create(&venda).Error; err != nil {
+				return err
+			}
+			inscricao.VendaID = &venda.ID
+			inscricao.Venda = &venda
+		}
+
+		if err := tx.Omit("Evento", "Categoria", "Cliente", "Faixa", "Venda").Create(inscricao).Error; err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "inscricao_evento", inscricao.ID, inscricao.ClienteID, "evento.inscricao", autor,
+			map[string]interface{}{"evento_id": eventoID, "valor": inscricao.Valor, "categoria_id": inscricao.CategoriaID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inscricao, nil
+}
+
+// CancelarInscricao cancela a inscrição e a venda, se ainda não paga
+func CancelarInscricao(eventoID, inscricaoID, autor string) (*models.InscricaoEvento, error) {
+	var inscricao models.InscricaoEvento
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&inscricao, "id = ? AND evento_id = ? AND status <> ?", inscricaoID, eventoID, models.InscricaoCancelada).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInscricaoNaoEncontrada
+			}
+			return err
+		}
+		if err := cancelarInscricao(tx, &inscricao); err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "inscricao_evento", inscricao.ID, inscricao.ClienteID, "evento.inscricao_cancelada", autor,
+			map[string]interface{}{"evento_id": eventoID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &inscricao, nil
+}
+
+// RegistrarCheckinEvento marca a chegada do participante. Inscrições não
+// pagas só entram com forcar.
+func RegistrarCheckinEvento(eventoID, inscricaoID string, forcar bool, autor string) (*models.InscricaoEvento, error) {
+	var inscricao models.InscricaoEvento
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Venda").
+			First(&inscricao, "id = ? AND evento_id = ? AND status = ?", inscricaoID, eventoID, models.InscricaoConfirmada).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInscricaoNaoEncontrada
+			}
+			return err
+		}
+		if inscricao.CheckinEm != nil {
+			return ErrCheckinEventoDuplicado
+		}
+		if !inscricao.Paga() && !forcar {
+			return ErrInscricaoNaoPaga
+		}
+
+		agora := time.Now()
+		if err := tx.Model(&inscricao).Update("checkin_em", agora).Error; err != nil {
+			return err
+		}
+		inscricao.CheckinEm = &agora
+
+		return RegistrarAuditoria(tx, "inscricao_evento", inscricao.ID, inscricao.ClienteID, "evento.checkin", autor,
+			map[string]interface{}{"evento_id": eventoID, "pago": inscricao.Paga()})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &inscricao, nil
+}
+
+// ListarInscricoes retorna as inscrições não canceladas em ordem de nome,
+// que também serve de lista de check-in na entrada do evento
+func ListarInscricoes(eventoID string) ([]models.InscricaoEvento, error) {
+	if _, err := buscarEvento(config.DB, eventoID); err != nil {
+		return nil, err
+	}
+
+	var inscricoes []models.InscricaoEvento
+	err := config.DB.Preload("Categoria").Preload("Faixa").Preload("Venda").
+		Where("evento_id = ? AND status <> ?", eventoID, models.InscricaoCancelada).
+		Order("nome").Find(&inscricoes).Error
+	for i := range inscricoes {
+		inscricoes[i].Pago = inscricoes[i].Paga()
+	}
+	return inscricoes, err
+}
+
+// ChavesEvento monta as chaves de eliminação simples de cada categoria com os
+// inscritos pagos (ou todos, com incluirPendentes). Atletas da mesma academia
+// são espalhados para não se enfrentarem na primeira rodada quando possível.
+func ChavesEvento(eventoID string, incluirPendentes bool) ([]ChaveCategoria, error) {
+	evento, err := buscarEvento(config.DB, eventoID)
+	if err != nil {
+		return nil, err
+	}
+
+	var categorias []models.CategoriaEvento
+	if err := config.DB.Where("evento_id = ?", eventoID).Order("nome").Find(&categorias).Error; err != nil {
+		return nil, err
+	}
+	inscricoes, err := ListarInscricoes(evento.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	porCategoria := map[string][]models.InscricaoEvento{}
+	for _, inscricao := range inscricoes {
+		if inscricao.CategoriaID == nil || (!incluirPendentes && !inscricao.Paga()) {
+			continue
+		}
+		porCategoria[*inscricao.CategoriaID] = append(porCategoria[*inscricao.CategoriaID], inscricao)
+	}
+
+	chaves := make([]ChaveCategoria, 0, len(categorias))
+	for _, categoria := range categorias {
+		atletas := distribuirPorAcademia(porCategoria[categoria.ID])
+		chave := ChaveCategoria{Categoria: categoria, Participantes: len(atletas), Lutas: []LutaChave{}}
+
+		// A chave tem o tamanho da próxima potência de 2; as posições vazias são folgas
+		tamanho := 1
+		for tamanho < len(atletas) {
+			tamanho *= 2
+		}
+		if len(atletas) > 1 {
+			for i := 0; i < tamanho/2; i++ {
+				luta := LutaChave{Numero: i + 1, AtletaA: participanteChave(atletas, i), AtletaB: participanteChave(atletas, tamanho-1-i)}
+				chave.Lutas = append(chave.Lutas, luta)
+			}
+		}
+		chaves = append(chaves, chave)
+	}
+	return chaves, nil
+}
+
+// Intercala os atletas das academias, começando pelas maiores equipes
+func distribuirPorAcademia(inscricoes []models.InscricaoEvento) []models.InscricaoEvento {
+	grupos := map[string][]models.InscricaoEvento{}
+	ordem := []string{}
+	for _, inscricao := range inscricoes {
+		academia := strings.ToLower(inscricao.Academia)
+		if _, ok := grupos[academia]; !ok {
+			ordem = append(ordem, academia)
+		}
+		grupos[academia] = append(grupos[academia], inscricao)
+	}
+	sort.SliceStable(ordem, func(a, b int) bool { return len(grupos[ordem[a]]) > len(grupos[ordem[b]]) })
+
+	resultado := make([]models.InscricaoEvento, 0, len(inscricoes))
+	for len(resultado) < len(inscricoes) {
+		for _, academia := range ordem {
+			if len(grupos[academia]) > 0 {
+				resultado = append(resultado, grupos[academia][0])
+				grupos[academia] = grupos[academia][1:]
+			}
+		}
+	}
+	return resultado
+}
+
+func participanteChave(atletas []models.InscricaoEvento, posicao int) *ParticipanteChave {
+	if posicao >= len(atletas) {
+		return nil
+	}
+	atleta := atletas[posicao]
+	participante := &ParticipanteChave{InscricaoID: atleta.ID, Nome: atleta.Nome, Academia: atleta.Academia}
+	if atleta.Faixa != nil {
+		participante.Faixa = atleta.Faixa.Nome
+	}
+	return participante
+}
+
+// Copia os dados do cliente para a inscrição e usa a graduação atual na modalidade do evento
+func preencherInscricaoCliente(tx *gorm.DB, evento *models.Evento, inscricao *models.InscricaoEvento, clienteID string) error {
+	var cliente models.Cliente
+	if err := tx.Scopes(ApenasAtivos).Where("anonimizado_em IS NULL").First(&cliente, "id = ?", clienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrClienteNaoEncontrado
+		}
+		return err
+	}
+
+	var existente int64
+	if err := tx.Model(&models.InscricaoEvento{}).
+		Where("evento_id = ? AND cliente_id = ? AND status <> ?", evento.ID, clienteID, models.InscricaoCancelada).
+		Count(&existente).Error; err != nil {
+		return err
+	}
+	if existente > 0 {
+		return ErrInscricaoDuplicada
+	}
+
+	inscricao.ClienteID = &cliente.ID
+	inscricao.Nome = cliente.Nome
+	inscricao.Email = cliente.Email
+	inscricao.Telefone = cliente.Telefone
+	inscricao.DataNascimento = &cliente.DataNascimento
+	inscricao.Genero = cliente.Genero
+	if inscricao.Academia == "" {
+		inscricao.Academia = NomeAcademia()
+	}
+
+	if evento.ModalidadeID != nil {
+		atual, err := GraduacaoAtual(tx, cliente.ID, *evento.ModalidadeID)
+		if err != nil {
+			return err
+		}
+		if atual != nil {
+			inscricao.FaixaID = &atual.FaixaID
+		}
+	}
+	return nil
+}
+
+func preencherInscricaoExterno(inscricao *models.InscricaoEvento, solicitacao SolicitacaoInscricao) error {
+	if strings.TrimSpace(solicitacao.Nome) == "" || (solicitacao.Email == "" && solicitacao.Telefone == "") || solicitacao.DataNascimento == "" {
+		return ErrParticipanteIncompleto
+	}
+	nascimento, err := time.ParseInLocation("2006-01-02", solicitacao.DataNascimento, time.Local)
+	if err != nil {
+		return ErrParticipanteIncompleto
+	}
+
+	inscricao.Nome = strings.TrimSpace(solicitacao.Nome)
+	inscricao.Email = solicitacao.Email
+	inscricao.Telefone = solicitacao.Telefone
+	inscricao.DataNascimento = &nascimento
+	inscricao.Genero = solicitacao.Genero
+	if solicitacao.FaixaID != "" {
+		inscricao.FaixaID = &solicitacao.FaixaID
+	}
+	return nil
+}
+
+// Confere se o participante se enquadra na categoria escolhida. A idade é a
+// da data do evento; peso e faixa não informados não passam por limites definidos.
+func enquadrarCategoria(tx *gorm.DB, evento *models.Evento, inscricao *models.InscricaoEvento, categoriaID string) error {
+	if categoriaID == "" {
+		var total int64
+		if err := tx.Model(&models.CategoriaEvento{}).Where("evento_id = ?", evento.ID).Count(&total).Error; err != nil {
+			return err
+		}
+		if total > 0 && evento.Tipo == models.EventoCampeonato {
+			return ErrCategoriaObrigatoria
+		}
+		return nil
+	}
+
+	var categoria models.CategoriaEvento
+	if err := tx.Preload("FaixaMinima").Preload("FaixaMaxima").
+		First(&categoria, "id = ? AND evento_id = ?", categoriaID, evento.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: categoria não encontrada", ErrCategoriaIncompativel)
+		}
+		return err
+	}
+	inscricao.CategoriaID = &categoria.ID
+
+	if categoria.Genero != "" && inscricao.Genero != categoria.Genero {
+		return fmt.Errorf("%w: gênero", ErrCategoriaIncompativel)
+	}
+	if inscricao.DataNascimento != nil {
+		idade := (&models.Cliente{DataNascimento: *inscricao.DataNascimento}).Idade(evento.Inicio)
+		if (categoria.IdadeMinima != nil && idade < *categoria.IdadeMinima) || (categoria.IdadeMaxima != nil && idade > *categoria.IdadeMaxima) {
+			return fmt.Errorf("%w: idade", ErrCategoriaIncompativel)
+		}
+	}
+	if categoria.PesoMinimo != nil || categoria.PesoMaximo != nil {
+		if inscricao.Peso == nil {
+			return fmt.Errorf("%w: informe o peso", ErrCategoriaIncompativel)
+		}
+		if (categoria.PesoMinimo != nil && *inscricao.Peso < *categoria.PesoMinimo) || (categoria.PesoMaximo != nil && *inscricao.Peso > *categoria.PesoMaximo) {
+			return fmt.Errorf("%w: peso", ErrCategoriaIncompativel)
+		}
+	}
+	if categoria.FaixaMinima != nil || categoria.FaixaMaxima != nil {
+		if inscricao.FaixaID == nil {
+			return fmt.Errorf("%w: faixa não informada", ErrCategoriaIncompativel)
+		}
+		var faixa models.Faixa
+		if err := tx.First(&faixa, "id = ?", *inscricao.FaixaID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: faixa não encontrada", ErrCategoriaIncompativel)
+			}
+			return err
+		}
+		if (categoria.FaixaMinima != nil && faixa.Ordem < categoria.FaixaMinima.Ordem) ||
+			(categoria.FaixaMaxima != nil && faixa.Ordem > categoria.FaixaMaxima.Ordem) {
+			return fmt.Errorf("%w: faixa", ErrCategoriaIncompativel)
+		}
+	}
+	return nil
+}
+
+func cancelarInscricao(tx *gorm.DB, inscricao *models.InscricaoEvento) error {
+	if err := tx.Model(inscricao).Update("status", models.InscricaoCancelada).Error; err != nil {
+		return err
+	}
+	inscricao.Status = models.InscricaoCancelada
+
+	return cancelarVendaPendente(tx, inscricao.VendaID)
+}
+
+func validarModalidadeEvento(tx *gorm.DB, modalidadeID *string) error {
+	if modalidadeID == nil {
+		return nil
+	}
+	var total int64
+	if err := tx.Model(&models.Modalidade{}).Where("id = ?", *modalidadeID).Count(&total).Error; err != nil {
+		return err
+	}
+	if total == 0 {
+		return ErrModalidadeInvalida
+	}
+	return nil
+}
+
+// As faixas-limite da categoria precisam ser da modalidade do evento
+func validarCategoriaEvento(tx *gorm.DB, evento *models.Evento, categoria *models.CategoriaEvento) error {
+	if categoria.IdadeMinima != nil && categoria.IdadeMaxima != nil && *categoria.IdadeMinima > *categoria.IdadeMaxima {
+		return ErrFaixaEtariaInvalida
+	}
+
+	for _, faixaID := range []*string{categoria.FaixaMinimaID, categoria.FaixaMaximaID} {
+		if faixaID == nil {
+			continue
+		}
+		if evento.ModalidadeID == nil {
+			return ErrFaixaCategoriaInvalida
+		}
+		var total int64
+		if err := tx.Model(&models.Faixa{}).Where("id = ? AND modalidade_id = ?", *faixaID, *evento.ModalidadeID).
+			Count(&total).Error; err != nil {
+			return err
+		}
+		if total == 0 {
+			return ErrFaixaCategoriaInvalida
+		}
+	}
+	return nil
+}
+
+// O preço de referência do produto acompanha o lote mais barato
+func atualizarPrecoProdutoEvento(tx *gorm.DB, eventoID string) error {
+	var evento models.Evento
+	if err := tx.Preload("Lotes").First(&evento, "id = ?", eventoID).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Produto{}).Where("id = ?", evento.ProdutoID).
+		Update("preco", menorPrecoLotes(evento.Lotes)).Error
+}
+
+func menorPrecoLotes(lotes []models.LoteEvento) float64 {
+	menor := 0.0
+	for i, lote := range lotes {
+		if i == 0 || lote.Preco < menor {
+			menor = lote.Preco
+		}
+	}
+	return menor
+}
+
+func buscarEvento(tx *gorm.DB, eventoID string) (*models.Evento, error) {
+	var evento models.Evento
+	if err := tx.First(&evento, "id = ?", eventoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEventoNaoEncontrado
+		}
+		return nil, err
+	}
+	return &evento, nil
+}
+
+// Carrega o evento com bloqueio de linha até o fim da transação
+func bloquearEvento(tx *gorm.DB, eventoID string) (*models.Evento, error) {
+	return buscarEvento(tx.Clauses(clause.Locking{Strength: "UPDATE"}), eventoID)
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"go-api/models"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// Formatos de exportação suportados
+const (
+	FormatoCSV   = "csv"
+	FormatoXLSX  = "xlsx"
+	FormatoJSONL = "jsonl"
+)
+
+var ErrFormatoExportacao = errors.New("formato de exportação inválido, use csv, xlsx ou jsonl")
+
+// OpcoesExportacao define quais dados entram na exportação de clientes
+type OpcoesExportacao struct {
+	Formato       string
+	Guardians     bool
+	Assinatura    bool
+	Inadimplencia bool
+	IncluirPII    bool
+}
+
+// ContentType retorna o tipo de conteúdo e a extensão do arquivo exportado
+func (o OpcoesExportacao) ContentType() (string, string, error) {
+	switch o.Formato {
+	case FormatoCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	case FormatoXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx", nil
+	case FormatoJSONL:
+		return "application/x-ndjson", "jsonl", nil
+	}
+	return "", "", ErrFormatoExportacao
+}
+
+// Coluna exportada e a função que extrai seu valor da linha
+type colunaExportacao struct {
+	nome  string
+	pii   bool
+	valor func(l *linhaExportacao) interface{}
+}
+
+// Dados de um cliente reunidos para a exportação
+type linhaExportacao struct {
+	cliente         models.Cliente
+	assinatura      *models.Subscription
+	valorEmAberto   float64
+	responsaveisTxt string
+}
+
+func colunasExportacao(opcoes OpcoesExportacao) []colunaExportacao {
+	colunas := []colunaExportacao{
+		{"id", false, func(l *linhaExportacao) interface{} { return l.cliente.ID }},
+		{"nome", false, func(l *linhaExportacao) interface{} { return l.cliente.Nome }},
+		{"data_nascimento", true, func(l *linhaExportacao) interface{} { return l.cliente.DataNascimento.Format("2006-01-02") }},
+		{"genero", false, func(l *linhaExportacao) interface{} { return l.cliente.Genero }},
+		{"email", true, func(l *linhaExportacao) interface{} { return l.cliente.Email }},
+		{"telefone", true, func(l *linhaExportacao) interface{} { return l.cliente.Telefone }},
+		{"cpf", true, func(l *linhaExportacao) interface{} { return l.cliente.CPF }},
+		{"endereco", true, func(l *linhaExportacao) interface{} { return l.cliente.Endereco }},
+		{"cidade", false, func(l *linhaExportacao) interface{} { return l.cliente.Cidade }},
+		{"estado", false, func(l *linhaExportacao) interface{} { return l.cliente.Estado }},
+		{"cep", true, func(l *linhaExportacao) interface{} { return l.cliente.CEP }},
+		{"flag_aniversariante", false, func(l *linhaExportacao) interface{} { return l.cliente.FlagAniversariante }},
+		{"created_at", false, func(l *linhaExportacao) interface{} { return l.cliente.CreatedAt.Format(time.RFC3339) }},
+	}
+
+	if opcoes.Guardians {
+		colunas = append(colunas, colunaExportacao{"responsaveis", true, func(l *linhaExportacao) interface{} { return l.responsaveisTxt }})
+	}
+
+	if opcoes.Assinatura {
+		colunas = append(colunas,
+			colunaExportacao{"assinatura_ativa", false, func(l *linhaExportacao) interface{} { return l.assinatura != nil }},
+			colunaExportacao{"assinatura_status", false, func(l *linhaExportacao) interface{} {
+				if l.assinatura == nil {
+					return ""
+				}
+				return string(l.assinatura.PaymentStatus)
+			}},
+			colunaExportacao{"assinatura_valor", false, func(l *linhaExportacao) interface{} {
+				if l.assinatura == nil {
+					return 0.0
+				}
+				return l.assinatura.Amount
+			}},
+		)
+	}
+
+	if opcoes.Inadimplencia {
+		colunas = append(colunas,
+			colunaExportacao{"inadimplente", false, func(l *linhaExportacao) interface{} { return l.cliente.FlagInadimplente }},
+			colunaExportacao{"valor_em_aberto", false, func(l *linhaExportacao) interface{} { return l.valorEmAberto }},
+		)
+	}
+
+	// Colunas de dados pessoais só entram para papéis com permissão
+	if !opcoes.IncluirPII {
+		filtradas := colunas[:0]
+		for _, coluna := range colunas {
+			if !coluna.pii {
+				filtradas = append(filtradas, coluna)
+			}
+		}
+		colunas = filtradas
+	}
+
+	return colunas
+}
+
+// Escritor de um formato de exportação
+type escritorExportacao interface {
+	cabecalho(colunas []string) error
+	linha(colunas []string, valores []interface{}) error
+	fechar() error
+}
+
+type escritorCSV struct{ w *csv.Writer }
+
+func (e *escritorCSV) cabecalho(colunas []string) error { return e.w.Write(colunas) }
+
+func (e *escritorCSV) linha(_ []string, valores []interface{}) error {
+	registro := make([]string, len(valores))
+	for i, v := range valores {
+		registro[i] = formatarValor(v)
+	}
+	return e.w.Write(registro)
+}
+
+func (e *escritorCSV) fechar() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type escritorJSONL struct{ enc *json.Encoder }
+
+func (e *escritorJSONL) cabecalho([]string) error { return nil }
+
+func (e *escritorJSONL) linha(colunas []string, valores []interface{}) error {
+	registro := make(map[string]interface{}, len(colunas))
+	for i, coluna := range colunas {
+		registro[coluna] = valores[i]
+	}
+	return e.enc.Encode(registro)
+}
+
+func (e *escritorJSONL) fechar() error { return nil }
+
+// O XLSX usa o StreamWriter do excelize, que mantém as linhas em arquivo
+// temporário; o arquivo final só pode ser escrito ao término
+type escritorXLSX struct {
+	out     io.Writer
+	arquivo *excelize.File
+	stream  *excelize.StreamWriter
+	linhaN  int
+}
+
+func (e *escritorXLSX) cabecalho(colunas []string) error {
+	celulas := make([]interface{}, len(colunas))
+	for i, coluna := range colunas {
+		celulas[i] = coluna
+	}
+	e.linhaN = 1
+	return e.stream.SetRow("A1", celulas)
+}
+
+func (e *escritorXLSX) linha(_ []string, valores []interface{}) error {
+	e.linhaN++
+	celula, err := excelize.CoordinatesToCellName(1, e.linhaN)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(celula, valores)
+}
+
+func (e *escritorXLSX) fechar() error {
+	defer e.arquivo.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := e.arquivo.WriteTo(e.out)
+	return err
+}
+
+func novoEscritor(formato string, w io.Writer) (escritorExportacao, error) {
+	switch formato {
+	case FormatoCSV:
+		return &escritorCSV{w: csv.NewWriter(w)}, nil
+	case FormatoJSONL:
+		return &escritorJSONL{enc: json.NewEncoder(w)}, nil
+	case FormatoXLSX:
+		arquivo := excelize.NewFile()
+		stream, err := arquivo.NewStreamWriter("Sheet1")
+		if err != nil {
+			arquivo.Close()
+			return nil, err
+		}
+		return &escritorXLSX{out: w, arquivo: arquivo, stream: stream}, nil
+	}
+	return nil, ErrFormatoExportacao
+}
+
+// ExportarClientes escreve os clientes retornados pela consulta no formato pedido,
+// processando em lotes para não carregar toda a base em memória
+func ExportarClientes(query *gorm.DB, opcoes OpcoesExportacao, w io.Writer) error {
+	escritor, err := novoEscritor(opcoes.Formato, w)
+	if err != nil {
+		return err
+	}
+
+	colunas := colunasExportacao(opcoes)
+	nomes := make([]string, len(colunas))
+	for i, coluna := range colunas {
+		nomes[i] = coluna.nome
+	}
+	if err := escritor.cabecalho(nomes); err != nil {
+		return err
+	}
+
+	if opcoes.Guardians {
+		query = query.Preload("Guardians", "arquivado_em IS NULL")
+	}
+
+	agora := time.Now()
+	var lote []models.Cliente
+	resultado := query.FindInBatches(&lote, 500, func(tx *gorm.DB, _ int) error {
+		assinaturas := map[string]*models.Subscription{}
+		if opcoes.Assinatura && len(lote) > 0 {
+			ids := make([]string, len(lote))
+			for i, cliente := range lote {
+				ids[i] = cliente.ID
+			}
+			var ativas []models.Subscription
+			if err := tx.Session(&gorm.Session{NewDB: true}).
+				Where("cliente_id IN ? AND active = ?", ids, true).
+				Order("criado_em").Find(&ativas).Error; err != nil {
+				return err
+			}
+			for i := range ativas {
+				assinaturas[ativas[i].ClienteID] = &ativas[i]
+			}
+		}
+
+		for _, cliente := range lote {
+			linha := linhaExportacao{cliente: cliente, assinatura: assinaturas[cliente.ID]}
+
+			if opcoes.Guardians {
+				partes := make([]string, 0, len(cliente.Guardians))
+				for _, g := range cliente.Guardians {
+					partes = append(partes, g.Nome+" ("+string(g.Parentesco)+") "+g.Telefone+" "+g.CPF)
+				}
+				linha.responsaveisTxt = strings.Join(partes, "; ")
+			}
+
+			if opcoes.Inadimplencia && cliente.FlagInadimplente {
+				valor, _, _, err := ValorEmAberto(tx.Session(&gorm.Session{NewDB: true}), cliente.ID, agora)
+				if err != nil {
+					return err
+				}
+				linha.valorEmAberto = valor
+			}
+
+			valores := make([]interface{}, len(colunas))
+			for i, coluna := range colunas {
+				valores[i] = coluna.valor(&linha)
+			}
+			if err := escritor.linha(nomes, valores); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if resultado.Error != nil {
+		return resultado.Error
+	}
+
+	return escritor.fechar()
+}
+
+func formatarValor(v interface{}) string {
+	switch valor := v.(type) {
+	case string:
+		return valor
+	case bool:
+		if valor {
+			return "sim"
+		}
+		return "nao"
+	default:
+		b, _ := json.Marshal(valor)
+		return string(b)
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFamiliaNaoEncontrada          = errors.New("família não encontrada")
+	ErrClienteEmOutraFamilia         = errors.New("cliente já pertence a outra família")
+	ErrClienteForaDaFamilia          = errors.New("cliente não pertence a esta família")
+	ErrResponsavelFinanceiroInvalido = errors.New("o responsável financeiro deve ser um membro maior de idade da família")
+)
+
+// ItemCobrancaFamilia é uma assinatura ativa de um membro da família
+type ItemCobrancaFamilia struct {
+	ClienteID       string               `json:"cliente_id"`
+	Nome            string               `json:"nome"`
+	AssinaturaID    string               `json:"assinatura_id"`
+	ValorOriginal   float64              `json:"valor_original"`
+	DescontoFamilia float64              `json:"desconto_familia"`
+	Valor           float64              `json:"valor"`
+	PaymentStatus   models.PaymentStatus `json:"payment_status"`
+	NextBillingDate time.Time            `json:"next_billing_date"`
+}
+
+// CobrancaFamilia consolida as assinaturas ativas dos membros em uma única cobrança
+type CobrancaFamilia struct {
+	Familia               models.Familia        `json:"familia"`
+	ResponsavelFinanceiro *models.Cliente       `json:"responsavel_financeiro"`
+	Itens                 []ItemCobrancaFamilia `json:"itens"`
+	Total                 float64               `json:"total"`
+	TotalDescontos        float64               `json:"total_descontos"`
+	EmAberto              float64               `json:"em_aberto"`
+	ProximaCobranca       *time.Time            `json:"proxima_cobranca"`
+}
+
+// CriarFamilia cria a família com os membros e o responsável financeiro informados
+func CriarFamilia(familia *models.Familia, membros []string, autor string) error {
+	familia.ID = ""
+	familia.Membros = nil
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(familia).Error; err != nil {
+			return err
+		}
+		for _, clienteID := range membros {
+			if err := adicionarMembro(tx, familia.ID, clienteID, autor); err != nil {
+				return err
+			}
+		}
+		if familia.ResponsavelFinanceiroID != nil {
+			if err := validarResponsavelFinanceiro(tx, familia.ID, *familia.ResponsavelFinanceiroID); err != nil {
+				return err
+			}
+		}
+		if familia.DiaCobranca != nil {
+			if err := alinharCobranca(tx, familia.ID, *familia.DiaCobranca); err != nil {
+				return err
+			}
+		}
+		return RegistrarAuditoria(tx, "familia", familia.ID, nil, "familia.criacao", autor, map[string]interface{}{"membros": membros})
+	})
+}
+
+// AtualizarFamilia altera o nome, o responsável financeiro e o dia de cobrança.
+// Mudar o dia de cobrança realinha as assinaturas ativas de todos os membros.
+func AtualizarFamilia(familia *models.Familia, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var existente models.Familia
+		if err := tx.First(&existente, "id = ?", familia.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFamiliaNaoEncontrada
+			}
+			return err
+		}
+
+		if familia.ResponsavelFinanceiroID != nil {
+			if err := validarResponsavelFinanceiro(tx, familia.ID, *familia.ResponsavelFinanceiroID); err != nil {
+				return err
+			}
+		}
+
+		familia.CreatedAt = existente.CreatedAt
+		if err := tx.Omit("Membros", "ResponsavelFinanceiro").Save(familia).Error; err != nil {
+			return err
+		}
+
+		if familia.DiaCobranca != nil && (existente.DiaCobranca == nil || *existente.DiaCobranca != *familia.DiaCobranca) {
+			if err := alinharCobranca(tx, familia.ID, *familia.DiaCobranca); err != nil {
+				return err
+			}
+		}
+
+		return RegistrarAuditoria(tx, "familia", familia.ID, familia.ResponsavelFinanceiroID, "familia.atualizacao", autor,
+			map[string]interface{}{"responsavel_financeiro_id": familia.ResponsavelFinanceiroID, "dia_cobranca": familia.DiaCobranca})
+	})
+}
+
+// AdicionarMembro vincula o cliente à família
+func AdicionarMembro(familiaID, clienteID, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var familia models.Familia
+		if err := tx.First(&familia, "id = ?", familiaID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFamiliaNaoEncontrada
+			}
+			return err
+		}
+		if err := adicionarMembro(tx, familiaID, clienteID, autor); err != nil {
+			return err
+		}
+
+		// As assinaturas do novo membro passam a seguir o dia de cobrança da família
+		if familia.DiaCobranca != nil {
+			return alinharCobranca(tx, familiaID, *familia.DiaCobranca)
+		}
+		return nil
+	})
+}
+
+func adicionarMembro(tx *gorm.DB, familiaID, clienteID, autor string) error {
+	var cliente models.Cliente
+	if err := tx.Scopes(ApenasAtivos).Select("id", "familia_id").First(&cliente, "id = ?", clienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrClienteNaoEncontrado
+		}
+		return err
+	}
+	if cliente.FamiliaID != nil && *cliente.FamiliaID != familiaID {
+		return ErrClienteEmOutraFamilia
+	}
+
+	if err := tx.Model(&models.Cliente{}).Where("id = ?", clienteID).Update("familia_id", familiaID).Error; err != nil {
+		return err
+	}
+	return RegistrarAuditoria(tx, "familia", familiaID, &clienteID, "familia.entrada", autor, nil)
+}
+
+// RemoverMembro desvincula o cliente da família. Se ele era o responsável
+// financeiro, a família fica sem responsável até que outro seja definido.
+// Os descontos já concedidos às assinaturas existentes são mantidos.
+func RemoverMembro(familiaID, clienteID, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Cliente{}).Where("id = ? AND familia_id = ?", clienteID, familiaID).Update("familia_id", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrClienteForaDaFamilia
+		}
+
+		if err := tx.Model(&models.Familia{}).Where("id = ? AND responsavel_financeiro_id = ?", familiaID, clienteID).
+			Update("responsavel_financeiro_id", nil).Error; err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "familia", familiaID, &clienteID, "familia.saida", autor, nil)
+	})
+}
+
+// ResumoCobrancaFamilia soma as assinaturas ativas dos membros da família
+func ResumoCobrancaFamilia(familiaID string) (*CobrancaFamilia, error) {
+	resumo := &CobrancaFamilia{Itens: []ItemCobrancaFamilia{}}
+	if err := config.DB.Preload("ResponsavelFinanceiro").First(&resumo.Familia, "id = ?", familiaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFamiliaNaoEncontrada
+		}
+		return nil, err
+	}
+	resumo.ResponsavelFinanceiro = resumo.Familia.ResponsavelFinanceiro
+
+	var assinaturas []models.Subscription
+	if err := config.DB.Preload("Cliente").
+		Joins("JOIN clientes ON clientes.id = subscriptions.cliente_id").
+		Where("clientes.familia_id = ? AND subscriptions.active = ?", familiaID, true).
+		Order("clientes.nome").
+		Find(&assinaturas).Error; err != nil {
+		return nil, err
+	}
+
+	for _, assinatura := range assinaturas {
+		valorOriginal := assinatura.ValorOriginal
+		if valorOriginal == 0 {
+			valorOriginal = assinatura.Amount // Assinaturas anteriores às famílias
+		}
+
+		resumo.Itens = append(resumo.Itens, ItemCobrancaFamilia{
+			ClienteID:       assinatura.ClienteID,
+			Nome:            assinatura.Cliente.Nome,
+			AssinaturaID:    assinatura.ID,
+			ValorOriginal:   valorOriginal,
+			DescontoFamilia: assinatura.DescontoFamilia,
+			Valor:           assinatura.Amount,
+			PaymentStatus:   assinatura.PaymentStatus,
+			NextBillingDate: assinatura.NextBillingDate,
+		})
+		resumo.Total += assinatura.Amount
+		resumo.TotalDescontos += valorOriginal - assinatura.Amount
+		if assinatura.PaymentStatus == models.Pending || assinatura.PaymentStatus == models.Overdue {
+			resumo.EmAberto += assinatura.Amount
+		}
+		if resumo.ProximaCobranca == nil || assinatura.NextBillingDate.Before(*resumo.ProximaCobranca) {
+			proxima := assinatura.NextBillingDate
+			resumo.ProximaCobranca = &proxima
+		}
+	}
+
+	return resumo, nil
+}
+
+// PagarCobrancaFamilia registra o pagamento consolidado, quitando de uma vez as
+// assinaturas ativas pendentes ou em atraso de todos os membros
+func PagarCobrancaFamilia(familiaID, autor string) ([]string, error) {
+	var clientes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var familia models.Familia
+		if err := tx.First(&familia, "id = ?", familiaID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFamiliaNaoEncontrada
+			}
+			return err
+		}
+
+		var assinaturas []models.Subscription
+		if err := tx.Joins("JOIN clientes ON clientes.id = subscriptions.cliente_id").
+			Where("clientes.familia_id = ? AND subscriptions.active = ? AND subscriptions.payment_status IN ?",
+				familiaID, true, []models.PaymentStatus{models.Pending, models.Overdue}).
+			Find(&assinaturas).Error; err != nil {
+			return err
+		}
+
+		for _, assinatura := range assinaturas {
+			statusAnterior := assinatura.PaymentStatus
+			if err := tx.Model(&assinatura).Update("payment_status", models.Paid).Error; err != nil {
+				return err
+			}
+			if err := RegistrarAuditoria(tx, "subscription", assinatura.ID, &assinatura.ClienteID, "assinatura.pagamento", autor,
+				map[string]interface{}{"status": models.Paid, "status_anterior": statusAnterior, "valor": assinatura.Amount, "familia_id": familiaID}); err != nil {
+				return err
+			}
+			clientes = append(clientes, assinatura.ClienteID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clientes, nil
+}
+
+// O responsável financeiro precisa ser membro da família e maior de idade
+func validarResponsavelFinanceiro(tx *gorm.DB, familiaID, clienteID string) error {
+	var cliente models.Cliente
+	if err := tx.First(&cliente, "id = ? AND familia_id = ?", clienteID, familiaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrResponsavelFinanceiroInvalido
+		}
+		return err
+	}
+	if cliente.MenorDeIdade() {
+		return ErrResponsavelFinanceiroInvalido
+	}
+	return nil
+}
+
+// Aplica o dia de cobrança da família às assinaturas ativas dos membros.
+// Assinaturas com congelamento agendado ou em andamento ficam de fora, para não
+// perder o adiamento da cobrança que o congelamento devolve ao ser encerrado.
+func alinharCobranca(tx *gorm.DB, familiaID string, dia int) error {
+	congeladas := tx.Model(&models.CongelamentoAssinatura{}).Select("subscription_id").
+		Where("status IN ?", congelamentosVigentes)
+
+	var assinaturas []models.Subscription
+	if err := tx.Joins("JOIN clientes ON clientes.id = subscriptions.cliente_id").
+		Where("clientes.familia_id = ? AND subscriptions.active = ? AND subscriptions.billing_day <> ?", familiaID, true, dia).
+		Where("subscriptions.id NOT IN (?)", congeladas).
+		Find(&assinaturas).Error; err != nil {
+		return err
+	}
+
+	agora := time.Now()
+	for _, assinatura := range assinaturas {
+		if err := tx.Model(&assinatura).Updates(map[string]interface{}{
+			"billing_day":       dia,
+			"next_billing_date": proximaCobranca(dia, agora),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
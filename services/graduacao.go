@@ -0,0 +1,448 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrFaixaNaoEncontrada     = errors.New("faixa não encontrada nesta modalidade")
+	ErrSemProximaGraduacao    = errors.New("cliente já está na graduação máxima da modalidade")
+	ErrGraduacaoRetroativa    = errors.New("a nova graduação deve ser superior à atual")
+	ErrGrauInvalido           = errors.New("grau acima do máximo da faixa")
+	ErrDataGraduacaoAnterior  = errors.New("a data da promoção é anterior à da graduação atual")
+	ErrRequisitosNaoAtendidos = errors.New("cliente não atende aos requisitos da graduação")
+	ErrExameNaoEncontrado     = errors.New("exame não encontrado")
+	ErrExameEncerrado         = errors.New("exame já realizado ou cancelado")
+	ErrCandidatoNaoEncontrado = errors.New("candidato não inscrito neste exame")
+)
+
+// Elegibilidade descreve a situação do cliente para a próxima graduação
+type Elegibilidade struct {
+	ClienteID        string        `json:"cliente_id"`
+	Nome             string        `json:"nome"`
+	ModalidadeID     string        `json:"modalidade_id"`
+	Atual            *models.Faixa `json:"faixa_atual"`
+	GrauAtual        int           `json:"grau_atual"`
+	DesdeEm          *time.Time    `json:"desde_em"`
+	Proxima          *models.Faixa `json:"proxima_faixa"`
+	ProximoGrau      int           `json:"proximo_grau"`
+	MesesNoNivel     int           `json:"meses_no_nivel"`
+	MesesExigidos    int           `json:"meses_exigidos"`
+	Presencas        int64         `json:"presencas"`
+	PresencasMinimas int           `json:"presencas_minimas"`
+	Elegivel         bool          `json:"elegivel"`
+	Pendencias       []string      `json:"pendencias"`
+}
+
+// PromocaoGraduacao são os dados de uma promoção manual ou por exame
+type PromocaoGraduacao struct {
+	ModalidadeID string    `json:"modalidade_id" validate:"required"`
+	FaixaID      string    `json:"faixa_id" validate:"required"`
+	Grau         int       `json:"grau" validate:"gte=0"`
+	Data         time.Time `json:"data"`
+	InstrutorID  *string   `json:"instrutor_id"`
+	Observacao   string    `json:"observacao"`
+	Forcar       bool      `json:"forcar"` // Ignora os requisitos de tempo e frequência
+}
+
+// GraduacaoAtual retorna a promoção mais recente do cliente na modalidade
+func GraduacaoAtual(tx *gorm.DB, clienteID, modalidadeID string) (*models.Graduacao, error) {
+	var graduacao models.Graduacao
+	err := tx.Preload("Faixa").Where("cliente_id = ? AND modalidade_id = ?", clienteID, modalidadeID).
+		Order("data DESC, created_at DESC").First(&graduacao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &graduacao, nil
+}
+
+// Próximo nível após a graduação atual: um grau a mais na mesma faixa ou a
+// faixa seguinte sem graus. Sem graduação, a primeira faixa da modalidade.
+func proximoNivel(tx *gorm.DB, modalidadeID string, atual *models.Graduacao) (*models.Faixa, int, error) {
+	if atual != nil && atual.Grau < atual.Faixa.GrausMaximos {
+		return atual.Faixa, atual.Grau + 1, nil
+	}
+
+	query := tx.Where("modalidade_id = ?", modalidadeID).Order("ordem")
+	if atual != nil {
+		query = query.Where("ordem > ?", atual.Faixa.Ordem)
+	}
+
+	var proxima models.Faixa
+	if err := query.First(&proxima).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, ErrSemProximaGraduacao
+		}
+		return nil, 0, err
+	}
+	return &proxima, 0, nil
+}
+
+// VerificarElegibilidade confere tempo na graduação, frequência nas aulas da
+// modalidade desde a última promoção e idade mínima da próxima faixa
+func VerificarElegibilidade(tx *gorm.DB, clienteID, modalidadeID string, ref time.Time) (*Elegibilidade, error) {
+	var cliente models.Cliente
+	if err := tx.Select("id", "nome", "data_nascimento").First(&cliente, "id = ?", clienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClienteNaoEncontrado
+		}
+		return nil, err
+	}
+
+	atual, err := GraduacaoAtual(tx, clienteID, modalidadeID)
+	if err != nil {
+		return nil, err
+	}
+	proxima, proximoGrau, err := proximoNivel(tx, modalidadeID, atual)
+	if err != nil {
+		return nil, err
+	}
+
+	resultado := &Elegibilidade{
+		ClienteID:    clienteID,
+		Nome:         cliente.Nome,
+		ModalidadeID: modalidadeID,
+		Proxima:      proxima,
+		ProximoGrau:  proximoGrau,
+		Pendencias:   []string{},
+	}
+
+	// Requisitos de tempo e frequência só existem a partir da primeira graduação
+	if atual != nil {
+		resultado.Atual = atual.Faixa
+		resultado.GrauAtual = atual.Grau
+		resultado.DesdeEm = &atual.Data
+
+		if proximoGrau > 0 {
+			resultado.MesesExigidos = atual.Faixa.TempoMinimoGrauMeses
+			resultado.PresencasMinimas = atual.Faixa.PresencasMinimasGrau
+		} else {
+			resultado.MesesExigidos = atual.Faixa.TempoMinimoMeses
+			resultado.PresencasMinimas = atual.Faixa.PresencasMinimas
+		}
+
+		resultado.MesesNoNivel = mesesEntre(atual.Data, ref)
+		if err := tx.Model(&models.Presenca{}).
+			Joins("JOIN aulas ON aulas.id = presencas.aula_id").
+			Where("presencas.cliente_id = ? AND aulas.modalidade_id = ? AND presencas.data >= ?", clienteID, modalidadeID, atual.Data).
+			Count(&resultado.Presencas).Error; err != nil {
+			return nil, err
+		}
+
+		if resultado.MesesNoNivel < resultado.MesesExigidos {
+			resultado.Pendencias = append(resultado.Pendencias,
+				fmt.Sprintf("tempo mínimo de %d meses (atual: %d)", resultado.MesesExigidos, resultado.MesesNoNivel))
+		}
+		if resultado.Presencas < int64(resultado.PresencasMinimas) {
+			resultado.Pendencias = append(resultado.Pendencias,
+				fmt.Sprintf("mínimo de %d presenças (atual: %d)", resultado.PresencasMinimas, resultado.Presencas))
+		}
+	}
+
+	if proximoGrau == 0 && proxima.IdadeMinima > 0 && cliente.Idade(ref) < proxima.IdadeMinima {
+		resultado.Pendencias = append(resultado.Pendencias, fmt.Sprintf("idade mínima de %d anos", proxima.IdadeMinima))
+	}
+
+	resultado.Elegivel = len(resultado.Pendencias) == 0
+	return resultado, nil
+}
+
+// ListarElegiveis avalia os clientes ativos que já têm graduação ou frequentam
+// a modalidade e retorna os que podem ser promovidos
+func ListarElegiveis(modalidadeID string, ref time.Time) ([]Elegibilidade, error) {
+	var clientes []string
+	if err := config.DB.Model(&models.Cliente{}).Scopes(ApenasAtivos).
+		Where("clientes.id IN (?) OR clientes.id IN (?)",
+			config.DB.Model(&models.Graduacao{}).Select("cliente_id").Where("modalidade_id = ?", modalidadeID),
+			config.DB.Model(&models.Presenca{}).Select("presencas.cliente_id").
+				Joins("JOIN aulas ON aulas.id = presencas.aula_id").Where("aulas.modalidade_id = ?", modalidadeID)).
+		Order("clientes.nome").
+		Pluck("clientes.id", &clientes).Error; err != nil {
+		return nil, err
+	}
+
+	elegiveis := []Elegibilidade{}
+	for _, clienteID := range clientes {
+		resultado, err := VerificarElegibilidade(config.DB, clienteID, modalidadeID, ref)
+		if errors.Is(err, ErrSemProximaGraduacao) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resultado.Elegivel {
+			elegiveis = append(elegiveis, *resultado)
+		}
+	}
+	return elegiveis, nil
+}
+
+// PromoverCliente registra uma nova graduação, que deve ser superior à atual.
+// Sem Forcar, os requisitos de tempo e frequência do nível atual são exigidos.
+func PromoverCliente(clienteID string, promocao PromocaoGraduacao, exameID *string, autor string) (*models.Graduacao, error) {
+	var graduacao *models.Graduacao
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		graduacao, err = promover(tx, clienteID, promocao, exameID, promocao.Forcar, autor)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return graduacao, nil
+}
+
+// Registra a promoção na transação recebida. dispensarRequisitos pula a
+// conferência de tempo e frequência; promocao.Forcar indica apenas se ela foi
+// dispensada por decisão manual, para a auditoria.
+func promover(tx *gorm.DB, clienteID string, promocao PromocaoGraduacao, exameID *string, dispensarRequisitos bool, autor string) (*models.Graduacao, error) {
+	// Bloqueia o cliente para que duas promoções simultâneas não partam da mesma graduação
+	var cliente models.Cliente
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(ApenasAtivos).Select("clientes.id").
+		Where("clientes.anonimizado_em IS NULL").First(&cliente, "clientes.id = ?", clienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClienteNaoEncontrado
+		}
+		return nil, err
+	}
+
+	var faixa models.Faixa
+	if err := tx.First(&faixa, "id = ? AND modalidade_id = ?", promocao.FaixaID, promocao.ModalidadeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFaixaNaoEncontrada
+		}
+		return nil, err
+	}
+	if promocao.Grau > faixa.GrausMaximos {
+		return nil, ErrGrauInvalido
+	}
+
+	atual, err := GraduacaoAtual(tx, clienteID, promocao.ModalidadeID)
+	if err != nil {
+		return nil, err
+	}
+	if atual != nil && (faixa.Ordem < atual.Faixa.Ordem || (faixa.Ordem == atual.Faixa.Ordem && promocao.Grau <= atual.Grau)) {
+		return nil, ErrGraduacaoRetroativa
+	}
+
+	data := promocao.Data
+	if data.IsZero() {
+		data = time.Now()
+	}
+	// A graduação atual é a de data mais recente; uma promoção anterior a ela nunca passaria a valer
+	if atual != nil && dataLocal(data.In(time.Local)).Before(dataLocal(atual.Data)) {
+		return nil, ErrDataGraduacaoAnterior
+	}
+
+	if !dispensarRequisitos {
+		elegibilidade, err := VerificarElegibilidade(tx, clienteID, promocao.ModalidadeID, data)
+		if err != nil {
+			return nil, err
+		}
+		if !elegibilidade.Elegivel {
+			return nil, fmt.Errorf("%w: %v", ErrRequisitosNaoAtendidos, elegibilidade.Pendencias)
+		}
+	}
+
+	graduacao := &models.Graduacao{
+		ClienteID:     clienteID,
+		ModalidadeID:  promocao.ModalidadeID,
+		FaixaID:       faixa.ID,
+		Grau:          promocao.Grau,
+		Data:          data,
+		InstrutorID:   promocao.InstrutorID,
+		ExameID:       exameID,
+		Observacao:    promocao.Observacao,
+		RegistradoPor: autor,
+	}
+	if err := tx.Omit(clause.Associations).Create(graduacao).Error; err != nil {
+		return nil, err
+	}
+	graduacao.Faixa = &faixa
+
+	if err := RegistrarAuditoria(tx, "graduacao", graduacao.ID, &clienteID, "graduacao.promocao", autor,
+		map[string]interface{}{"faixa": faixa.Nome, "grau": graduacao.Grau, "exame_id": exameID, "forcada": promocao.Forcar}); err != nil {
+		return nil, err
+	}
+	return graduacao, nil
+}
+
+// InscreverCandidatos inscreve os clientes no exame com a próxima graduação de
+// cada um. Sem clientes informados, inscreve todos os elegíveis da modalidade.
+func InscreverCandidatos(exameID string, clienteIDs []string, forcar bool) ([]models.CandidatoExame, error) {
+	var exame models.ExameGraduacao
+	if err := config.DB.First(&exame, "id = ?", exameID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExameNaoEncontrado
+		}
+		return nil, err
+	}
+	if exame.Status != models.ExameAgendado {
+		return nil, ErrExameEncerrado
+	}
+
+	// Clientes informados na lista passam pela mesma conferência, salvo com forcar
+	conferir := len(clienteIDs) > 0 && !forcar
+	if len(clienteIDs) == 0 {
+		forcar = false
+		elegiveis, err := ListarElegiveis(exame.ModalidadeID, exame.Data)
+		if err != nil {
+			return nil, err
+		}
+		for _, elegivel := range elegiveis {
+			clienteIDs = append(clienteIDs, elegivel.ClienteID)
+		}
+	}
+
+	inscritos := []models.CandidatoExame{}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, clienteID := range clienteIDs {
+			if conferir {
+				elegibilidade, err := VerificarElegibilidade(tx, clienteID, exame.ModalidadeID, exame.Data)
+				if err != nil {
+					return fmt.Errorf("cliente %s: %w", clienteID, err)
+				}
+				if !elegibilidade.Elegivel {
+					return fmt.Errorf("cliente %s: %w: %v", clienteID, ErrRequisitosNaoAtendidos, elegibilidade.Pendencias)
+				}
+			}
+
+			atual, err := GraduacaoAtual(tx, clienteID, exame.ModalidadeID)
+			if err != nil {
+				return err
+			}
+			proxima, grau, err := proximoNivel(tx, exame.ModalidadeID, atual)
+			if err != nil {
+				return fmt.Errorf("cliente %s: %w", clienteID, err)
+			}
+
+			candidato := models.CandidatoExame{
+				ExameID:               exame.ID,
+				ClienteID:             clienteID,
+				FaixaPretendidaID:     proxima.ID,
+				GrauPretendido:        grau,
+				Resultado:             models.ResultadoPendente,
+				RequisitosDispensados: forcar,
+			}
+			if atual != nil {
+				candidato.FaixaAtualID = &atual.FaixaID
+				candidato.GrauAtual = atual.Grau
+			}
+
+			// Reinscrever um cliente não duplica a inscrição
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&candidato)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				inscritos = append(inscritos, candidato)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inscritos, nil
+}
+
+// ResultadoCandidato é o resultado lançado para um candidato
+type ResultadoCandidato struct {
+	ClienteID  string                `json:"cliente_id" validate:"required"`
+	Resultado  models.ResultadoExame `json:"resultado" validate:"required,oneof=aprovado reprovado ausente"`
+	Observacao string                `json:"observacao"`
+}
+
+// RegistrarResultadosExame lança os resultados e promove os aprovados à
+// graduação pretendida. O exame passa a realizado quando não restam pendentes.
+func RegistrarResultadosExame(exameID string, resultados []ResultadoCandidato, autor string) (*models.ExameGraduacao, error) {
+	var exame models.ExameGraduacao
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&exame, "id = ?", exameID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrExameNaoEncontrado
+			}
+			return err
+		}
+		if exame.Status != models.ExameAgendado {
+			return ErrExameEncerrado
+		}
+
+		for _, resultado := range resultados {
+			var candidato models.CandidatoExame
+			if err := tx.First(&candidato, "exame_id = ? AND cliente_id = ?", exameID, resultado.ClienteID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: %s", ErrCandidatoNaoEncontrado, resultado.ClienteID)
+				}
+				return err
+			}
+
+			candidato.Resultado = resultado.Resultado
+			candidato.Observacao = resultado.Observacao
+			if resultado.Resultado == models.ResultadoAprovado && candidato.GraduacaoID == nil {
+				// A banca já avaliou o candidato. Os requisitos foram conferidos na
+				// inscrição, exceto para os inscritos com RequisitosDispensados
+				graduacao, err := promover(tx, candidato.ClienteID, PromocaoGraduacao{
+					ModalidadeID: exame.ModalidadeID,
+					FaixaID:      candidato.FaixaPretendidaID,
+					Grau:         candidato.GrauPretendido,
+					Data:         exame.Data,
+					InstrutorID:  exame.AvaliadorID,
+					Forcar:       candidato.RequisitosDispensados,
+				}, &exame.ID, true, autor)
+				if err != nil {
+					return fmt.Errorf("cliente %s: %w", candidato.ClienteID, err)
+				}
+				candidato.GraduacaoID = &graduacao.ID
+			}
+
+			if err := tx.Omit(clause.Associations).Save(&candidato).Error; err != nil {
+				return err
+			}
+		}
+
+		var pendentes int64
+		if err := tx.Model(&models.CandidatoExame{}).Where("exame_id = ? AND resultado = ?", exameID, models.ResultadoPendente).
+			Count(&pendentes).Error; err != nil {
+			return err
+		}
+		if pendentes == 0 {
+			exame.Status = models.ExameRealizado
+			if err := tx.Model(&exame).Update("status", exame.Status).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Preload("Candidatos.Cliente").Preload("Candidatos.FaixaPretendida").First(&exame, "id = ?", exameID).Error; err != nil {
+		return nil, err
+	}
+	return &exame, nil
+}
+
+// Meses completos entre duas datas
+func mesesEntre(de, ate time.Time) int {
+	meses := (ate.Year()-de.Year())*12 + int(ate.Month()) - int(de.Month())
+	if ate.Day() < de.Day() {
+		meses--
+	}
+	if meses < 0 {
+		return 0
+	}
+	return meses
+}
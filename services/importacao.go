@@ -0,0 +1,381 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+	"go-api/utils"
+
+	"github.com/matoous/go-nanoid/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// Campos aceitos na importação de clientes, com o nome padrão da coluna
+var CamposImportacao = []string{
+	"nome", "data_nascimento", "genero", "email", "telefone", "cpf",
+	"endereco", "cidade", "estado", "cep",
+	"responsavel_nome", "responsavel_parentesco", "responsavel_telefone",
+	"responsavel_email", "responsavel_cpf", "responsavel_financeiro",
+	"responsavel_autorizado_buscar",
+}
+
+// Formatos de data aceitos na coluna de nascimento
+var formatosData = []string{"2006-01-02", "02/01/2006", "2/1/2006", "02-01-2006", time.RFC3339}
+
+var ErrFormatoNaoSuportado = errors.New("formato de arquivo não suportado, use CSV ou XLSX")
+
+// Status do job de importação
+const (
+	ImportacaoPendente    = "pendente"
+	ImportacaoProcessando = "processando"
+	ImportacaoConcluida   = "concluida"
+)
+
+// LinhaImportacao é uma linha da planilha já convertida e validada
+type LinhaImportacao struct {
+	Linha    int              `json:"linha"`
+	Cliente  models.Cliente   `json:"cliente"`
+	Guardian *models.Guardian `json:"guardian,omitempty"`
+	Erros    []string         `json:"erros,omitempty"`
+}
+
+// ErroLinha descreve os problemas encontrados em uma linha
+type ErroLinha struct {
+	Linha int      `json:"linha"`
+	Erros []string `json:"erros"`
+}
+
+// JobImportacao acompanha o progresso de uma importação assíncrona
+type JobImportacao struct {
+	ID          string      `json:"id"`
+	Autor       string      `json:"autor"`
+	Status      string      `json:"status"`
+	Total       int         `json:"total"`
+	Processadas int         `json:"processadas"`
+	Importadas  int         `json:"importadas"`
+	Erros       []ErroLinha `json:"erros"`
+	CriadoEm    time.Time   `json:"criado_em"`
+	ConcluidoEm *time.Time  `json:"concluido_em,omitempty"`
+}
+
+// Jobs de importação mantidos em memória; jobsMu protege o mapa e os jobs
+var (
+	jobsMu sync.RWMutex
+	jobs   = map[string]*JobImportacao{}
+)
+
+// LerPlanilha lê um arquivo CSV ou XLSX e retorna as linhas, incluindo o cabeçalho
+func LerPlanilha(nomeArquivo string, conteudo []byte) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(nomeArquivo)) {
+	case ".csv":
+		leitor := csv.NewReader(bytes.NewReader(conteudo))
+		leitor.FieldsPerRecord = -1
+		leitor.TrimLeadingSpace = true
+
+		// Planilhas exportadas em português costumam usar ponto e vírgula
+		primeiraLinha, _, _ := strings.Cut(string(conteudo), "\n")
+		if strings.Count(primeiraLinha, ";") > strings.Count(primeiraLinha, ",") {
+			leitor.Comma = ';'
+		}
+		return leitor.ReadAll()
+
+	case ".xlsx":
+		arquivo, err := excelize.OpenReader(bytes.NewReader(conteudo))
+		if err != nil {
+			return nil, err
+		}
+		defer arquivo.Close()
+		return arquivo.GetRows(arquivo.GetSheetName(0))
+	}
+
+	return nil, ErrFormatoNaoSuportado
+}
+
+// ValidarImportacao converte as linhas em clientes usando o mapeamento
+// campo -> coluna e aplica as mesmas validações do cadastro individual.
+// Campos sem mapeamento usam o próprio nome do campo como cabeçalho.
+func ValidarImportacao(linhas [][]string, mapeamento map[string]string) ([]LinhaImportacao, error) {
+	if len(linhas) == 0 {
+		return nil, errors.New("arquivo vazio")
+	}
+
+	indices := map[string]int{}
+	for i, coluna := range linhas[0] {
+		indices[normalizarCabecalho(coluna)] = i
+	}
+
+	colunas := map[string]int{}
+	for _, campo := range CamposImportacao {
+		cabecalho := campo
+		if mapeado, ok := mapeamento[campo]; ok && mapeado != "" {
+			cabecalho = mapeado
+		}
+		if i, ok := indices[normalizarCabecalho(cabecalho)]; ok {
+			colunas[campo] = i
+		}
+	}
+
+	resultado := make([]LinhaImportacao, 0, len(linhas)-1)
+	cpfsArquivo := map[string]int{}
+	emailsArquivo := map[string]int{}
+
+	for n, registro := range linhas[1:] {
+		valor := func(campo string) string {
+			if i, ok := colunas[campo]; ok && i < len(registro) {
+				return strings.TrimSpace(registro[i])
+			}
+			return ""
+		}
+
+		// Ignorar linhas totalmente vazias
+		if strings.TrimSpace(strings.Join(registro, "")) == "" {
+			continue
+		}
+
+		linha := LinhaImportacao{Linha: n + 2}
+		linha.Cliente = models.Cliente{
+			Nome:     valor("nome"),
+			Genero:   valor("genero"),
+			Email:    strings.ToLower(valor("email")),
+			Telefone: valor("telefone"),
+			CPF:      valor("cpf"),
+			Endereco: valor("endereco"),
+			Cidade:   valor("cidade"),
+			Estado:   valor("estado"),
+			CEP:      valor("cep"),
+		}
+
+		if nascimento, ok := lerData(valor("data_nascimento")); ok {
+			linha.Cliente.DataNascimento = nascimento
+		} else {
+			linha.Erros = append(linha.Erros, fmt.Sprintf("data_nascimento inválida: %q", valor("data_nascimento")))
+		}
+
+		if err := utils.Validate.Struct(linha.Cliente); err != nil {
+			linha.Erros = append(linha.Erros, err.Error())
+		}
+		if linha.Cliente.CPF != "" && !utils.ValidarCPF(linha.Cliente.CPF) {
+			linha.Erros = append(linha.Erros, "cpf inválido")
+		}
+
+		// Colunas do responsável, obrigatórias para menores de idade
+		if valor("responsavel_nome") != "" || valor("responsavel_cpf") != "" {
+			linha.Guardian = &models.Guardian{
+				Nome:                  valor("responsavel_nome"),
+				Parentesco:            models.Parentesco(strings.ToLower(valor("responsavel_parentesco"))),
+				Telefone:              valor("responsavel_telefone"),
+				Email:                 strings.ToLower(valor("responsavel_email")),
+				CPF:                   valor("responsavel_cpf"),
+				ResponsavelFinanceiro: lerBool(valor("responsavel_financeiro")),
+				AutorizadoBuscar:      lerBool(valor("responsavel_autorizado_buscar")),
+			}
+			if err := utils.Validate.Struct(linha.Guardian); err != nil {
+				linha.Erros = append(linha.Erros, "responsável: "+err.Error())
+			}
+		}
+		if !linha.Cliente.DataNascimento.IsZero() && linha.Cliente.MenorDeIdade() && linha.Guardian == nil {
+			linha.Erros = append(linha.Erros, ErrResponsavelObrigatorio.Error())
+		}
+
+		// Duplicidade dentro do próprio arquivo
+		if cpf := utils.NormalizarCPF(linha.Cliente.CPF); cpf != "" {
+			if anterior, ok := cpfsArquivo[cpf]; ok {
+				linha.Erros = append(linha.Erros, fmt.Sprintf("cpf duplicado com a linha %d", anterior))
+			} else {
+				cpfsArquivo[cpf] = linha.Linha
+			}
+		}
+		if email := linha.Cliente.Email; email != "" {
+			if anterior, ok := emailsArquivo[email]; ok {
+				linha.Erros = append(linha.Erros, fmt.Sprintf("email duplicado com a linha %d", anterior))
+			} else {
+				emailsArquivo[email] = linha.Linha
+			}
+		}
+
+		resultado = append(resultado, linha)
+	}
+
+	if err := marcarDuplicadosNoBanco(resultado, cpfsArquivo, emailsArquivo); err != nil {
+		return nil, err
+	}
+
+	return resultado, nil
+}
+
+// Marca as linhas cujo CPF ou e-mail já pertence a um cliente cadastrado, com
+// o mesmo critério dos índices únicos. A comparação usa os índices cegos, já
+// que os campos são criptografados.
+func marcarDuplicadosNoBanco(linhas []LinhaImportacao, cpfs, emails map[string]int) error {
+	porLinha := map[int]*LinhaImportacao{}
+	for i := range linhas {
+		porLinha[linhas[i].Linha] = &linhas[i]
+	}
+
+	verificar := func(coluna string, valores map[string]int, indice func(string) string, mensagem string) error {
+		if len(valores) == 0 {
+			return nil
+		}
+
+		linhaPorHash := make(map[string]int, len(valores))
+		hashes := make([]string, 0, len(valores))
+		for valor, linha := range valores {
+			hash := indice(valor)
+			linhaPorHash[hash] = linha
+			hashes = append(hashes, hash)
+		}
+
+		var existentes []string
+		if err := config.DB.Model(&models.Cliente{}).Scopes(OcupamIndicesUnicos).
+			Where(coluna+" IN ?", hashes).
+			Pluck(coluna, &existentes).Error; err != nil {
+			return err
+		}
+		for _, hash := range existentes {
+			if linha, ok := porLinha[linhaPorHash[hash]]; ok {
+				linha.Erros = append(linha.Erros, mensagem)
+			}
+		}
+		return nil
+	}
+
+	if err := verificar("cpf_hash", cpfs, models.IndiceCPF, "cpf já cadastrado"); err != nil {
+		return err
+	}
+	return verificar("email_hash", emails, models.IndiceEmail, "email já cadastrado")
+}
+
+// IniciarImportacao cria os clientes válidos em segundo plano.
+// Linhas com erro são ignoradas e reportadas no job.
+func IniciarImportacao(linhas []LinhaImportacao, autor string) (JobImportacao, error) {
+	id, err := gonanoid.New()
+	if err != nil {
+		return JobImportacao{}, err
+	}
+
+	job := &JobImportacao{
+		ID:       id,
+		Autor:    autor,
+		Status:   ImportacaoPendente,
+		Total:    len(linhas),
+		Erros:    []ErroLinha{},
+		CriadoEm: time.Now(),
+	}
+
+	jobsMu.Lock()
+	jobs[id] = job
+	copia := *job
+	jobsMu.Unlock()
+
+	go job.executar(linhas)
+	return copia, nil
+}
+
+// BuscarImportacao retorna uma cópia do estado atual do job
+func BuscarImportacao(id string) (JobImportacao, bool) {
+	jobsMu.RLock()
+	defer jobsMu.RUnlock()
+
+	job, ok := jobs[id]
+	if !ok {
+		return JobImportacao{}, false
+	}
+
+	copia := *job
+	copia.Erros = append([]ErroLinha(nil), job.Erros...)
+	return copia, true
+}
+
+// RetencaoImportacao retorna por quanto tempo um job concluído continua
+// consultável, configurável em horas por IMPORTACAO_RETENCAO_HORAS (padrão: 24)
+func RetencaoImportacao() time.Duration {
+	horas := 24
+	if valor := os.Getenv("IMPORTACAO_RETENCAO_HORAS"); valor != "" {
+		if n, err := strconv.Atoi(valor); err == nil && n > 0 {
+			horas = n
+		}
+	}
+	return time.Duration(horas) * time.Hour
+}
+
+// LimparImportacoes remove da memória os jobs concluídos antes de limite e
+// retorna quantos foram removidos; jobs em andamento são mantidos
+func LimparImportacoes(limite time.Time) int {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	removidos := 0
+	for id, job := range jobs {
+		if job.ConcluidoEm != nil && job.ConcluidoEm.Before(limite) {
+			delete(jobs, id)
+			removidos++
+		}
+	}
+	return removidos
+}
+
+func (job *JobImportacao) executar(linhas []LinhaImportacao) {
+	jobsMu.Lock()
+	job.Status = ImportacaoProcessando
+	jobsMu.Unlock()
+
+	for _, linha := range linhas {
+		erros := linha.Erros
+		if len(erros) == 0 {
+			cliente := linha.Cliente
+			if linha.Guardian != nil {
+				cliente.Guardians = []models.Guardian{*linha.Guardian}
+			}
+			if err := CriarCliente(&cliente, nil, job.Autor); err != nil {
+				erros = []string{err.Error()}
+			}
+		}
+
+		jobsMu.Lock()
+		job.Processadas++
+		if len(erros) == 0 {
+			job.Importadas++
+		} else {
+			job.Erros = append(job.Erros, ErroLinha{Linha: linha.Linha, Erros: erros})
+		}
+		jobsMu.Unlock()
+	}
+
+	agora := time.Now()
+	jobsMu.Lock()
+	job.Status = ImportacaoConcluida
+	job.ConcluidoEm = &agora
+	jobsMu.Unlock()
+}
+
+func normalizarCabecalho(coluna string) string {
+	coluna = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(coluna, "\ufeff")))
+	return strings.ReplaceAll(coluna, " ", "_")
+}
+
+func lerData(valor string) (time.Time, bool) {
+	for _, formato := range formatosData {
+		if data, err := time.ParseInLocation(formato, valor, time.Local); err == nil {
+			return data, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func lerBool(valor string) bool {
+	switch strings.ToLower(valor) {
+	case "sim", "s", "true", "1", "x", "yes":
+		return true
+	}
+	return false
+}
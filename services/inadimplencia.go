@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+// RelatorioInadimplente resume a situação de um cliente inadimplente
+type RelatorioInadimplente struct {
+	Cliente             models.Cliente `json:"cliente"`
+	ValorEmAberto       float64        `json:"valor_em_aberto"`
+	VendasEmAtraso      int64          `json:"vendas_em_atraso"`
+	AssinaturasEmAtraso int64          `json:"assinaturas_em_atraso"`
+	InadimplenteDesde   *time.Time     `json:"inadimplente_desde"`
+}
+
+// CarenciaInadimplencia retorna o período de tolerância após o vencimento,
+// configurável em dias pela variável INADIMPLENCIA_CARENCIA_DIAS (padrão: 5)
+func CarenciaInadimplencia() time.Duration {
+	dias := 5
+	if valor := os.Getenv("INADIMPLENCIA_CARENCIA_DIAS"); valor != "" {
+		if n, err := strconv.Atoi(valor); err == nil && n >= 0 {
+			dias = n
+		}
+	}
+	return time.Duration(dias) * 24 * time.Hour
+}
+
+// Vendas não pagas cujo vencimento, somado à carência, já passou
+func vendasEmAtraso(tx *gorm.DB, limite time.Time) *gorm.DB {
+	return tx.Model(&models.Sale{}).
+		Where("pago = ? AND status NOT IN ?", false, []models.PaymentStatus{models.Paid, models.Cancelled}).
+		Where("vencimento < ?", limite)
+}
+
+// Assinaturas ativas marcadas como atrasadas há mais tempo que a carência
+func assinaturasEmAtraso(tx *gorm.DB, limite time.Time) *gorm.DB {
+	return tx.Model(&models.Subscription{}).
+		Where("active = ? AND payment_status = ?", true, models.Overdue).
+		Where("next_billing_date < ?", limite)
+}
+
+// ValorEmAberto soma as vendas e assinaturas em atraso do cliente
+func ValorEmAberto(tx *gorm.DB, clienteID string, ref time.Time) (total float64, vendas int64, assinaturas int64, err error) {
+	limite := ref.Add(-CarenciaInadimplencia())
+
+	var resultado struct {
+		Total      float64
+		Quantidade int64
+	}
+
+	if err = vendasEmAtraso(tx, limite).Where("cliente_id = ?", clienteID).
+		Select("COALESCE(SUM(valor), 0) AS total, COUNT(*) AS quantidade").
+		Scan(&resultado).Error; err != nil {
+		return
+	}
+	total, vendas = resultado.Total, resultado.Quantidade
+
+	resultado.Total, resultado.Quantidade = 0, 0
+	if err = assinaturasEmAtraso(tx, limite).Where("cliente_id = ?", clienteID).
+		Select("COALESCE(SUM(amount), 0) AS total, COUNT(*) AS quantidade").
+		Scan(&resultado).Error; err != nil {
+		return
+	}
+	total += resultado.Total
+	assinaturas = resultado.Quantidade
+	return
+}
+
+// RecalcularInadimplencia atualiza a flag de inadimplência do cliente a partir
+// das vendas e assinaturas em atraso, abrindo ou encerrando o período no histórico.
+func RecalcularInadimplencia(clienteID string) error {
+	if clienteID == "" {
+		return nil
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		agora := time.Now()
+		valor, vendas, assinaturas, err := ValorEmAberto(tx, clienteID, agora)
+		if err != nil {
+			return err
+		}
+		inadimplente := vendas > 0 || assinaturas > 0
+
+		if err := tx.Model(&models.Cliente{}).Where("id = ?", clienteID).
+			Update("flag_inadimplente", inadimplente).Error; err != nil {
+			return err
+		}
+
+		var periodo models.PeriodoInadimplencia
+		err = tx.Where("cliente_id = ? AND fim IS NULL", clienteID).First(&periodo).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		emAberto := err == nil
+
+		switch {
+		case inadimplente && !emAberto:
+			return tx.Create(&models.PeriodoInadimplencia{
+				ClienteID:     clienteID,
+				Inicio:        agora,
+				ValorEmAberto: valor,
+			}).Error
+		case inadimplente && valor > periodo.ValorEmAberto:
+			return tx.Model(&periodo).Update("valor_em_aberto", valor).Error
+		case !inadimplente && emAberto:
+			return tx.Model(&periodo).Update("fim", agora).Error
+		}
+		return nil
+	})
+}
+
+// RecalcularTodosInadimplentes reavalia os clientes marcados como inadimplentes
+// e os que passaram a ter pendências vencidas desde a última execução
+func RecalcularTodosInadimplentes() error {
+	limite := time.Now().Add(-CarenciaInadimplencia())
+
+	var ids []string
+	if err := config.DB.Model(&models.Cliente{}).
+		Where("flag_inadimplente = ?", true).
+		Or("id IN (?)", vendasEmAtraso(config.DB, limite).Select("cliente_id")).
+		Or("id IN (?)", assinaturasEmAtraso(config.DB, limite).Select("cliente_id")).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	var primeiroErro error
+	for _, id := range ids {
+		if err := RecalcularInadimplencia(id); err != nil && primeiroErro == nil {
+			primeiroErro = err
+		}
+	}
+	return primeiroErro
+}
+
+// ListarInadimplentes monta o relatório de clientes inadimplentes com o valor em aberto
+func ListarInadimplentes() ([]RelatorioInadimplente, error) {
+	var clientes []models.Cliente
+	if err := config.DB.Where("flag_inadimplente = ?", true).Order("nome").Find(&clientes).Error; err != nil {
+		return nil, err
+	}
+
+	agora := time.Now()
+	relatorio := make([]RelatorioInadimplente, 0, len(clientes))
+	for _, cliente := range clientes {
+		valor, vendas, assinaturas, err := ValorEmAberto(config.DB, cliente.ID, agora)
+		if err != nil {
+			return nil, err
+		}
+
+		item := RelatorioInadimplente{
+			Cliente:             cliente,
+			ValorEmAberto:       valor,
+			VendasEmAtraso:      vendas,
+			AssinaturasEmAtraso: assinaturas,
+		}
+
+		var periodo models.PeriodoInadimplencia
+		if err := config.DB.Where("cliente_id = ? AND fim IS NULL", cliente.ID).First(&periodo).Error; err == nil {
+			item.InadimplenteDesde = &periodo.Inicio
+		}
+
+		relatorio = append(relatorio, item)
+	}
+	return relatorio, nil
+}
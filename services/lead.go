@@ -0,0 +1,454 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+	"go-api/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrLeadNaoEncontrado             = errors.New("lead não encontrado")
+	ErrLeadEncerrado                 = errors.New("o lead já foi convertido ou perdido")
+	ErrTransicaoLeadInvalida         = errors.New("mudança de etapa do lead não permitida")
+	ErrMotivoPerdaObrigatorio        = errors.New("informe o motivo da perda")
+	ErrAulaLotada                    = errors.New("a aula não tem vagas nesta data")
+	ErrAulaExperimentalNaoEncontrada = errors.New("aula experimental não encontrada")
+	ErrAulaExperimentalJaRegistrada  = errors.New("a aula experimental já teve o comparecimento registrado")
+	ErrConversaoInvalida             = errors.New("dados inválidos para a conversão")
+	ErrResponsavelLeadInvalido       = errors.New("responsável pelo lead não encontrado")
+)
+
+// Etapas que podem ser definidas manualmente a partir de cada etapa. A
+// conversão só acontece por ConverterLead; agendamento e comparecimento
+// também avançam o funil automaticamente.
+var transicoesLead = map[models.StatusLead][]models.StatusLead{
+	models.LeadNovo:         {models.LeadAulaAgendada, models.LeadPerdido},
+	models.LeadAulaAgendada: {models.LeadNovo, models.LeadCompareceu, models.LeadPerdido},
+	models.LeadCompareceu:   {models.LeadAulaAgendada, models.LeadPerdido},
+	models.LeadPerdido:      {models.LeadNovo},
+}
+
+// ConversaoLead traz os dados que completam o cadastro do cliente e, se
+// informada, a primeira assinatura. Campos vazios são preenchidos com os do lead.
+type ConversaoLead struct {
+	Cliente    models.Cliente       `json:"cliente"`
+	Pais       *models.Pais         `json:"pais,omitempty"`
+	Assinatura *models.Subscription `json:"assinatura,omitempty"`
+}
+
+// FunilLeads resume os leads do período por etapa e por origem
+type FunilLeads struct {
+	De            string                      `json:"de"`
+	Ate           string                      `json:"ate"`
+	Total         int64                       `json:"total"`
+	PorStatus     map[models.StatusLead]int64 `json:"por_status"`
+	PorOrigem     map[models.OrigemLead]int64 `json:"por_origem"`
+	TaxaConversao float64                     `json:"taxa_conversao"` // Percentual de convertidos sobre o total
+}
+
+// CriarLead grava um novo lead no início do funil
+func CriarLead(lead *models.Lead, autor string) error {
+	lead.ID = "" // Ignorar o ID enviado e gerar um novo
+	lead.Status = models.LeadNovo
+	lead.MotivoPerda = ""
+	lead.ClienteID = nil
+	lead.ConvertidoEm = nil
+	lead.AulasExperimentais = nil
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := validarResponsavelLead(tx, lead.ResponsavelID); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(lead).Error; err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "lead", lead.ID, nil, "lead.criacao", autor,
+			map[string]interface{}{"origem": lead.Origem})
+	})
+}
+
+// AtualizarLead grava os dados de contato e interesse do lead. A etapa do
+// funil e a conversão são alteradas apenas pelas operações próprias.
+func AtualizarLead(lead *models.Lead, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		existente, err := bloquearLead(tx, lead.ID)
+		if err != nil {
+			return err
+		}
+		if err := validarResponsavelLead(tx, lead.ResponsavelID); err != nil {
+			return err
+		}
+
+		lead.Status = existente.Status
+		lead.MotivoPerda = existente.MotivoPerda
+		lead.ClienteID = existente.ClienteID
+		lead.ConvertidoEm = existente.ConvertidoEm
+		lead.CreatedAt = existente.CreatedAt
+		lead.AulasExperimentais = nil
+		if err := tx.Omit(clause.Associations).Save(lead).Error; err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "lead", lead.ID, nil, "lead.atualizacao", autor, nil)
+	})
+}
+
+// AlterarStatusLead move o lead no funil respeitando as transições permitidas
+func AlterarStatusLead(leadID string, status models.StatusLead, motivo, autor string) (*models.Lead, error) {
+	var lead *models.Lead
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if lead, err = bloquearLead(tx, leadID); err != nil {
+			return err
+		}
+		if !transicaoLeadPermitida(lead.Status, status) {
+			return fmt.Errorf("%w: de %s para %s", ErrTransicaoLeadInvalida, lead.Status, status)
+		}
+		if status == models.LeadPerdido && motivo == "" {
+			return ErrMotivoPerdaObrigatorio
+		}
+
+		anterior := lead.Status
+		lead.Status = status
+		lead.MotivoPerda = ""
+		if status == models.LeadPerdido {
+			lead.MotivoPerda = motivo
+			lead.ProximoContatoEm = nil
+		}
+		if err := tx.Model(lead).Updates(map[string]interface{}{
+			"status":             lead.Status,
+			"motivo_perda":       lead.MotivoPerda,
+			"proximo_contato_em": lead.ProximoContatoEm,
+		}).Error; err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "lead", lead.ID, nil, "lead.status", autor,
+			map[string]interface{}{"de": anterior, "para": status, "motivo": motivo})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lead, nil
+}
+
+// AgendarAulaExperimental reserva uma vaga para o lead em uma aula da grade e
+// agenda o contato de acompanhamento para o dia seguinte
+func AgendarAulaExperimental(leadID, aulaID string, data time.Time, autor string) (*models.AulaExperimental, error) {
+	dia := time.Date(data.Year(), data.Month(), data.Day(), 0, 0, 0, 0, time.Local)
+	experimental := &models.AulaExperimental{LeadID: leadID, AulaID: aulaID, Data: dia, Status: models.ExperimentalAgendada, AgendadaPor: autor}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		lead, err := bloquearLead(tx, leadID)
+		if err != nil {
+			return err
+		}
+		if lead.Encerrado() {
+			return ErrLeadEncerrado
+		}
+
+		// O bloqueio da aula serializa os agendamentos e matrículas concorrentes
+		aula, err := bloquearAula(tx, aulaID)
+		if err != nil {
+			return err
+		}
+		if aula.DiaSemana != int(dia.Weekday()) {
+			return ErrAulaForaDoDia
+		}
+		if excecao, err := AulaCancelada(tx, aula.ID, dia); err != nil {
+			return err
+		} else if excecao != nil {
+			return ErrAulaCancelada
+		}
+
+		ativas, err := contarMatriculasAtivas(tx, aula.ID)
+		if err != nil {
+			return err
+		}
+		var agendadas int64
+		if err := tx.Model(&models.AulaExperimental{}).
+			Where("aula_id = ? AND data = ? AND status = ?", aula.ID, dia.Format("2006-01-02"), models.ExperimentalAgendada).
+			Count(&agendadas).Error; err != nil {
+			return err
+		}
+		if ativas+int(agendadas) >= aula.Capacidade {
+			return ErrAulaLotada
+		}
+
+		if err := tx.Omit("Aula").Create(experimental).Error; err != nil {
+			return err
+		}
+		experimental.Aula = aula
+
+		acompanhamento := dia.AddDate(0, 0, 1)
+		if err := tx.Model(lead).Updates(map[string]interface{}{
+			"status":             models.LeadAulaAgendada,
+			"proximo_contato_em": acompanhamento,
+		}).Error; err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "lead", lead.ID, nil, "lead.aula_experimental", autor,
+			map[string]interface{}{"aula_id": aula.ID, "data": dia.Format("2006-01-02")})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return experimental, nil
+}
+
+// RegistrarAulaExperimental registra se o lead compareceu, faltou ou cancelou a
+// aula experimental. O comparecimento avança o lead no funil; falta e
+// cancelamento o devolvem para novo se não houver outra aula agendada.
+func RegistrarAulaExperimental(leadID, experimentalID string, status models.StatusAulaExperimental, autor string) (*models.AulaExperimental, error) {
+	var experimental models.AulaExperimental
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		lead, err := bloquearLead(tx, leadID)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&experimental, "id = ? AND lead_id = ?", experimentalID, leadID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAulaExperimentalNaoEncontrada
+			}
+			return err
+		}
+		if experimental.Status != models.ExperimentalAgendada {
+			return ErrAulaExperimentalJaRegistrada
+		}
+
+		if err := tx.Model(&experimental).Update("status", status).Error; err != nil {
+			return err
+		}
+		experimental.Status = status
+
+		if !lead.Encerrado() {
+			novoStatus := lead.Status
+			switch {
+			case status == models.ExperimentalRealizada:
+				novoStatus = models.LeadCompareceu
+			case lead.Status == models.LeadAulaAgendada:
+				var outras int64
+				if err := tx.Model(&models.AulaExperimental{}).
+					Where("lead_id = ? AND status = ?", leadID, models.ExperimentalAgendada).
+					Count(&outras).Error; err != nil {
+					return err
+				}
+				if outras == 0 {
+					novoStatus = models.LeadNovo
+				}
+			}
+			if novoStatus != lead.Status {
+				if err := tx.Model(lead).Update("status", novoStatus).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return RegistrarAuditoria(tx, "lead", leadID, nil, "lead.aula_experimental."+string(status), autor,
+			map[string]interface{}{"aula_experimental_id": experimental.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &experimental, nil
+}
+
+// ConverterLead cria o cliente e, se informada, a primeira assinatura a partir
+// dos dados do lead, tudo em uma transação, e encerra o lead como convertido
+func ConverterLead(leadID string, conversao *ConversaoLead, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		lead, err := bloquearLead(tx, leadID)
+		if err != nil {
+			return err
+		}
+		if lead.Encerrado() {
+			return ErrLeadEncerrado
+		}
+
+		cliente := &conversao.Cliente
+		preencherClienteDoLead(cliente, lead)
+		if err := utils.Validate.Struct(cliente); err != nil {
+			return fmt.Errorf("%w: %v", ErrConversaoInvalida, err)
+		}
+		for i := range cliente.Guardians {
+			if err := utils.Validate.Struct(cliente.Guardians[i]); err != nil {
+				return fmt.Errorf("%w: responsável: %v", ErrConversaoInvalida, err)
+			}
+		}
+		if err := criarCliente(tx, cliente, conversao.Pais, autor); err != nil {
+			return err
+		}
+
+		if assinatura := conversao.Assinatura; assinatura != nil {
+			assinatura.ID = ""
+			assinatura.ClienteID = cliente.ID
+			if err := preencherAssinaturaDoLead(tx, assinatura, lead); err != nil {
+				return err
+			}
+			if err := utils.Validate.Struct(assinatura); err != nil {
+				return fmt.Errorf("%w: assinatura: %v", ErrConversaoInvalida, err)
+			}
+			if err := criarAssinatura(tx, assinatura, autor); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(lead).Updates(map[string]interface{}{
+			"status":             models.LeadConvertido,
+			"cliente_id":         cliente.ID,
+			"convertido_em":      time.Now(),
+			"proximo_contato_em": nil,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AulaExperimental{}).Where("lead_id = ? AND status = ?", leadID, models.ExperimentalAgendada).
+			Update("status", models.ExperimentalCancelada).Error; err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "lead", lead.ID, &cliente.ID, "lead.conversao", autor,
+			map[string]interface{}{"cliente_id": cliente.ID, "origem": lead.Origem})
+	})
+}
+
+// LeadsParaContato retorna os leads em aberto com contato previsto até a data de referência
+func LeadsParaContato(ref time.Time) ([]models.Lead, error) {
+	var leads []models.Lead
+	err := config.DB.Preload("Responsavel").
+		Where("status NOT IN ? AND proximo_contato_em <= ?", []models.StatusLead{models.LeadConvertido, models.LeadPerdido}, ref.Format("2006-01-02")).
+		Order("proximo_contato_em, created_at").Find(&leads).Error
+	return leads, err
+}
+
+// AulasExperimentaisSemLembrete retorna as aulas experimentais agendadas para a
+// data informada cujo lembrete ainda não foi enviado ao lead
+func AulasExperimentaisSemLembrete(data time.Time) ([]models.AulaExperimental, error) {
+	var experimentais []models.AulaExperimental
+	err := config.DB.Preload("Aula").
+		Where("data = ? AND status = ? AND lembrete_enviado_em IS NULL", data.Format("2006-01-02"), models.ExperimentalAgendada).
+		Find(&experimentais).Error
+	return experimentais, err
+}
+
+// ResumoFunilLeads conta os leads criados no período por etapa e por origem
+func ResumoFunilLeads(de, ate time.Time) (*FunilLeads, error) {
+	funil := &FunilLeads{
+		De:        de.Format("2006-01-02"),
+		Ate:       ate.Format("2006-01-02"),
+		PorStatus: map[models.StatusLead]int64{},
+		PorOrigem: map[models.OrigemLead]int64{},
+	}
+
+	periodo := config.DB.Model(&models.Lead{}).Where("created_at >= ? AND created_at < ?", de, ate.AddDate(0, 0, 1))
+
+	var porStatus []struct {
+		Status models.StatusLead
+		Total  int64
+	}
+	if err := periodo.Session(&gorm.Session{}).Select("status, COUNT(*) AS total").Group("status").Scan(&porStatus).Error; err != nil {
+		return nil, err
+	}
+	for _, linha := range porStatus {
+		funil.PorStatus[linha.Status] = linha.Total
+		funil.Total += linha.Total
+	}
+
+	var porOrigem []struct {
+		Origem models.OrigemLead
+		Total  int64
+	}
+	if err := periodo.Session(&gorm.Session{}).Select("origem, COUNT(*) AS total").Group("origem").Scan(&porOrigem).Error; err != nil {
+		return nil, err
+	}
+	for _, linha := range porOrigem {
+		funil.PorOrigem[linha.Origem] = linha.Total
+	}
+
+	if funil.Total > 0 {
+		funil.TaxaConversao = float64(funil.PorStatus[models.LeadConvertido]) * 100 / float64(funil.Total)
+	}
+	return funil, nil
+}
+
+// Completa os campos do cliente não informados com os dados do lead
+func preencherClienteDoLead(cliente *models.Cliente, lead *models.Lead) {
+	if cliente.Nome == "" {
+		cliente.Nome = lead.Nome
+	}
+	if cliente.Email == "" {
+		cliente.Email = lead.Email
+	}
+	if cliente.Telefone == "" {
+		cliente.Telefone = lead.Telefone
+	}
+	if cliente.DataNascimento.IsZero() && lead.DataNascimento != nil {
+		cliente.DataNascimento = *lead.DataNascimento
+	}
+}
+
+// Sugere o plano de interesse do lead, com seu preço, e o dia de hoje para a cobrança
+func preencherAssinaturaDoLead(tx *gorm.DB, assinatura *models.Subscription, lead *models.Lead) error {
+	if assinatura.ProdutoID == nil {
+		assinatura.ProdutoID = lead.PlanoID
+	}
+	if assinatura.ProdutoID != nil && assinatura.Amount == 0 {
+		var plano models.ProdutoServico
+		if err := tx.First(&plano, "id = ?", *assinatura.ProdutoID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPlanoNaoEncontrado
+			}
+			return err
+		}
+		assinatura.Amount = plano.Produto.Preco
+	}
+	if assinatura.BillingDay == 0 {
+		assinatura.BillingDay = time.Now().Day()
+	}
+	// A comissão do plano vai para quem acompanhou o lead
+	if assinatura.VendedorID == nil {
+		assinatura.VendedorID = lead.ResponsavelID
+	}
+	return nil
+}
+
+// Carrega o lead com bloqueio de linha até o fim da transação
+func bloquearLead(tx *gorm.DB, leadID string) (*models.Lead, error) {
+	var lead models.Lead
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lead, "id = ?", leadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNaoEncontrado
+		}
+		return nil, err
+	}
+	return &lead, nil
+}
+
+func validarResponsavelLead(tx *gorm.DB, responsavelID *string) error {
+	if responsavelID == nil {
+		return nil
+	}
+	var total int64
+	if err := tx.Model(&models.User{}).Where("id = ?", *responsavelID).Count(&total).Error; err != nil {
+		return err
+	}
+	if total == 0 {
+		return ErrResponsavelLeadInvalido
+	}
+	return nil
+}
+
+func transicaoLeadPermitida(de, para models.StatusLead) bool {
+	for _, permitido := range transicoesLead[de] {
+		if permitido == para {
+			return true
+		}
+	}
+	return false
+}
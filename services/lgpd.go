@@ -0,0 +1,308 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+	"go-api/utils"
+
+	"gorm.io/gorm"
+)
+
+var ErrClienteAnonimizado = errors.New("cliente já foi anonimizado")
+
+// AssinaturaLGPD é a assinatura exportada com os dados do cartão mascarados
+type AssinaturaLGPD struct {
+	models.Subscription
+	CardNumber *string `json:"card_number,omitempty"`
+	CardCVV    *string `json:"card_cvv,omitempty"`
+
+	Congelamentos []models.CongelamentoAssinatura `json:"congelamentos"`
+}
+
+// PacoteLGPD reúne todos os dados pessoais mantidos sobre um titular
+type PacoteLGPD struct {
+	GeradoEm               time.Time                     `json:"gerado_em"`
+	Titular                models.Cliente                `json:"titular"`
+	Responsaveis           []models.Guardian             `json:"responsaveis"`
+	Pais                   *models.Pais                  `json:"pais"`
+	Vendas                 []models.Sale                 `json:"vendas"`
+	Assinaturas            []AssinaturaLGPD              `json:"assinaturas"`
+	HistoricoInadimplencia []models.PeriodoInadimplencia `json:"historico_inadimplencia"`
+	Notas                  []models.Nota                 `json:"notas"`
+	Tags                   []models.Tag                  `json:"tags"`
+	Saude                  *models.PerfilSaude           `json:"saude"`
+	ContatosEmergencia     []models.ContatoEmergencia    `json:"contatos_emergencia"`
+	Documentos             []models.Documento            `json:"documentos"`
+	Presencas              []models.Presenca             `json:"presencas"`
+	Graduacoes             []models.Graduacao            `json:"graduacoes"`
+	Matriculas             []models.Matricula            `json:"matriculas"`
+	Leads                  []models.Lead                 `json:"leads"`
+	InscricoesEventos      []models.InscricaoEvento      `json:"inscricoes_eventos"`
+	Reservas               []models.Reserva              `json:"reservas"`
+	Auditoria              []models.RegistroAuditoria    `json:"auditoria"`
+}
+
+// ExportarDadosLGPD monta o pacote com os dados do titular e registra a solicitação
+func ExportarDadosLGPD(clienteID, autor string) (*PacoteLGPD, error) {
+	pacote := &PacoteLGPD{GeradoEm: time.Now()}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&pacote.Titular, "id = ?", clienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+
+		if err := tx.Where("cliente_id = ?", clienteID).Order("created_at").Find(&pacote.Responsaveis).Error; err != nil {
+			return err
+		}
+
+		var pais models.Pais
+		if err := tx.Where("cliente_id = ?", clienteID).First(&pais).Error; err == nil {
+			pacote.Pais = &pais
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.Preload("Produto").Where("cliente_id = ?", clienteID).Order("criado_em").Find(&pacote.Vendas).Error; err != nil {
+			return err
+		}
+
+		var assinaturas []models.Subscription
+		if err := tx.Where("cliente_id = ?", clienteID).Order("criado_em").Find(&assinaturas).Error; err != nil {
+			return err
+		}
+		pacote.Assinaturas = make([]AssinaturaLGPD, len(assinaturas))
+		for i, assinatura := range assinaturas {
+			pacote.Assinaturas[i] = AssinaturaLGPD{
+				Subscription: assinatura,
+				CardNumber:   mascararCartao(assinatura.CardNumber),
+			}
+			if assinatura.CardCVV != nil {
+				cvv := "***"
+				pacote.Assinaturas[i].CardCVV = &cvv
+			}
+			if err := tx.Where("subscription_id = ?", assinatura.ID).Order("inicio").
+				Find(&pacote.Assinaturas[i].Congelamentos).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("cliente_id = ?", clienteID).Order("inicio").Find(&pacote.HistoricoInadimplencia).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("cliente_id = ?", clienteID).Order("created_at").Find(&pacote.Notas).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&pacote.Titular).Association("Tags").Find(&pacote.Tags); err != nil {
+			return err
+		}
+		var saude models.PerfilSaude
+		if err := tx.Where("cliente_id = ?", clienteID).First(&saude).Error; err == nil {
+			pacote.Saude = &saude
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Where("cliente_id = ?", clienteID).Order("prioridade").Find(&pacote.ContatosEmergencia).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("cliente_id = ?", clienteID).Order("created_at").Find(&pacote.Documentos).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("cliente_id = ?", clienteID).Order("data").Find(&pacote.Presencas).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Faixa").Where("cliente_id = ?", clienteID).Order("data").Find(&pacote.Graduacoes).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("cliente_id = ?", clienteID).Order("created_at").Find(&pacote.Matriculas).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("AulasExperimentais").Where("cliente_id = ?", clienteID).Order("created_at").Find(&pacote.Leads).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Evento").Where("cliente_id = ?", clienteID).Order("created_at").Find(&pacote.InscricoesEventos).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Recurso").Where("cliente_id = ?", clienteID).Order("inicio").Find(&pacote.Reservas).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("cliente_id = ?", clienteID).Order("criado_em").Find(&pacote.Auditoria).Error; err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "cliente", clienteID, &clienteID, "lgpd.exportacao", autor, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pacote, nil
+}
+
+// AnonimizarCliente remove de forma irreversível os dados pessoais do titular,
+// dos responsáveis, dos pais, dos cartões e da auditoria. Vendas e assinaturas
+// são mantidas com seus valores para fins contábeis.
+func AnonimizarCliente(clienteID, autor string) error {
+	var arquivos []string
+	var promovidas []models.Matricula
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var cliente models.Cliente
+		if err := tx.First(&cliente, "id = ?", clienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+		if cliente.AnonimizadoEm != nil {
+			return ErrClienteAnonimizado
+		}
+
+		// Apenas o ano de nascimento é mantido, para estatísticas por faixa etária
+		agora := time.Now()
+		nascimento := time.Date(cliente.DataNascimento.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		cliente.Nome = "Titular anonimizado"
+		cliente.Email = fmt.Sprintf("anonimizado-%s@anonimizado.invalid", clienteID)
+		cliente.Telefone = ""
+		cliente.CPF = ""
+		cliente.Endereco = ""
+		cliente.Cidade = ""
+		cliente.Estado = ""
+		cliente.CEP = ""
+		cliente.DataNascimento = nascimento
+		cliente.AnonimizadoEm = &agora
+
+		// Gravado pelo modelo para que o e-mail passe pelo serializer de
+		// criptografia e os índices cegos sejam recalculados em BeforeSave
+		if err := tx.Model(&cliente).Select("nome", "email", "email_hash", "telefone", "cpf", "cpf_hash",
+			"endereco", "cidade", "estado", "cep", "data_nascimento", "anonimizado_em").
+			Updates(&cliente).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Guardian{}).Where("cliente_id = ?", clienteID).Updates(map[string]interface{}{
+			"nome":     "Responsável anonimizado",
+			"telefone": "",
+			"email":    "",
+			"cpf":      "",
+			"cpf_hash": "",
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Pais{}).Where("cliente_id = ?", clienteID).Updates(map[string]interface{}{
+			"nome_pai":     "",
+			"telefone_pai": "",
+			"email_pai":    "",
+			"cpf_pai":      "",
+			"nome_mae":     "",
+			"telefone_mae": "",
+			"email_mae":    "",
+			"cpf_mae":      "",
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Subscription{}).Where("cliente_id = ?", clienteID).Updates(map[string]interface{}{
+			"card_number": nil,
+			"card_cvv":    nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		// O lead que originou o cliente guarda os mesmos dados de contato
+		if err := tx.Model(&models.Lead{}).Where("cliente_id = ?", clienteID).Updates(map[string]interface{}{
+			"nome":            "Lead anonimizado",
+			"email":           "",
+			"telefone":        "",
+			"data_nascimento": nil,
+			"interesse":       "",
+			"observacoes":     "",
+		}).Error; err != nil {
+			return err
+		}
+
+		// As inscrições em eventos guardam uma cópia dos dados do participante
+		if err := tx.Model(&models.InscricaoEvento{}).Where("cliente_id = ?", clienteID).Updates(map[string]interface{}{
+			"nome":            "Participante anonimizado",
+			"email":           "",
+			"telefone":        "",
+			"data_nascimento": nil,
+			"peso":            nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Notas livres podem conter dados pessoais e de saúde
+		if err := tx.Model(&models.Nota{}).Where("cliente_id = ?", clienteID).
+			Update("texto", "[removido pela LGPD]").Error; err != nil {
+			return err
+		}
+
+		// Dados de saúde e contatos de emergência não têm valor contábil e são removidos
+		if err := tx.Where("cliente_id = ?", clienteID).Delete(&models.PerfilSaude{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("cliente_id = ?", clienteID).Delete(&models.ContatoEmergencia{}).Error; err != nil {
+			return err
+		}
+
+		// O titular anonimizado deixa as aulas e libera as vagas
+		var err error
+		if promovidas, err = CancelarMatriculasCliente(tx, clienteID, "titular anonimizado"); err != nil {
+			return err
+		}
+		if err := CancelarReservasCliente(tx, clienteID, "titular anonimizado"); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Reserva{}).Where("cliente_id = ?", clienteID).Update("observacao", "").Error; err != nil {
+			return err
+		}
+
+		// Sem credencial, nenhum QR code do titular volta a ser aceito
+		if err := tx.Where("cliente_id = ?", clienteID).Delete(&models.CredencialCheckin{}).Error; err != nil {
+			return err
+		}
+
+		// Fotos, termos e autorizações identificam o titular; os arquivos são removidos após o commit
+		if arquivos, err = chavesDocumentos(tx, clienteID); err != nil {
+			return err
+		}
+		if err := tx.Where("cliente_id = ?", clienteID).Delete(&models.Documento{}).Error; err != nil {
+			return err
+		}
+
+		// Os dados dos registros de auditoria podem conter cópias dos dados pessoais
+		if err := tx.Model(&models.RegistroAuditoria{}).Where("cliente_id = ?", clienteID).
+			Update("dados", nil).Error; err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "cliente", clienteID, &clienteID, "lgpd.anonimizacao", autor, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	removerArquivos(arquivos)
+	notificarPromocoes(promovidas)
+	return nil
+}
+
+// Mantém apenas os quatro últimos dígitos do cartão criptografado
+func mascararCartao(criptografado *string) *string {
+	if criptografado == nil {
+		return nil
+	}
+
+	mascara := "****"
+	if numero, err := utils.Decrypt(*criptografado); err == nil && len(numero) >= 4 {
+		mascara = "**** **** **** " + string(numero[len(numero)-4:])
+	}
+	return &mascara
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go-api/db"
+	"go-api/events"
+	"go-api/models"
+	"go-api/notifications"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMatriculaDuplicada     = errors.New("o cliente já está matriculado ou na lista de espera desta aula")
+	ErrMatriculaNaoEncontrada = errors.New("matrícula não encontrada")
+	ErrFaixaEtariaInvalida    = errors.New("a idade mínima deve ser menor ou igual à idade máxima")
+)
+
+// Pendência de matrícula além das de assinatura usadas no check-in
+const PendenciaIdadeForaDaFaixa = "idade fora da faixa etária da aula"
+
+// MatriculaRecusadaError informa por que a matrícula foi recusada
+type MatriculaRecusadaError struct {
+	Pendencias []string
+}
+
+func (e *MatriculaRecusadaError) Error() string {
+	return "matrícula recusada: " + strings.Join(e.Pendencias, ", ")
+}
+
+// MatriculasAula reúne os matriculados e a lista de espera de uma aula
+type MatriculasAula struct {
+	Aula   models.Aula        `json:"aula"`
+	Vagas  int                `json:"vagas"`
+	Ativas []models.Matricula `json:"ativas"`
+	Espera []models.Matricula `json:"espera"`
+}
+
+// PendenciasMatricula lista o que impede o cliente de se matricular na aula:
+// assinatura ativa e em dia, plano com a modalidade e idade dentro da faixa
+func PendenciasMatricula(tx *gorm.DB, cliente *models.Cliente, aula *models.Aula, ref time.Time) ([]string, error) {
+	pendencias, err := pendenciasAssinatura(tx, cliente.ID, aula, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	idade := cliente.Idade(ref)
+	if (aula.IdadeMinima != nil && idade < *aula.IdadeMinima) || (aula.IdadeMaxima != nil && idade > *aula.IdadeMaxima) {
+		pendencias = append(pendencias, PendenciaIdadeForaDaFaixa)
+	}
+	return pendencias, nil
+}
+
+// MatricularCliente matricula o cliente na aula ou, se ela estiver lotada, o
+// coloca no fim da lista de espera. Com forcar a matrícula é aceita mesmo com
+// pendências, que ficam registradas nela.
+func MatricularCliente(aulaID, clienteID string, forcar bool, autor string) (*models.Matricula, error) {
+	matricula := &models.Matricula{AulaID: aulaID, ClienteID: clienteID}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// O bloqueio da aula serializa as matrículas concorrentes na contagem de vagas
+		aula, err := bloquearAula(tx, aulaID)
+		if err != nil {
+			return err
+		}
+
+		var cliente models.Cliente
+		if err := tx.Scopes(ApenasAtivos).Where("anonimizado_em IS NULL").First(&cliente, "id = ?", clienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+
+		var existente int64
+		if err := tx.Model(&models.Matricula{}).
+			Where("aula_id = ? AND cliente_id = ? AND status <> ?", aulaID, clienteID, models.MatriculaCancelada).
+			Count(&existente).Error; err != nil {
+			return err
+		}
+		if existente > 0 {
+			return ErrMatriculaDuplicada
+		}
+
+		pendencias, err := PendenciasMatricula(tx, &cliente, aula, time.Now())
+		if err != nil {
+			return err
+		}
+		if len(pendencias) > 0 && !forcar {
+			return &MatriculaRecusadaError{Pendencias: pendencias}
+		}
+		matricula.Pendencias = pendencias
+
+		ativas, err := contarMatriculasAtivas(tx, aulaID)
+		if err != nil {
+			return err
+		}
+		matricula.Status = models.MatriculaAtiva
+		if ativas >= aula.Capacidade {
+			matricula.Status = models.MatriculaEspera
+		}
+
+		if err := tx.Omit("Cliente", "Aula").Create(matricula).Error; err != nil {
+			return err
+		}
+		if matricula.Status == models.MatriculaEspera {
+			if matricula.PosicaoEspera, err = posicaoEspera(tx, matricula); err != nil {
+				return err
+			}
+		}
+
+		return RegistrarAuditoria(tx, "matricula", matricula.ID, &clienteID, "matricula.criacao", autor,
+			map[string]interface{}{"aula_id": aulaID, "status": matricula.Status, "pendencias": pendencias})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matricula, nil
+}
+
+// CancelarMatricula cancela a matrícula ou a vaga na lista de espera do cliente.
+// A vaga liberada é oferecida aos próximos da lista de espera.
+func CancelarMatricula(aulaID, clienteID, motivo, autor string) error {
+	var promovidas []models.Matricula
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Matrículas de aulas desativadas também podem ser canceladas
+		aula, err := bloquearAulaCadastrada(tx, aulaID)
+		if err != nil {
+			return err
+		}
+
+		var matricula models.Matricula
+		if err := tx.Where("aula_id = ? AND cliente_id = ? AND status <> ?", aulaID, clienteID, models.MatriculaCancelada).
+			First(&matricula).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMatriculaNaoEncontrada
+			}
+			return err
+		}
+
+		if err := tx.Model(&matricula).Updates(map[string]interface{}{
+			"status":              models.MatriculaCancelada,
+			"cancelada_em":        time.Now(),
+			"motivo_cancelamento": motivo,
+		}).Error; err != nil {
+			return err
+		}
+		if err := RegistrarAuditoria(tx, "matricula", matricula.ID, &clienteID, "matricula.cancelamento", autor,
+			map[string]interface{}{"aula_id": aulaID, "motivo": motivo}); err != nil {
+			return err
+		}
+
+		promovidas, err = promoverListaEspera(tx, aula)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	notificarPromocoes(promovidas)
+	return nil
+}
+
+// DesativarAula desativa a aula, preservando o histórico de frequência, e
+// cancela as matrículas e a lista de espera dela, avisando os alunos
+func DesativarAula(aulaID, autor string) error {
+	var canceladas []models.Matricula
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		aula, err := bloquearAulaCadastrada(tx, aulaID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(aula).Update("ativa", false).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Preload("Cliente").Preload("Aula").
+			Where("aula_id = ? AND status <> ?", aulaID, models.MatriculaCancelada).
+			Find(&canceladas).Error; err != nil {
+			return err
+		}
+		for _, matricula := range canceladas {
+			if err := tx.Model(&models.Matricula{}).Where("id = ?", matricula.ID).Updates(map[string]interface{}{
+				"status":              models.MatriculaCancelada,
+				"cancelada_em":        time.Now(),
+				"motivo_cancelamento": "aula desativada",
+			}).Error; err != nil {
+				return err
+			}
+			if err := RegistrarAuditoria(tx, "matricula", matricula.ID, &matricula.ClienteID, "matricula.cancelamento", autor,
+				map[string]interface{}{"aula_id": aulaID, "motivo": "aula desativada"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, matricula := range canceladas {
+		if matricula.Cliente == nil || matricula.Aula == nil {
+			continue
+		}
+		notifications.Enviar(notifications.Mensagem{
+			Destinatario: matricula.Cliente.Email,
+			Assunto:      "Aula encerrada",
+			Texto: fmt.Sprintf("Olá, %s! A aula %s (%s às %s) foi encerrada e sua matrícula foi cancelada.",
+				matricula.Cliente.Nome, matricula.Aula.Nome, nomeDiaSemana(matricula.Aula.DiaSemana), matricula.Aula.HoraInicio),
+		})
+	}
+	return nil
+}
+
+// CancelarMatriculasCliente cancela todas as matrículas do cliente, como no
+// arquivamento, e promove a lista de espera das aulas afetadas
+func CancelarMatriculasCliente(tx *gorm.DB, clienteID, motivo string) ([]models.Matricula, error) {
+	var matriculas []models.Matricula
+	if err := tx.Where("cliente_id = ? AND status <> ?", clienteID, models.MatriculaCancelada).
+		Find(&matriculas).Error; err != nil {
+		return nil, err
+	}
+
+	promovidas := []models.Matricula{}
+	for _, matricula := range matriculas {
+		aula, err := bloquearAula(tx, matricula.AulaID)
+		if err != nil && !errors.Is(err, ErrAulaNaoEncontrada) {
+			return nil, err
+		}
+
+		if err := tx.Model(&matricula).Updates(map[string]interface{}{
+			"status":              models.MatriculaCancelada,
+			"cancelada_em":        time.Now(),
+			"motivo_cancelamento": motivo,
+		}).Error; err != nil {
+			return nil, err
+		}
+
+		if aula != nil {
+			novas, err := promoverListaEspera(tx, aula)
+			if err != nil {
+				return nil, err
+			}
+			promovidas = append(promovidas, novas...)
+		}
+	}
+	return promovidas, nil
+}
+
+// ListarMatriculasAula retorna os matriculados e a lista de espera em ordem
+func ListarMatriculasAula(aulaID string) (*MatriculasAula, error) {
+	resultado := &MatriculasAula{Ativas: []models.Matricula{}, Espera: []models.Matricula{}}
+	if err := config.DB.Preload("Modalidade").First(&resultado.Aula, "id = ?", aulaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAulaNaoEncontrada
+		}
+		return nil, err
+	}
+
+	var matriculas []models.Matricula
+	if err := config.DB.Preload("Cliente").
+		Where("aula_id = ? AND status <> ?", aulaID, models.MatriculaCancelada).
+		Order("created_at, id").Find(&matriculas).Error; err != nil {
+		return nil, err
+	}
+
+	for _, matricula := range matriculas {
+		if matricula.Status == models.MatriculaAtiva {
+			resultado.Ativas = append(resultado.Ativas, matricula)
+			continue
+		}
+		matricula.PosicaoEspera = len(resultado.Espera) + 1
+		resultado.Espera = append(resultado.Espera, matricula)
+	}
+	resultado.Vagas = max(resultado.Aula.Capacidade-len(resultado.Ativas), 0)
+	return resultado, nil
+}
+
+// PromoverListaEspera oferece as vagas livres da aula à lista de espera, como
+// após um aumento de capacidade, e avisa os promovidos
+func PromoverListaEspera(aulaID string) ([]models.Matricula, error) {
+	var promovidas []models.Matricula
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		aula, err := bloquearAula(tx, aulaID)
+		if err != nil {
+			return err
+		}
+		promovidas, err = promoverListaEspera(tx, aula)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notificarPromocoes(promovidas)
+	return promovidas, nil
+}
+
+// Promove a lista de espera por ordem de inscrição enquanto houver vagas.
+// Quem deixou de atender aos requisitos é pulado e continua na fila.
+func promoverListaEspera(tx *gorm.DB, aula *models.Aula) ([]models.Matricula, error) {
+	promovidas := []models.Matricula{}
+
+	ativas, err := contarMatriculasAtivas(tx, aula.ID)
+	if err != nil {
+		return nil, err
+	}
+	vagas := aula.Capacidade - ativas
+	if vagas <= 0 || !aula.Ativa {
+		return promovidas, nil
+	}
+
+	var espera []models.Matricula
+	if err := tx.Preload("Cliente").Where("aula_id = ? AND status = ?", aula.ID, models.MatriculaEspera).
+		Order("created_at, id").Find(&espera).Error; err != nil {
+		return nil, err
+	}
+
+	agora := time.Now()
+	for _, matricula := range espera {
+		if vagas == 0 {
+			break
+		}
+		if matricula.Cliente == nil || matricula.Cliente.ArquivadoEm != nil {
+			continue
+		}
+		pendencias, err := PendenciasMatricula(tx, matricula.Cliente, aula, agora)
+		if err != nil {
+			return nil, err
+		}
+		if len(pendencias) > 0 {
+			continue
+		}
+
+		if err := tx.Model(&matricula).Updates(map[string]interface{}{
+			"status":       models.MatriculaAtiva,
+			"promovida_em": agora,
+		}).Error; err != nil {
+			return nil, err
+		}
+		if err := RegistrarAuditoria(tx, "matricula", matricula.ID, &matricula.ClienteID, "matricula.promocao", "sistema",
+			map[string]interface{}{"aula_id": aula.ID}); err != nil {
+			return nil, err
+		}
+
+		matricula.Status = models.MatriculaAtiva
+		matricula.PromovidaEm = &agora
+		matricula.Aula = aula
+		promovidas = append(promovidas, matricula)
+		vagas--
+	}
+	return promovidas, nil
+}
+
+// Publica o evento e avisa cada cliente que saiu da lista de espera
+func notificarPromocoes(promovidas []models.Matricula) {
+	for _, matricula := range promovidas {
+		if matricula.Cliente == nil || matricula.Aula == nil {
+			log.Printf("Matrícula %s promovida sem dados para notificação", matricula.ID)
+			continue
+		}
+
+		events.Publish(events.Evento{
+			Tipo:      events.MatriculaPromovida,
+			ClienteID: matricula.ClienteID,
+			Dados:     map[string]interface{}{"matricula_id": matricula.ID, "aula_id": matricula.AulaID},
+		})
+		notifications.Enviar(notifications.Mensagem{
+			Destinatario: matricula.Cliente.Email,
+			Assunto:      "Vaga confirmada na aula",
+			Texto: fmt.Sprintf("Olá, %s! Abriu uma vaga e sua matrícula na aula %s (%s às %s) foi confirmada.",
+				matricula.Cliente.Nome, matricula.Aula.Nome, nomeDiaSemana(matricula.Aula.DiaSemana), matricula.Aula.HoraInicio),
+		})
+	}
+}
+
+// Carrega a aula ativa com bloqueio de linha até o fim da transação
+func bloquearAula(tx *gorm.DB, aulaID string) (*models.Aula, error) {
+	return bloquearAulaCadastrada(tx.Where("ativa = ?", true), aulaID)
+}
+
+// Carrega a aula, ativa ou não, com bloqueio de linha até o fim da transação
+func bloquearAulaCadastrada(tx *gorm.DB, aulaID string) (*models.Aula, error) {
+	var aula models.Aula
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&aula, "id = ?", aulaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAulaNaoEncontrada
+		}
+		return nil, err
+	}
+	return &aula, nil
+}
+
+func contarMatriculasAtivas(tx *gorm.DB, aulaID string) (int, error) {
+	var total int64
+	if err := tx.Model(&models.Matricula{}).Where("aula_id = ? AND status = ?", aulaID, models.MatriculaAtiva).
+		Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return int(total), nil
+}
+
+func posicaoEspera(tx *gorm.DB, matricula *models.Matricula) (int, error) {
+	var antes int64
+	if err := tx.Model(&models.Matricula{}).
+		Where("aula_id = ? AND status = ? AND (created_at < ? OR (created_at = ? AND id < ?))",
+			matricula.AulaID, models.MatriculaEspera, matricula.CreatedAt, matricula.CreatedAt, matricula.ID).
+		Count(&antes).Error; err != nil {
+		return 0, err
+	}
+	return int(antes) + 1, nil
+}
+
+var diasSemana = []string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"}
+
+func nomeDiaSemana(dia int) string {
+	if dia < 0 || dia >= len(diasSemana) {
+		return ""
+	}
+	return diasSemana[dia]
+}
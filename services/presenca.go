@@ -0,0 +1,98 @@
+package services
+
+import (
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+// PresencaPorCliente resume a frequência de um cliente no período
+type PresencaPorCliente struct {
+	ClienteID      string    `json:"cliente_id"`
+	Nome           string    `json:"nome"`
+	Presencas      int64     `json:"presencas"`
+	Irregulares    int64     `json:"irregulares"`
+	UltimaPresenca time.Time `json:"ultima_presenca"`
+}
+
+// PresencaPorAula resume a frequência de uma aula no período
+type PresencaPorAula struct {
+	AulaID     string  `json:"aula_id"`
+	Nome       string  `json:"nome"`
+	Modalidade string  `json:"modalidade"`
+	DiaSemana  int     `json:"dia_semana"`
+	HoraInicio string  `json:"hora_inicio"`
+	Capacidade int     `json:"capacidade"`
+	Sessoes    int64   `json:"sessoes"`
+	Presencas  int64   `json:"presencas"`
+	Media      float64 `json:"media"`
+}
+
+// PresencaPorInstrutor resume as aulas ministradas por um instrutor no período
+type PresencaPorInstrutor struct {
+	InstrutorID      string `json:"instrutor_id"`
+	Email            string `json:"email"`
+	AulasMinistradas int64  `json:"aulas_ministradas"`
+	Presencas        int64  `json:"presencas"`
+	AlunosDiferentes int64  `json:"alunos_diferentes"`
+}
+
+// Presenças entre as datas de e ate, ambas inclusivas
+func presencasNoPeriodo(de, ate time.Time) *gorm.DB {
+	return config.DB.Model(&models.Presenca{}).
+		Where("presencas.data >= ? AND presencas.data <= ?", de.Format("2006-01-02"), ate.Format("2006-01-02"))
+}
+
+// RelatorioPresencaPorCliente retorna a frequência de cada cliente no período
+func RelatorioPresencaPorCliente(de, ate time.Time) ([]PresencaPorCliente, error) {
+	relatorio := []PresencaPorCliente{}
+	err := presencasNoPeriodo(de, ate).
+		Select(`presencas.cliente_id, clientes.nome, COUNT(*) AS presencas,
+			COUNT(*) FILTER (WHERE presencas.irregular) AS irregulares, MAX(presencas.data) AS ultima_presenca`).
+		Joins("JOIN clientes ON clientes.id = presencas.cliente_id").
+		Group("presencas.cliente_id, clientes.nome").
+		Order("presencas DESC, clientes.nome").
+		Scan(&relatorio).Error
+	return relatorio, err
+}
+
+// RelatorioPresencaPorAula retorna a frequência de cada aula no período,
+// com a média de alunos por sessão que teve check-in
+func RelatorioPresencaPorAula(de, ate time.Time) ([]PresencaPorAula, error) {
+	relatorio := []PresencaPorAula{}
+	err := presencasNoPeriodo(de, ate).
+		Select(`presencas.aula_id, aulas.nome, modalidades.nome AS modalidade, aulas.dia_semana, aulas.hora_inicio,
+			aulas.capacidade, COUNT(DISTINCT presencas.data) AS sessoes, COUNT(*) AS presencas`).
+		Joins("JOIN aulas ON aulas.id = presencas.aula_id").
+		Joins("JOIN modalidades ON modalidades.id = aulas.modalidade_id").
+		Group("presencas.aula_id, aulas.nome, modalidades.nome, aulas.dia_semana, aulas.hora_inicio, aulas.capacidade").
+		Order("aulas.dia_semana, aulas.hora_inicio").
+		Scan(&relatorio).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range relatorio {
+		if relatorio[i].Sessoes > 0 {
+			relatorio[i].Media = float64(relatorio[i].Presencas) / float64(relatorio[i].Sessoes)
+		}
+	}
+	return relatorio, nil
+}
+
+// RelatorioPresencaPorInstrutor retorna as aulas ministradas por cada instrutor no período.
+// Uma aula conta como ministrada quando teve ao menos um check-in na data.
+func RelatorioPresencaPorInstrutor(de, ate time.Time) ([]PresencaPorInstrutor, error) {
+	relatorio := []PresencaPorInstrutor{}
+	err := presencasNoPeriodo(de, ate).
+		Select(`presencas.instrutor_id, users.email, COUNT(DISTINCT (presencas.aula_id, presencas.data)) AS aulas_ministradas,
+			COUNT(*) AS presencas, COUNT(DISTINCT presencas.cliente_id) AS alunos_diferentes`).
+		Joins("JOIN users ON users.id = presencas.instrutor_id").
+		Group("presencas.instrutor_id, users.email").
+		Order("users.email").
+		Scan(&relatorio).Error
+	return relatorio, err
+}
@@ -0,0 +1,690 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrVendedorNaoEncontrado       = errors.New("vendedor não encontrado")
+	ErrRegraRemuneracaoInvalida    = errors.New("regra de remuneração inválida")
+	ErrRegraNaoEncontrada          = errors.New("regra de remuneração não encontrada")
+	ErrAulaFutura                  = errors.New("não é possível registrar uma aula em data futura")
+	ErrAulaMinistradaNaoEncontrada = errors.New("aula ministrada não encontrada")
+	ErrAulaMinistradaComPresencas  = errors.New("a aula tem check-ins e não pode ser removida")
+	ErrCompetenciaInvalida         = errors.New("competência inválida, use AAAA-MM")
+	ErrExtratoNaoEncontrado        = errors.New("extrato não encontrado")
+	ErrExtratoFechado              = errors.New("o extrato da competência já foi aprovado")
+	ErrTransicaoExtratoInvalida    = errors.New("mudança de status do extrato não permitida")
+)
+
+// Mudanças de status permitidas no extrato; aprovado volta a rascunho para correções
+var transicoesExtrato = map[models.StatusExtrato][]models.StatusExtrato{
+	models.ExtratoRascunho: {models.ExtratoAprovado},
+	models.ExtratoAprovado: {models.ExtratoRascunho, models.ExtratoPago},
+}
+
+// DadosAulaMinistrada registra uma aula dada ou a troca de instrutor em uma data
+type DadosAulaMinistrada struct {
+	Data        string `json:"data" validate:"required,datetime=2006-01-02"`
+	InstrutorID string `json:"instrutor_id"` // Vazio usa o instrutor da grade
+	Observacao  string `json:"observacao"`
+}
+
+// FiltroAulasMinistradas restringe a listagem de aulas ministradas
+type FiltroAulasMinistradas struct {
+	De          time.Time
+	Ate         time.Time
+	AulaID      string
+	InstrutorID string
+}
+
+// ValidarVendedor confere se o usuário informado como vendedor existe
+func ValidarVendedor(tx *gorm.DB, vendedorID *string) error {
+	if vendedorID == nil || *vendedorID == "" {
+		return nil
+	}
+	var total int64
+	if err := tx.Model(&models.User{}).Where("id = ?", *vendedorID).Count(&total).Error; err != nil {
+		return err
+	}
+	if total == 0 {
+		return ErrVendedorNaoEncontrado
+	}
+	return nil
+}
+
+// SalvarRegraRemuneracao valida e grava uma regra nova ou existente
+func SalvarRegraRemuneracao(regra *models.RegraRemuneracao, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var instrutor models.User
+		if err := tx.Select("id").First(&instrutor, "id = ?", regra.InstrutorID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInstrutorInvalido
+			}
+			return err
+		}
+		if regra.VigenteAte != nil && regra.VigenteAte.Before(regra.VigenteDesde) {
+			return fmt.Errorf("%w: o fim da vigência é anterior ao início", ErrRegraRemuneracaoInvalida)
+		}
+		if regra.Tipo == models.RemuneracaoComissao {
+			if regra.Valor > 100 {
+				return fmt.Errorf("%w: a comissão é um percentual de até 100", ErrRegraRemuneracaoInvalida)
+			}
+			if regra.ModalidadeID != nil {
+				return fmt.Errorf("%w: a comissão não é restrita a modalidades", ErrRegraRemuneracaoInvalida)
+			}
+		}
+		if regra.ModalidadeID != nil {
+			var modalidade models.Modalidade
+			if err := tx.Select("id").First(&modalidade, "id = ?", *regra.ModalidadeID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrModalidadeInvalida
+				}
+				return err
+			}
+		}
+
+		acao := "remuneracao.regra_atualizacao"
+		if regra.ID == "" {
+			acao = "remuneracao.regra_criacao"
+		}
+		if err := tx.Omit(clause.Associations).Save(regra).Error; err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "regra_remuneracao", regra.ID, nil, acao, autor,
+			map[string]interface{}{"instrutor_id": regra.InstrutorID, "tipo": regra.Tipo, "valor": regra.Valor})
+	})
+}
+
+// Registra a aula como ministrada no dia, se ainda não estiver, e retorna o
+// registro existente, que pode ter um instrutor substituto
+func registrarAulaMinistrada(tx *gorm.DB, aula *models.Aula, dia time.Time, autor string) (*models.AulaMinistrada, error) {
+	nova := models.AulaMinistrada{
+		AulaID:         aula.ID,
+		Data:           dia,
+		InstrutorID:    aula.InstrutorID,
+		DuracaoMinutos: duracaoAula(aula),
+		RegistradoPor:  autor,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations).Create(&nova).Error; err != nil {
+		return nil, err
+	}
+
+	var ministrada models.AulaMinistrada
+	if err := tx.First(&ministrada, "aula_id = ? AND data = ?", aula.ID, dia.Format("2006-01-02")).Error; err != nil {
+		return nil, err
+	}
+	return &ministrada, nil
+}
+
+// SalvarAulaMinistrada lança uma aula sem check-ins ou troca o instrutor de
+// uma aula já registrada. Os check-ins do dia passam para o novo instrutor.
+func SalvarAulaMinistrada(aulaID string, dados DadosAulaMinistrada, autor string) (*models.AulaMinistrada, error) {
+	dia, err := time.ParseInLocation("2006-01-02", dados.Data, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("data inválida: %w", err)
+	}
+	if dia.After(time.Now()) {
+		return nil, ErrAulaFutura
+	}
+
+	var ministrada *models.AulaMinistrada
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var aula models.Aula
+		if err := tx.First(&aula, "id = ?", aulaID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAulaNaoEncontrada
+			}
+			return err
+		}
+		if aula.DiaSemana != int(dia.Weekday()) {
+			return ErrAulaForaDoDia
+		}
+		if excecao, err := AulaCancelada(tx, aula.ID, dia); err != nil {
+			return err
+		} else if excecao != nil {
+			return ErrAulaCancelada
+		}
+
+		instrutorID := aula.InstrutorID
+		if dados.InstrutorID != "" {
+			var instrutor models.User
+			if err := tx.Select("id").First(&instrutor, "id = ?", dados.InstrutorID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrInstrutorInvalido
+				}
+				return err
+			}
+			instrutorID = &instrutor.ID
+		}
+
+		var err error
+		ministrada, err = registrarAulaMinistrada(tx, &aula, dia, autor)
+		if err != nil {
+			return err
+		}
+		anterior := ministrada.InstrutorID
+
+		// A troca mexe no extrato dos dois instrutores
+		for _, id := range []*string{anterior, instrutorID} {
+			if err := competenciaAberta(tx, id, dia); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(ministrada).Updates(map[string]interface{}{
+			"instrutor_id": instrutorID,
+			"observacao":   dados.Observacao,
+		}).Error; err != nil {
+			return err
+		}
+		ministrada.InstrutorID = instrutorID
+		ministrada.Observacao = dados.Observacao
+
+		if err := tx.Model(&models.Presenca{}).Where("aula_id = ? AND data = ?", aula.ID, dia.Format("2006-01-02")).
+			Update("instrutor_id", instrutorID).Error; err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "aula_ministrada", ministrada.ID, nil, "aula.ministrada", autor,
+			map[string]interface{}{"aula_id": aula.ID, "data": dados.Data, "instrutor_anterior": anterior, "instrutor": instrutorID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ministrada, nil
+}
+
+// RemoverAulaMinistrada exclui um lançamento feito por engano; aulas com
+// check-ins só saem junto com as presenças
+func RemoverAulaMinistrada(id, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var ministrada models.AulaMinistrada
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ministrada, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAulaMinistradaNaoEncontrada
+			}
+			return err
+		}
+
+		var presencas int64
+		if err := tx.Model(&models.Presenca{}).Where("aula_id = ? AND data = ?", ministrada.AulaID, ministrada.Data.Format("2006-01-02")).
+			Count(&presencas).Error; err != nil {
+			return err
+		}
+		if presencas > 0 {
+			return ErrAulaMinistradaComPresencas
+		}
+		if err := competenciaAberta(tx, ministrada.InstrutorID, ministrada.Data); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&ministrada).Error; err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "aula_ministrada", ministrada.ID, nil, "aula.ministrada_remocao", autor,
+			map[string]interface{}{"aula_id": ministrada.AulaID, "data": ministrada.Data.Format("2006-01-02")})
+	})
+}
+
+// ListarAulasMinistradas retorna as aulas dadas no período com a quantidade de check-ins
+func ListarAulasMinistradas(filtro FiltroAulasMinistradas) ([]models.AulaMinistrada, error) {
+	query := config.DB.Preload("Aula").Preload("Instrutor").
+		Where("data >= ? AND data <= ?", filtro.De.Format("2006-01-02"), filtro.Ate.Format("2006-01-02"))
+	if filtro.AulaID != "" {
+		query = query.Where("aula_id = ?", filtro.AulaID)
+	}
+	if filtro.InstrutorID != "" {
+		query = query.Where("instrutor_id = ?", filtro.InstrutorID)
+	}
+
+	var ministradas []models.AulaMinistrada
+	if err := query.Order("data, aula_id").Find(&ministradas).Error; err != nil {
+		return nil, err
+	}
+	if err := contarAlunos(config.DB, ministradas); err != nil {
+		return nil, err
+	}
+	return ministradas, nil
+}
+
+// CalcularExtrato monta (ou refaz) o rascunho do extrato do instrutor na
+// competência a partir das aulas ministradas e das vendas comissionadas
+func CalcularExtrato(instrutorID, competencia, autor string) (*models.ExtratoRemuneracao, error) {
+	inicio, err := inicioCompetencia(competencia)
+	if err != nil {
+		return nil, err
+	}
+	fim := inicio.AddDate(0, 1, 0)
+
+	var extrato models.ExtratoRemuneracao
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var instrutor models.User
+		if err := tx.Select("id").First(&instrutor, "id = ?", instrutorID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInstrutorInvalido
+			}
+			return err
+		}
+
+		// Cria o rascunho se ainda não existir e o bloqueia contra cálculos simultâneos
+		novo := models.ExtratoRemuneracao{InstrutorID: instrutorID, Competencia: competencia, Status: models.ExtratoRascunho}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations).Create(&novo).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&extrato, "instrutor_id = ? AND competencia = ?", instrutorID, competencia).Error; err != nil {
+			return err
+		}
+		if extrato.Status != models.ExtratoRascunho {
+			return ErrExtratoFechado
+		}
+		if err := tx.Where("extrato_id = ?", extrato.ID).Delete(&models.ItemExtrato{}).Error; err != nil {
+			return err
+		}
+
+		var regras []models.RegraRemuneracao
+		if err := tx.Where("instrutor_id = ? AND vigente_desde < ? AND (vigente_ate IS NULL OR vigente_ate >= ?)",
+			instrutorID, fim.Format("2006-01-02"), inicio.Format("2006-01-02")).
+			Order("vigente_desde DESC").Find(&regras).Error; err != nil {
+			return err
+		}
+
+		itens := []models.ItemExtrato{}
+		extrato.Aulas, extrato.Horas, extrato.Alunos = 0, 0, 0
+		extrato.ValorAulas, extrato.ValorComissoes = 0, 0
+
+		var ministradas []models.AulaMinistrada
+		if err := tx.Preload("Aula").Where("instrutor_id = ? AND data >= ? AND data < ?",
+			instrutorID, inicio.Format("2006-01-02"), fim.Format("2006-01-02")).
+			Order("data").Find(&ministradas).Error; err != nil {
+			return err
+		}
+		if err := contarAlunos(tx, ministradas); err != nil {
+			return err
+		}
+
+		for _, ministrada := range ministradas {
+			horas := float64(ministrada.DuracaoMinutos) / 60
+			extrato.Aulas++
+			extrato.Horas += horas
+			extrato.Alunos += ministrada.Alunos
+
+			quantidades := map[models.TipoRegraRemuneracao]float64{
+				models.RemuneracaoPorHora:  horas,
+				models.RemuneracaoPorAula:  1,
+				models.RemuneracaoPorAluno: float64(ministrada.Alunos),
+			}
+			for _, tipo := range []models.TipoRegraRemuneracao{models.RemuneracaoPorHora, models.RemuneracaoPorAula, models.RemuneracaoPorAluno} {
+				regra := regraAplicavel(regras, tipo, &ministrada.Aula.ModalidadeID, ministrada.Data)
+				if regra == nil || quantidades[tipo] == 0 {
+					continue
+				}
+				item := models.ItemExtrato{
+					RegraID:    &regra.ID,
+					Tipo:       tipo,
+					Referencia: ministrada.ID,
+					Data:       ministrada.Data,
+					Descricao:  fmt.Sprintf("%s (%s)", ministrada.Aula.Nome, ministrada.Data.Format("02/01")),
+					Quantidade: quantidades[tipo],
+					Base:       regra.Valor,
+					Valor:      arredondarCentavos(quantidades[tipo] * regra.Valor),
+				}
+				extrato.ValorAulas += item.Valor
+				itens = append(itens, item)
+			}
+		}
+
+		// Comissões: vendas pagas e planos vendidos no mês que seguem ativos e já foram pagos
+		var vendas []models.Sale
+		if err := tx.Preload("Produto").
+			Where("vendedor_id = ? AND criado_em >= ? AND criado_em < ?", instrutorID, inicio, fim).
+			Where("(pago = ? OR status = ?) AND status <> ?", true, models.Paid, models.Cancelled).
+			Order("criado_em").Find(&vendas).Error; err != nil {
+			return err
+		}
+		for _, venda := range vendas {
+			regra := regraAplicavel(regras, models.RemuneracaoComissao, nil, venda.CriadoEm)
+			if regra == nil {
+				continue
+			}
+			item := models.ItemExtrato{
+				RegraID:    &regra.ID,
+				Tipo:       models.RemuneracaoComissao,
+				Referencia: venda.ID,
+				Data:       venda.CriadoEm,
+				Descricao:  "Venda: " + venda.Produto.Nome,
+				Quantidade: 1,
+				Base:       venda.Valor,
+				Valor:      arredondarCentavos(venda.Valor * regra.Valor / 100),
+			}
+			extrato.ValorComissoes += item.Valor
+			itens = append(itens, item)
+		}
+
+		var assinaturas []models.Subscription
+		if err := tx.Where("vendedor_id = ? AND criado_em >= ? AND criado_em < ? AND active = ?", instrutorID, inicio, fim, true).
+			Where("payment_status = ?", models.Paid).
+			Order("criado_em").Find(&assinaturas).Error; err != nil {
+			return err
+		}
+		for _, assinatura := range assinaturas {
+			regra := regraAplicavel(regras, models.RemuneracaoComissao, nil, assinatura.CriadoEm)
+			if regra == nil {
+				continue
+			}
+			item := models.ItemExtrato{
+				RegraID:    &regra.ID,
+				Tipo:       models.RemuneracaoComissao,
+				Referencia: assinatura.ID,
+				Data:       assinatura.CriadoEm,
+				Descricao:  "Plano vendido",
+				Quantidade: 1,
+				Base:       assinatura.Amount,
+				Valor:      arredondarCentavos(assinatura.Amount * regra.Valor / 100),
+			}
+			extrato.ValorComissoes += item.Valor
+			itens = append(itens, item)
+		}
+
+		extrato.Horas = arredondarCentavos(extrato.Horas)
+		extrato.ValorAulas = arredondarCentavos(extrato.ValorAulas)
+		extrato.ValorComissoes = arredondarCentavos(extrato.ValorComissoes)
+		extrato.Total = arredondarCentavos(extrato.ValorAulas + extrato.ValorComissoes + extrato.Ajuste)
+		extrato.CalculadoEm = time.Now()
+		if err := tx.Omit(clause.Associations).Save(&extrato).Error; err != nil {
+			return err
+		}
+
+		for i := range itens {
+			itens[i].ExtratoID = extrato.ID
+		}
+		if len(itens) > 0 {
+			if err := tx.Create(&itens).Error; err != nil {
+				return err
+			}
+		}
+		extrato.Itens = itens
+
+		return RegistrarAuditoria(tx, "extrato_remuneracao", extrato.ID, nil, "remuneracao.calculo", autor,
+			map[string]interface{}{"instrutor_id": instrutorID, "competencia": competencia, "total": extrato.Total})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &extrato, nil
+}
+
+// CalcularExtratosCompetencia refaz os rascunhos de todos os instrutores com
+// regras vigentes ou aulas ministradas na competência; os já aprovados ficam
+// como estão
+func CalcularExtratosCompetencia(competencia, autor string) ([]models.ExtratoRemuneracao, error) {
+	inicio, err := inicioCompetencia(competencia)
+	if err != nil {
+		return nil, err
+	}
+	fim := inicio.AddDate(0, 1, 0)
+
+	var instrutores []string
+	if err := config.DB.Raw(`SELECT instrutor_id FROM regra_remuneracaos
+		WHERE vigente_desde < ? AND (vigente_ate IS NULL OR vigente_ate >= ?)
+		UNION
+		SELECT instrutor_id FROM aula_ministradas WHERE instrutor_id IS NOT NULL AND data >= ? AND data < ?`,
+		fim.Format("2006-01-02"), inicio.Format("2006-01-02"), inicio.Format("2006-01-02"), fim.Format("2006-01-02")).
+		Scan(&instrutores).Error; err != nil {
+		return nil, err
+	}
+
+	extratos := []models.ExtratoRemuneracao{}
+	for _, instrutorID := range instrutores {
+		extrato, err := CalcularExtrato(instrutorID, competencia, autor)
+		if errors.Is(err, ErrExtratoFechado) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		extratos = append(extratos, *extrato)
+	}
+	return extratos, nil
+}
+
+// AjustarExtrato lança um bônus (positivo) ou desconto (negativo) no rascunho
+func AjustarExtrato(extratoID string, ajuste float64, observacao, autor string) (*models.ExtratoRemuneracao, error) {
+	var extrato *models.ExtratoRemuneracao
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		extrato, err = bloquearExtrato(tx, extratoID)
+		if err != nil {
+			return err
+		}
+		if extrato.Status != models.ExtratoRascunho {
+			return ErrExtratoFechado
+		}
+
+		extrato.Ajuste = arredondarCentavos(ajuste)
+		extrato.ObservacaoAjuste = observacao
+		extrato.Total = arredondarCentavos(extrato.ValorAulas + extrato.ValorComissoes + extrato.Ajuste)
+		if err := tx.Model(extrato).Updates(map[string]interface{}{
+			"ajuste":            extrato.Ajuste,
+			"observacao_ajuste": observacao,
+			"total":             extrato.Total,
+		}).Error; err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "extrato_remuneracao", extrato.ID, nil, "remuneracao.ajuste", autor,
+			map[string]interface{}{"ajuste": extrato.Ajuste, "observacao": observacao})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return extrato, nil
+}
+
+// AlterarStatusExtrato aprova, reabre ou marca o extrato como pago
+func AlterarStatusExtrato(extratoID string, status models.StatusExtrato, autor string) (*models.ExtratoRemuneracao, error) {
+	var extrato *models.ExtratoRemuneracao
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		extrato, err = bloquearExtrato(tx, extratoID)
+		if err != nil {
+			return err
+		}
+
+		permitida := false
+		for _, proximo := range transicoesExtrato[extrato.Status] {
+			if proximo == status {
+				permitida = true
+			}
+		}
+		if !permitida {
+			return fmt.Errorf("%w: de %s para %s", ErrTransicaoExtratoInvalida, extrato.Status, status)
+		}
+
+		anterior := extrato.Status
+		agora := time.Now()
+		campos := map[string]interface{}{"status": status}
+		switch status {
+		case models.ExtratoAprovado:
+			campos["aprovado_por"], campos["aprovado_em"] = autor, agora
+			extrato.AprovadoPor, extrato.AprovadoEm = autor, &agora
+		case models.ExtratoRascunho:
+			campos["aprovado_por"], campos["aprovado_em"] = "", nil
+			extrato.AprovadoPor, extrato.AprovadoEm = "", nil
+		case models.ExtratoPago:
+			campos["pago_em"] = agora
+			extrato.PagoEm = &agora
+		}
+		if err := tx.Model(extrato).Updates(campos).Error; err != nil {
+			return err
+		}
+		extrato.Status = status
+
+		return RegistrarAuditoria(tx, "extrato_remuneracao", extrato.ID, nil, "remuneracao.status", autor,
+			map[string]interface{}{"de": anterior, "para": status, "total": extrato.Total})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return extrato, nil
+}
+
+// ExportarFolha escreve os extratos aprovados ou pagos da competência para a
+// folha de pagamento, uma linha por instrutor
+func ExportarFolha(competencia, formato string, w io.Writer) error {
+	if _, err := inicioCompetencia(competencia); err != nil {
+		return err
+	}
+	escritor, err := novoEscritor(formato, w)
+	if err != nil {
+		return err
+	}
+
+	var extratos []models.ExtratoRemuneracao
+	if err := config.DB.Joins("Instrutor").
+		Where("competencia = ? AND status IN ?", competencia, []models.StatusExtrato{models.ExtratoAprovado, models.ExtratoPago}).
+		Order(`"Instrutor"."email"`).Find(&extratos).Error; err != nil {
+		return err
+	}
+
+	colunas := []string{"instrutor_id", "instrutor_email", "competencia", "aulas", "horas", "alunos",
+		"valor_aulas", "valor_comissoes", "ajuste", "total", "status", "aprovado_por", "aprovado_em"}
+	if err := escritor.cabecalho(colunas); err != nil {
+		return err
+	}
+	for _, extrato := range extratos {
+		email, aprovadoEm := "", ""
+		if extrato.Instrutor != nil {
+			email = extrato.Instrutor.Email
+		}
+		if extrato.AprovadoEm != nil {
+			aprovadoEm = extrato.AprovadoEm.Format("2006-01-02")
+		}
+		valores := []interface{}{extrato.InstrutorID, email, extrato.Competencia, extrato.Aulas, extrato.Horas, extrato.Alunos,
+			extrato.ValorAulas, extrato.ValorComissoes, extrato.Ajuste, extrato.Total, string(extrato.Status), extrato.AprovadoPor, aprovadoEm}
+		if err := escritor.linha(colunas, valores); err != nil {
+			return err
+		}
+	}
+	return escritor.fechar()
+}
+
+// Escolhe a regra do tipo vigente na data. A regra da modalidade da aula tem
+// preferência sobre a geral; entre iguais vale a mais recente (as regras
+// chegam ordenadas pelo início da vigência, da mais nova para a mais antiga).
+func regraAplicavel(regras []models.RegraRemuneracao, tipo models.TipoRegraRemuneracao, modalidadeID *string, dia time.Time) *models.RegraRemuneracao {
+	var geral *models.RegraRemuneracao
+	for i := range regras {
+		regra := &regras[i]
+		if regra.Tipo != tipo || !regra.Vigente(dia) {
+			continue
+		}
+		if regra.ModalidadeID == nil {
+			if geral == nil {
+				geral = regra
+			}
+		} else if modalidadeID != nil && *regra.ModalidadeID == *modalidadeID {
+			return regra
+		}
+	}
+	return geral
+}
+
+// Preenche a quantidade de check-ins de cada aula ministrada
+func contarAlunos(tx *gorm.DB, ministradas []models.AulaMinistrada) error {
+	if len(ministradas) == 0 {
+		return nil
+	}
+	aulas := []string{}
+	de, ate := ministradas[0].Data, ministradas[0].Data
+	for _, ministrada := range ministradas {
+		aulas = append(aulas, ministrada.AulaID)
+		if ministrada.Data.Before(de) {
+			de = ministrada.Data
+		}
+		if ministrada.Data.After(ate) {
+			ate = ministrada.Data
+		}
+	}
+
+	var contagens []struct {
+		AulaID string
+		Data   time.Time
+		Total  int
+	}
+	if err := tx.Model(&models.Presenca{}).Select("aula_id, data, COUNT(*) AS total").
+		Where("aula_id IN ? AND data >= ? AND data <= ?", unicos(aulas), de.Format("2006-01-02"), ate.Format("2006-01-02")).
+		Group("aula_id, data").Scan(&contagens).Error; err != nil {
+		return err
+	}
+
+	totais := map[string]int{}
+	for _, contagem := range contagens {
+		totais[contagem.AulaID+"|"+contagem.Data.Format("2006-01-02")] = contagem.Total
+	}
+	for i := range ministradas {
+		ministradas[i].Alunos = totais[ministradas[i].AulaID+"|"+ministradas[i].Data.Format("2006-01-02")]
+	}
+	return nil
+}
+
+// Impede mudanças que alterariam um extrato já aprovado
+func competenciaAberta(tx *gorm.DB, instrutorID *string, dia time.Time) error {
+	if instrutorID == nil {
+		return nil
+	}
+	var fechados int64
+	if err := tx.Model(&models.ExtratoRemuneracao{}).
+		Where("instrutor_id = ? AND competencia = ? AND status <> ?", *instrutorID, dia.Format("2006-01"), models.ExtratoRascunho).
+		Count(&fechados).Error; err != nil {
+		return err
+	}
+	if fechados > 0 {
+		return ErrExtratoFechado
+	}
+	return nil
+}
+
+// Duração da aula da grade em minutos; os horários são "HH:MM"
+func duracaoAula(aula *models.Aula) int {
+	inicio, err1 := time.Parse("15:04", aula.HoraInicio)
+	fim, err2 := time.Parse("15:04", aula.HoraFim)
+	if err1 != nil || err2 != nil || !fim.After(inicio) {
+		return 0
+	}
+	return int(fim.Sub(inicio).Minutes())
+}
+
+func inicioCompetencia(competencia string) (time.Time, error) {
+	inicio, err := time.ParseInLocation("2006-01", competencia, time.Local)
+	if err != nil {
+		return time.Time{}, ErrCompetenciaInvalida
+	}
+	return inicio, nil
+}
+
+func arredondarCentavos(valor float64) float64 {
+	return math.Round(valor*100) / 100
+}
+
+// Carrega o extrato com bloqueio de linha até o fim da transação
+func bloquearExtrato(tx *gorm.DB, extratoID string) (*models.ExtratoRemuneracao, error) {
+	var extrato models.ExtratoRemuneracao
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&extrato, "id = ?", extratoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExtratoNaoEncontrado
+		}
+		return nil, err
+	}
+	return &extrato, nil
+}
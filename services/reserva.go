@@ -0,0 +1,492 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRecursoNaoEncontrado     = errors.New("recurso não encontrado")
+	ErrRecursoInativo           = errors.New("o recurso está desativado")
+	ErrRecursoDuplicado         = errors.New("já existe um recurso com este nome")
+	ErrJanelaReservaInvalida    = errors.New("o fim da janela deve ser posterior ao início")
+	ErrPeriodoReservaInvalido   = errors.New("a reserva deve terminar depois do início, no mesmo dia")
+	ErrReservaPassada           = errors.New("não é possível reservar um horário que já passou")
+	ErrReservaForaDaJanela      = errors.New("o horário está fora da disponibilidade do recurso")
+	ErrConflitoReserva          = errors.New("o recurso já está ocupado neste horário")
+	ErrConflitoReservaInstrutor = errors.New("o instrutor já tem outra reserva neste horário")
+	ErrReservaNaoEncontrada     = errors.New("reserva não encontrada")
+)
+
+// Restrições de exclusão criadas em InitDB e o erro correspondente
+var restricoesReserva = map[string]error{
+	"reservas_recurso_sem_conflito":   ErrConflitoReserva,
+	"reservas_instrutor_sem_conflito": ErrConflitoReservaInstrutor,
+}
+
+// SolicitacaoReserva traz os dados de uma nova reserva e do pagamento
+type SolicitacaoReserva struct {
+	RecursoID      string               `json:"recurso_id" validate:"required"`
+	ClienteID      string               `json:"cliente_id" validate:"required"`
+	InstrutorID    *string              `json:"instrutor_id"`
+	Inicio         time.Time            `json:"inicio" validate:"required"`
+	Fim            time.Time            `json:"fim" validate:"required"`
+	Observacao     string               `json:"observacao"`
+	FormaPagamento models.PaymentMethod `json:"forma_pagamento" validate:"omitempty,oneof=boleto pix debit_card credit_card"`
+	Pago           bool                 `json:"pago"`
+}
+
+// OcupacaoRecurso é um intervalo em que o recurso não está livre
+type OcupacaoRecurso struct {
+	Inicio    time.Time `json:"inicio"`
+	Fim       time.Time `json:"fim"`
+	Origem    string    `json:"origem"` // "reserva" ou "aula"
+	ReservaID string    `json:"reserva_id,omitempty"`
+	AulaID    string    `json:"aula_id,omitempty"`
+}
+
+// IntervaloLivre é um trecho de uma janela de disponibilidade sem ocupação
+type IntervaloLivre struct {
+	Inicio time.Time `json:"inicio"`
+	Fim    time.Time `json:"fim"`
+}
+
+// DisponibilidadeRecurso resume a agenda do recurso em uma data
+type DisponibilidadeRecurso struct {
+	Recurso  models.Recurso    `json:"recurso"`
+	Data     string            `json:"data"`
+	Ocupacao []OcupacaoRecurso `json:"ocupacao"`
+	Livre    []IntervaloLivre  `json:"livre"`
+}
+
+// SalvarRecurso valida e grava o recurso, substituindo as janelas de disponibilidade
+func SalvarRecurso(recurso *models.Recurso, autor string) error {
+	for _, janela := range recurso.Janelas {
+		if janela.HoraFim <= janela.HoraInicio {
+			return ErrJanelaReservaInvalida
+		}
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		acao := "recurso.criacao"
+		if recurso.ID != "" {
+			acao = "recurso.atualizacao"
+			var existente models.Recurso
+			if err := tx.Select("id", "created_at").First(&existente, "id = ?", recurso.ID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrRecursoNaoEncontrado
+				}
+				return err
+			}
+			recurso.CreatedAt = existente.CreatedAt
+		}
+
+		var homonimos int64
+		if err := tx.Model(&models.Recurso{}).Where("nome = ? AND id <> ?", recurso.Nome, recurso.ID).
+			Count(&homonimos).Error; err != nil {
+			return err
+		}
+		if homonimos > 0 {
+			return ErrRecursoDuplicado
+		}
+
+		if recurso.ProdutoID != nil {
+			var servico models.ProdutoServico
+			if err := tx.Select("id").First(&servico, "id = ?", *recurso.ProdutoID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrPlanoNaoEncontrado
+				}
+				return err
+			}
+		}
+
+		janelas := recurso.Janelas
+		if err := tx.Omit(clause.Associations).Save(recurso).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("recurso_id = ?", recurso.ID).Delete(&models.JanelaDisponibilidade{}).Error; err != nil {
+			return err
+		}
+		for i := range janelas {
+			janelas[i].ID = ""
+			janelas[i].RecursoID = recurso.ID
+		}
+		if len(janelas) > 0 {
+			if err := tx.Create(&janelas).Error; err != nil {
+				return err
+			}
+		}
+		recurso.Janelas = janelas
+
+		return RegistrarAuditoria(tx, "recurso", recurso.ID, nil, acao, autor,
+			map[string]interface{}{"nome": recurso.Nome, "janelas": len(janelas)})
+	})
+}
+
+// CriarReserva confere a disponibilidade do recurso e do instrutor, calcula o
+// preço pelo serviço associado e grava a reserva com a venda correspondente
+func CriarReserva(solicitacao SolicitacaoReserva, autor string) (*models.Reserva, error) {
+	inicio, fim := solicitacao.Inicio.In(time.Local), solicitacao.Fim.In(time.Local)
+	if !fim.After(inicio) || fim.Format("2006-01-02") != inicio.Format("2006-01-02") {
+		return nil, ErrPeriodoReservaInvalido
+	}
+	if inicio.Before(time.Now()) {
+		return nil, ErrReservaPassada
+	}
+
+	reserva := &models.Reserva{
+		RecursoID:   solicitacao.RecursoID,
+		ClienteID:   solicitacao.ClienteID,
+		InstrutorID: solicitacao.InstrutorID,
+		Inicio:      inicio,
+		Fim:         fim,
+		Status:      models.ReservaConfirmada,
+		Observacao:  solicitacao.Observacao,
+		CriadaPor:   autor,
+	}
+	if reserva.InstrutorID != nil && *reserva.InstrutorID == "" {
+		reserva.InstrutorID = nil
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		recurso, err := buscarRecurso(tx, reserva.RecursoID)
+		if err != nil {
+			return err
+		}
+		if !recurso.Ativo {
+			return ErrRecursoInativo
+		}
+
+		var cliente models.Cliente
+		if err := tx.Scopes(ApenasAtivos).Select("clientes.id").Where("clientes.anonimizado_em IS NULL").
+			First(&cliente, "clientes.id = ?", reserva.ClienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+		if reserva.InstrutorID != nil {
+			var instrutor models.User
+			if err := tx.Select("id").First(&instrutor, "id = ?", *reserva.InstrutorID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrInstrutorInvalido
+				}
+				return err
+			}
+		}
+
+		if !dentroDasJanelas(recurso.Janelas, inicio, fim) {
+			return ErrReservaForaDaJanela
+		}
+		if err := conflitosReserva(tx, recurso, reserva.InstrutorID, inicio, fim); err != nil {
+			return err
+		}
+
+		if recurso.ProdutoID != nil {
+			var servico models.ProdutoServico
+			if err := tx.First(&servico, "id = ?", *recurso.ProdutoID).Error; err != nil {
+				return err
+			}
+			reserva.Valor = servico.Produto.Preco
+			if recurso.CobrancaPorHora {
+				reserva.Valor = arredondarCentavos(servico.Produto.Preco * fim.Sub(inicio).Hours())
+			}
+
+			formaPagamento := solicitacao.FormaPagamento
+			if formaPagamento == "" {
+				formaPagamento = models.Pix
+			}
+			venda := models.Sale{
+				ProdutoID:      *recurso.ProdutoID,
+				Valor:          reserva.Valor,
+				Quantidade:     1,
+				ClienteID:      &reserva.ClienteID,
+				FormaPagamento: formaPagamento,
+				Pago:           solicitacao.Pago,
+				Vencimento:     &inicio,
+			}
+			if err := tx.Omit(clause.Associations).Create(&venda).Error; err != nil {
+				return err
+			}
+			reserva.VendaID = &venda.ID
+			reserva.Venda = &venda
+		}
+
+		if err := tx.Omit("Recurso", "Cliente", "Instrutor", "Venda").Create(reserva).Error; err != nil {
+			return traduzirErroReserva(err)
+		}
+		reserva.Recurso = recurso
+
+		return RegistrarAuditoria(tx, "reserva", reserva.ID, &reserva.ClienteID, "reserva.criacao", autor,
+			map[string]interface{}{"recurso_id": recurso.ID, "inicio": inicio, "fim": fim, "valor": reserva.Valor})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reserva, nil
+}
+
+// CancelarReserva libera o horário e cancela a venda, se ainda não paga
+func CancelarReserva(reservaID, motivo, autor string) (*models.Reserva, error) {
+	var reserva models.Reserva
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&reserva, "id = ? AND status = ?", reservaID, models.ReservaConfirmada).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReservaNaoEncontrada
+			}
+			return err
+		}
+		if err := cancelarReserva(tx, &reserva, motivo); err != nil {
+			return err
+		}
+		return RegistrarAuditoria(tx, "reserva", reserva.ID, &reserva.ClienteID, "reserva.cancelamento", autor,
+			map[string]interface{}{"motivo": motivo})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reserva, nil
+}
+
+// CancelarReservasCliente libera os horários futuros do cliente, como no
+// arquivamento ou na anonimização
+func CancelarReservasCliente(tx *gorm.DB, clienteID, motivo string) error {
+	var reservas []models.Reserva
+	if err := tx.Where("cliente_id = ? AND status = ? AND inicio > ?", clienteID, models.ReservaConfirmada, time.Now()).
+		Find(&reservas).Error; err != nil {
+		return err
+	}
+	for i := range reservas {
+		if err := cancelarReserva(tx, &reservas[i], motivo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConsultarDisponibilidade lista as ocupações do recurso na data e os
+// trechos livres das janelas de disponibilidade
+func ConsultarDisponibilidade(recursoID string, dia time.Time) (*DisponibilidadeRecurso, error) {
+	recurso, err := buscarRecurso(config.DB, recursoID)
+	if err != nil {
+		return nil, err
+	}
+	inicioDia := time.Date(dia.Year(), dia.Month(), dia.Day(), 0, 0, 0, 0, time.Local)
+	fimDia := inicioDia.AddDate(0, 0, 1)
+
+	ocupacao, err := ocupacoesRecurso(config.DB, recurso, inicioDia, fimDia)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sem janelas o recurso pode ser reservado o dia todo
+	janelas := []IntervaloLivre{}
+	for _, janela := range recurso.Janelas {
+		if janela.DiaSemana == int(inicioDia.Weekday()) {
+			janelas = append(janelas, IntervaloLivre{Inicio: horarioNoDia(inicioDia, janela.HoraInicio), Fim: horarioNoDia(inicioDia, janela.HoraFim)})
+		}
+	}
+	if len(recurso.Janelas) == 0 {
+		janelas = append(janelas, IntervaloLivre{Inicio: inicioDia, Fim: fimDia})
+	}
+	sort.Slice(janelas, func(i, j int) bool { return janelas[i].Inicio.Before(janelas[j].Inicio) })
+
+	livre := []IntervaloLivre{}
+	for _, janela := range janelas {
+		livre = append(livre, subtrairOcupacao(janela, ocupacao)...)
+	}
+
+	return &DisponibilidadeRecurso{Recurso: *recurso, Data: inicioDia.Format("2006-01-02"), Ocupacao: ocupacao, Livre: livre}, nil
+}
+
+// Reservas confirmadas e, para salas, as aulas da grade que usam o recurso no intervalo
+func ocupacoesRecurso(tx *gorm.DB, recurso *models.Recurso, inicio, fim time.Time) ([]OcupacaoRecurso, error) {
+	var reservas []models.Reserva
+	if err := tx.Where("recurso_id = ? AND status = ? AND inicio < ? AND fim > ?", recurso.ID, models.ReservaConfirmada, fim, inicio).
+		Order("inicio").Find(&reservas).Error; err != nil {
+		return nil, err
+	}
+	ocupacao := []OcupacaoRecurso{}
+	for _, reserva := range reservas {
+		ocupacao = append(ocupacao, OcupacaoRecurso{Inicio: reserva.Inicio, Fim: reserva.Fim, Origem: "reserva", ReservaID: reserva.ID})
+	}
+
+	if recurso.Tipo == models.RecursoSala {
+		for dia := time.Date(inicio.Year(), inicio.Month(), inicio.Day(), 0, 0, 0, 0, time.Local); dia.Before(fim); dia = dia.AddDate(0, 0, 1) {
+			aulas, err := aulasNoHorario(tx, "sala", recurso.Nome, dia, inicio, fim)
+			if err != nil {
+				return nil, err
+			}
+			for _, aula := range aulas {
+				ocupacao = append(ocupacao, OcupacaoRecurso{
+					Inicio: horarioNoDia(dia, aula.HoraInicio), Fim: horarioNoDia(dia, aula.HoraFim), Origem: "aula", AulaID: aula.ID,
+				})
+			}
+		}
+	}
+
+	sort.Slice(ocupacao, func(i, j int) bool { return ocupacao[i].Inicio.Before(ocupacao[j].Inicio) })
+	return ocupacao, nil
+}
+
+// Confere reservas e aulas da grade que ocupariam o recurso ou o instrutor.
+// A restrição de exclusão garante o mesmo para reservas concorrentes.
+func conflitosReserva(tx *gorm.DB, recurso *models.Recurso, instrutorID *string, inicio, fim time.Time) error {
+	ocupacao, err := ocupacoesRecurso(tx, recurso, inicio, fim)
+	if err != nil {
+		return err
+	}
+	if len(ocupacao) > 0 {
+		return ErrConflitoReserva
+	}
+
+	if instrutorID == nil {
+		return nil
+	}
+	var reservas int64
+	if err := tx.Model(&models.Reserva{}).
+		Where("instrutor_id = ? AND status = ? AND inicio < ? AND fim > ?", *instrutorID, models.ReservaConfirmada, fim, inicio).
+		Count(&reservas).Error; err != nil {
+		return err
+	}
+	if reservas > 0 {
+		return ErrConflitoReservaInstrutor
+	}
+	dia := time.Date(inicio.Year(), inicio.Month(), inicio.Day(), 0, 0, 0, 0, time.Local)
+	aulas, err := aulasNoHorario(tx, "instrutor_id", *instrutorID, dia, inicio, fim)
+	if err != nil {
+		return err
+	}
+	if len(aulas) > 0 {
+		return ErrConflitoInstrutor
+	}
+	return nil
+}
+
+// Aulas ativas com a coluna igual ao valor (sala ou instrutor) que acontecem no
+// dia e se sobrepõem ao intervalo, desconsiderando as canceladas na agenda
+func aulasNoHorario(tx *gorm.DB, coluna, valor string, dia, inicio, fim time.Time) ([]models.Aula, error) {
+	de, ate := "00:00", "24:00"
+	if inicio.After(dia) {
+		de = inicio.Format("15:04")
+	}
+	if fim.Format("2006-01-02") == dia.Format("2006-01-02") {
+		ate = fim.Format("15:04")
+	}
+
+	var aulas []models.Aula
+	if err := tx.Where(coluna+" = ?", valor).
+		Where("ativa = ? AND dia_semana = ? AND hora_inicio < ? AND hora_fim > ?", true, int(dia.Weekday()), ate, de).
+		Find(&aulas).Error; err != nil {
+		return nil, err
+	}
+
+	naoCanceladas := []models.Aula{}
+	for _, aula := range aulas {
+		excecao, err := AulaCancelada(tx, aula.ID, dia)
+		if err != nil {
+			return nil, err
+		}
+		if excecao == nil {
+			naoCanceladas = append(naoCanceladas, aula)
+		}
+	}
+	return naoCanceladas, nil
+}
+
+func cancelarReserva(tx *gorm.DB, reserva *models.Reserva, motivo string) error {
+	agora := time.Now()
+	if err := tx.Model(reserva).Updates(map[string]interface{}{
+		"status":              models.ReservaCancelada,
+		"cancelada_em":        agora,
+		"motivo_cancelamento": motivo,
+	}).Error; err != nil {
+		return err
+	}
+	reserva.Status = models.ReservaCancelada
+	reserva.CanceladaEm = &agora
+	reserva.MotivoCancelamento = motivo
+	return cancelarVendaPendente(tx, reserva.VendaID)
+}
+
+// Cancela a venda vinculada se ainda não foi paga; as pagas ficam para reembolso
+func cancelarVendaPendente(tx *gorm.DB, vendaID *string) error {
+	if vendaID == nil {
+		return nil
+	}
+	return tx.Model(&models.Sale{}).Where("id = ? AND pago = ?", *vendaID, false).
+		Updates(map[string]interface{}{"status": models.Cancelled, "atualizado_em": time.Now()}).Error
+}
+
+// Sem janelas cadastradas o recurso aceita qualquer horário
+func dentroDasJanelas(janelas []models.JanelaDisponibilidade, inicio, fim time.Time) bool {
+	if len(janelas) == 0 {
+		return true
+	}
+	de, ate := inicio.Format("15:04"), fim.Format("15:04")
+	for _, janela := range janelas {
+		if janela.DiaSemana == int(inicio.Weekday()) && janela.HoraInicio <= de && janela.HoraFim >= ate {
+			return true
+		}
+	}
+	return false
+}
+
+// Partes da janela que não se sobrepõem a nenhuma ocupação (ordenadas por início)
+func subtrairOcupacao(janela IntervaloLivre, ocupacao []OcupacaoRecurso) []IntervaloLivre {
+	livre := []IntervaloLivre{}
+	cursor := janela.Inicio
+	for _, ocupado := range ocupacao {
+		if !ocupado.Fim.After(cursor) || !ocupado.Inicio.Before(janela.Fim) {
+			continue
+		}
+		if ocupado.Inicio.After(cursor) {
+			livre = append(livre, IntervaloLivre{Inicio: cursor, Fim: ocupado.Inicio})
+		}
+		cursor = ocupado.Fim
+	}
+	if cursor.Before(janela.Fim) {
+		livre = append(livre, IntervaloLivre{Inicio: cursor, Fim: janela.Fim})
+	}
+	return livre
+}
+
+// Converte um horário "HH:MM" da grade em instante na data
+func horarioNoDia(dia time.Time, horario string) time.Time {
+	hora, err := time.Parse("15:04", horario)
+	if err != nil {
+		return dia
+	}
+	return time.Date(dia.Year(), dia.Month(), dia.Day(), hora.Hour(), hora.Minute(), 0, 0, time.Local)
+}
+
+// Converte a violação das restrições de exclusão no erro de conflito
+func traduzirErroReserva(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23P01" {
+		if conflito, ok := restricoesReserva[pgErr.ConstraintName]; ok {
+			return conflito
+		}
+	}
+	return err
+}
+
+func buscarRecurso(tx *gorm.DB, recursoID string) (*models.Recurso, error) {
+	var recurso models.Recurso
+	if err := tx.Preload("Janelas").First(&recurso, "id = ?", recursoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecursoNaoEncontrado
+		}
+		return nil, err
+	}
+	return &recurso, nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+// RelatorioAtestado aponta um cliente com assinatura ativa e atestado ausente ou vencido
+type RelatorioAtestado struct {
+	Cliente           models.Cliente `json:"cliente"`
+	AtestadoValidoAte *time.Time     `json:"atestado_valido_ate"`
+	DiasVencido       *int           `json:"dias_vencido"`
+}
+
+// DiasAvisoAtestado retorna com quantos dias de antecedência o vencimento do
+// atestado é avisado, configurável em ATESTADO_AVISO_DIAS (padrão: 15)
+func DiasAvisoAtestado() int {
+	if valor := os.Getenv("ATESTADO_AVISO_DIAS"); valor != "" {
+		if dias, err := strconv.Atoi(valor); err == nil && dias >= 0 {
+			return dias
+		}
+	}
+	return 15
+}
+
+// BuscarPerfilSaude retorna o perfil de saúde e registra a leitura na auditoria
+func BuscarPerfilSaude(clienteID, autor string) (*models.PerfilSaude, error) {
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", clienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClienteNaoEncontrado
+		}
+		return nil, err
+	}
+
+	perfil := models.PerfilSaude{ClienteID: clienteID}
+	if err := config.DB.Where("cliente_id = ?", clienteID).First(&perfil).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := RegistrarAuditoria(config.DB, "saude", clienteID, &clienteID, "saude.leitura", autor, nil); err != nil {
+		return nil, err
+	}
+	return &perfil, nil
+}
+
+// SalvarPerfilSaude cria ou substitui o perfil de saúde do cliente
+func SalvarPerfilSaude(perfil *models.PerfilSaude, autor string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var cliente models.Cliente
+		if err := tx.First(&cliente, "id = ?", perfil.ClienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+
+		var existente models.PerfilSaude
+		if err := tx.Where("cliente_id = ?", perfil.ClienteID).First(&existente).Error; err == nil {
+			perfil.CreatedAt = existente.CreatedAt
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		perfil.AtualizadoPor = autor
+		if err := tx.Save(perfil).Error; err != nil {
+			return err
+		}
+
+		// Os dados médicos não vão para a auditoria, apenas a validade do atestado
+		return RegistrarAuditoria(tx, "saude", perfil.ClienteID, &perfil.ClienteID, "saude.atualizacao", autor,
+			map[string]interface{}{"atestado_valido_ate": perfil.AtestadoValidoAte})
+	})
+}
+
+// Clientes não arquivados com assinatura ativa, ou seja, que estão treinando
+func clientesTreinando(tx *gorm.DB) *gorm.DB {
+	return tx.Scopes(ApenasAtivos).
+		Where("EXISTS (SELECT 1 FROM subscriptions s WHERE s.cliente_id = clientes.id AND s.active = ?)", true)
+}
+
+// ListarAtestadosVencidos retorna os clientes treinando com atestado vencido
+// e, opcionalmente, os que nunca apresentaram atestado
+func ListarAtestadosVencidos(ref time.Time, incluirSemAtestado bool) ([]RelatorioAtestado, error) {
+	condicao := "ps.atestado_valido_ate < ?"
+	if incluirSemAtestado {
+		condicao = "(ps.atestado_valido_ate IS NULL OR ps.atestado_valido_ate < ?)"
+	}
+
+	var clientes []models.Cliente
+	if err := clientesTreinando(config.DB.Model(&models.Cliente{})).
+		Joins("LEFT JOIN perfil_saudes ps ON ps.cliente_id = clientes.id").
+		Where(condicao, ref).
+		Order("clientes.nome").
+		Preload("PerfilSaude").
+		Find(&clientes).Error; err != nil {
+		return nil, err
+	}
+
+	relatorio := make([]RelatorioAtestado, 0, len(clientes))
+	for _, cliente := range clientes {
+		item := RelatorioAtestado{Cliente: cliente}
+		if cliente.PerfilSaude != nil && cliente.PerfilSaude.AtestadoValidoAte != nil {
+			validade := *cliente.PerfilSaude.AtestadoValidoAte
+			dias := int(math.Floor(ref.Sub(validade).Hours() / 24))
+			item.AtestadoValidoAte = &validade
+			item.DiasVencido = &dias
+		}
+		relatorio = append(relatorio, item)
+	}
+	return relatorio, nil
+}
+
+// AtestadosAVencer retorna os perfis de clientes treinando cujo atestado vence
+// exatamente em uma das datas de aviso (hoje ou daqui a DiasAvisoAtestado dias)
+func AtestadosAVencer(ref time.Time) ([]models.PerfilSaude, error) {
+	hoje := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, ref.Location())
+	aviso := hoje.AddDate(0, 0, DiasAvisoAtestado())
+
+	var perfis []models.PerfilSaude
+	err := config.DB.
+		Where("cliente_id IN (?)", clientesTreinando(config.DB.Model(&models.Cliente{})).Select("clientes.id")).
+		Where("(atestado_valido_ate >= ? AND atestado_valido_ate < ?) OR (atestado_valido_ate >= ? AND atestado_valido_ate < ?)",
+			hoje, hoje.AddDate(0, 0, 1), aviso, aviso.AddDate(0, 0, 1)).
+		Find(&perfis).Error
+	return perfis, err
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-api/db"
+	"go-api/models"
+	"go-api/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTagInvalida = errors.New("tag inválida")
+
+// DefinirTags substitui as tags do cliente pelas informadas, criando as que não existem
+func DefinirTags(clienteID string, nomes []string, autor string) ([]models.Tag, error) {
+	tags := []models.Tag{}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var cliente models.Cliente
+		if err := tx.First(&cliente, "id = ?", clienteID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClienteNaoEncontrado
+			}
+			return err
+		}
+
+		vistos := map[string]bool{}
+		for _, nome := range nomes {
+			nome = strings.ToLower(strings.TrimSpace(nome))
+			if nome == "" || vistos[nome] {
+				continue
+			}
+			vistos[nome] = true
+
+			tag := models.Tag{Nome: nome}
+			if err := utils.Validate.Struct(tag); err != nil {
+				return fmt.Errorf("%w: %q: %v", ErrTagInvalida, nome, err)
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("nome = ?", nome).First(&tag).Error; err != nil {
+				return err
+			}
+			tags = append(tags, tag)
+		}
+
+		if err := tx.Model(&cliente).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+
+		return RegistrarAuditoria(tx, "cliente", clienteID, &clienteID, "cliente.tags", autor,
+			map[string]interface{}{"tags": nomesTags(tags)})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func nomesTags(tags []models.Tag) []string {
+	nomes := make([]string, len(tags))
+	for i, tag := range tags {
+		nomes[i] = tag.Nome
+	}
+	return nomes
+}
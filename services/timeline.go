@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+// ItemTimeline é um acontecimento na linha do tempo do cliente
+type ItemTimeline struct {
+	Tipo       string                 `json:"tipo"`
+	Data       time.Time              `json:"data"`
+	Descricao  string                 `json:"descricao"`
+	Autor      string                 `json:"autor,omitempty"`
+	Referencia string                 `json:"referencia,omitempty"`
+	Dados      map[string]interface{} `json:"dados,omitempty"`
+}
+
+// Timeline reúne em ordem cronológica decrescente as notas visíveis ao usuário,
+// as vendas, as assinaturas e os registros de auditoria (pagamentos e alterações)
+func Timeline(clienteID, email, role string, limite int) ([]ItemTimeline, error) {
+	var cliente models.Cliente
+	if err := config.DB.First(&cliente, "id = ?", clienteID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClienteNaoEncontrado
+		}
+		return nil, err
+	}
+
+	itens := []ItemTimeline{}
+
+	var notas []models.Nota
+	if err := config.DB.Where("cliente_id = ?", clienteID).Find(&notas).Error; err != nil {
+		return nil, err
+	}
+	for _, nota := range notas {
+		if !nota.VisivelPara(email, role) {
+			continue
+		}
+		itens = append(itens, ItemTimeline{
+			Tipo:       "nota",
+			Data:       nota.CreatedAt,
+			Descricao:  nota.Texto,
+			Autor:      nota.Autor,
+			Referencia: nota.ID,
+			Dados:      map[string]interface{}{"visibilidade": nota.Visibilidade},
+		})
+	}
+
+	var vendas []models.Sale
+	if err := config.DB.Preload("Produto").Where("cliente_id = ?", clienteID).Find(&vendas).Error; err != nil {
+		return nil, err
+	}
+	for _, venda := range vendas {
+		itens = append(itens, ItemTimeline{
+			Tipo:       "venda",
+			Data:       venda.CriadoEm,
+			Descricao:  fmt.Sprintf("Venda de %dx %s", venda.Quantidade, venda.Produto.Nome),
+			Referencia: venda.ID,
+			Dados:      map[string]interface{}{"valor": venda.Valor, "status": venda.Status},
+		})
+	}
+
+	// As assinaturas vêm da tabela, incluindo as anteriores à auditoria
+	var assinaturas []models.Subscription
+	if err := config.DB.Where("cliente_id = ?", clienteID).Find(&assinaturas).Error; err != nil {
+		return nil, err
+	}
+	var criacoes []models.RegistroAuditoria
+	if err := config.DB.Where("cliente_id = ? AND acao = ?", clienteID, "assinatura.criacao").Find(&criacoes).Error; err != nil {
+		return nil, err
+	}
+	autores := map[string]string{}
+	for _, criacao := range criacoes {
+		autores[criacao.EntidadeID] = criacao.Autor
+	}
+	for _, assinatura := range assinaturas {
+		itens = append(itens, ItemTimeline{
+			Tipo:       "assinatura",
+			Data:       assinatura.CriadoEm,
+			Descricao:  "assinatura.criacao",
+			Autor:      autores[assinatura.ID],
+			Referencia: assinatura.ID,
+			Dados:      map[string]interface{}{"valor": assinatura.Amount, "forma_pagamento": assinatura.PaymentMethod, "ativa": assinatura.Active},
+		})
+	}
+
+	var registros []models.RegistroAuditoria
+	if err := config.DB.Where("cliente_id = ? AND acao <> ?", clienteID, "assinatura.criacao").Find(&registros).Error; err != nil {
+		return nil, err
+	}
+	for _, registro := range registros {
+		// Registros de acesso aos dados pela LGPD só interessam a quem pode vê-los
+		if strings.HasPrefix(registro.Acao, "lgpd.") && role != "superadmin" {
+			continue
+		}
+		itens = append(itens, ItemTimeline{
+			Tipo:       tipoTimeline(registro.Acao),
+			Data:       registro.CriadoEm,
+			Descricao:  registro.Acao,
+			Autor:      registro.Autor,
+			Referencia: registro.EntidadeID,
+			Dados:      registro.Dados,
+		})
+	}
+
+	sort.SliceStable(itens, func(i, j int) bool {
+		return itens[i].Data.After(itens[j].Data)
+	})
+
+	if limite > 0 && len(itens) > limite {
+		itens = itens[:limite]
+	}
+	return itens, nil
+}
+
+// Agrupa as ações de auditoria nos tipos exibidos na linha do tempo
+func tipoTimeline(acao string) string {
+	switch {
+	case strings.HasSuffix(acao, ".pagamento"):
+		return "pagamento"
+	case strings.HasPrefix(acao, "assinatura."):
+		return "assinatura"
+	case strings.HasPrefix(acao, "cliente."):
+		return "cadastro"
+	}
+	return strings.SplitN(acao, ".", 2)[0]
+}
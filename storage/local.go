@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local grava os arquivos em um diretório do sistema de arquivos
+type Local struct {
+	Diretorio string
+}
+
+// NovoLocal cria um armazenamento local no diretório informado
+func NovoLocal(diretorio string) *Local {
+	return &Local{Diretorio: diretorio}
+}
+
+// Converte a chave em caminho, impedindo que ela saia do diretório base
+func (l *Local) caminho(chave string) (string, error) {
+	base := filepath.Clean(l.Diretorio)
+	caminho := filepath.Join(base, filepath.FromSlash(chave))
+	if !strings.HasPrefix(caminho, base+string(filepath.Separator)) {
+		return "", fmt.Errorf("chave inválida: %s", chave)
+	}
+	return caminho, nil
+}
+
+// Salvar grava o conteúdo em um arquivo temporário e o renomeia ao final,
+// para que uma escrita interrompida não deixe um arquivo pela metade
+func (l *Local) Salvar(chave string, conteudo io.Reader, tamanho int64, contentType string) error {
+	caminho, err := l.caminho(chave)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(caminho), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(caminho), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, conteudo); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), caminho)
+}
+
+// Abrir retorna o conteúdo do arquivo
+func (l *Local) Abrir(chave string) (io.ReadCloser, error) {
+	caminho, err := l.caminho(chave)
+	if err != nil {
+		return nil, err
+	}
+
+	arquivo, err := os.Open(caminho)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNaoEncontrado
+	}
+	return arquivo, err
+}
+
+// Remover apaga o arquivo; remover um arquivo inexistente não é erro
+func (l *Local) Remover(chave string) error {
+	caminho, err := l.caminho(chave)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(caminho); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalSalvarAbrirRemover(t *testing.T) {
+	local := NovoLocal(t.TempDir())
+
+	if err := local.Salvar("clientes/c1/doc", strings.NewReader("conteúdo"), 9, "text/plain"); err != nil {
+		t.Fatalf("Salvar: %v", err)
+	}
+
+	arquivo, err := local.Abrir("clientes/c1/doc")
+	if err != nil {
+		t.Fatalf("Abrir: %v", err)
+	}
+	lido, err := io.ReadAll(arquivo)
+	arquivo.Close()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(lido) != "conteúdo" {
+		t.Errorf("conteúdo lido = %q, esperado %q", lido, "conteúdo")
+	}
+
+	if err := local.Remover("clientes/c1/doc"); err != nil {
+		t.Fatalf("Remover: %v", err)
+	}
+	if _, err := local.Abrir("clientes/c1/doc"); !errors.Is(err, ErrNaoEncontrado) {
+		t.Errorf("Abrir após remover: erro = %v, esperado ErrNaoEncontrado", err)
+	}
+	if err := local.Remover("clientes/c1/doc"); err != nil {
+		t.Errorf("remover arquivo inexistente não deveria falhar: %v", err)
+	}
+}
+
+func TestLocalSobrescreveSemArquivosTemporarios(t *testing.T) {
+	diretorio := t.TempDir()
+	local := NovoLocal(diretorio)
+
+	for _, conteudo := range []string{"primeira versão", "segunda"} {
+		if err := local.Salvar("doc", strings.NewReader(conteudo), int64(len(conteudo)), ""); err != nil {
+			t.Fatalf("Salvar: %v", err)
+		}
+	}
+
+	lido, err := os.ReadFile(filepath.Join(diretorio, "doc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(lido) != "segunda" {
+		t.Errorf("conteúdo = %q, esperado %q", lido, "segunda")
+	}
+
+	entradas, err := os.ReadDir(diretorio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entradas) != 1 {
+		t.Errorf("esperado apenas o arquivo final no diretório, encontrados %d", len(entradas))
+	}
+}
+
+// Um leitor que falha no meio não pode deixar um arquivo pela metade
+func TestLocalEscritaInterrompida(t *testing.T) {
+	diretorio := t.TempDir()
+	local := NovoLocal(diretorio)
+
+	leitor := io.MultiReader(strings.NewReader("parcial"), &leitorComErro{})
+	if err := local.Salvar("doc", leitor, 100, ""); err == nil {
+		t.Fatal("esperado erro na escrita interrompida")
+	}
+	if _, err := local.Abrir("doc"); !errors.Is(err, ErrNaoEncontrado) {
+		t.Errorf("Abrir após falha: erro = %v, esperado ErrNaoEncontrado", err)
+	}
+
+	entradas, err := os.ReadDir(diretorio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entradas) != 0 {
+		t.Errorf("arquivo temporário não foi removido: %d entradas", len(entradas))
+	}
+}
+
+func TestLocalChaveForaDoDiretorio(t *testing.T) {
+	local := NovoLocal(t.TempDir())
+
+	for _, chave := range []string{"../fora", "a/../../fora", "", "."} {
+		if err := local.Salvar(chave, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Salvar(%q) deveria recusar a chave", chave)
+		}
+		if _, err := local.Abrir(chave); err == nil {
+			t.Errorf("Abrir(%q) deveria recusar a chave", chave)
+		}
+		if err := local.Remover(chave); err == nil {
+			t.Errorf("Remover(%q) deveria recusar a chave", chave)
+		}
+	}
+}
+
+type leitorComErro struct{}
+
+func (*leitorComErro) Read([]byte) (int, error) {
+	return 0, errors.New("conexão interrompida")
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// S3 grava os arquivos em um bucket compatível com S3 (AWS, MinIO, R2...),
+// usando URLs no estilo de caminho e assinatura AWS Signature V4
+type S3 struct {
+	Endpoint    string
+	Regiao      string
+	Bucket      string
+	AccessKey   string
+	SecretKey   string
+	HTTPCliente *http.Client
+}
+
+// NovoS3DoAmbiente configura o backend a partir de S3_ENDPOINT, S3_REGION,
+// S3_BUCKET, S3_ACCESS_KEY e S3_SECRET_KEY
+func NovoS3DoAmbiente() (*S3, error) {
+	s3 := &S3{
+		Endpoint:    strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/"),
+		Regiao:      os.Getenv("S3_REGION"),
+		Bucket:      os.Getenv("S3_BUCKET"),
+		AccessKey:   os.Getenv("S3_ACCESS_KEY"),
+		SecretKey:   os.Getenv("S3_SECRET_KEY"),
+		HTTPCliente: &http.Client{Timeout: 2 * time.Minute},
+	}
+	if s3.Regiao == "" {
+		s3.Regiao = "us-east-1"
+	}
+	if s3.Endpoint == "" || s3.Bucket == "" || s3.AccessKey == "" || s3.SecretKey == "" {
+		return nil, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY e S3_SECRET_KEY são obrigatórias")
+	}
+	return s3, nil
+}
+
+// Salvar envia o objeto ao bucket
+func (s *S3) Salvar(chave string, conteudo io.Reader, tamanho int64, contentType string) error {
+	req, err := s.requisicao(http.MethodPut, chave, conteudo)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = tamanho
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.executar(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Abrir baixa o objeto do bucket
+func (s *S3) Abrir(chave string) (io.ReadCloser, error) {
+	req, err := s.requisicao(http.MethodGet, chave, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.executar(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Remover apaga o objeto do bucket; o S3 não reclama de objetos inexistentes
+func (s *S3) Remover(chave string) error {
+	req, err := s.requisicao(http.MethodDelete, chave, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.executar(req)
+	if err != nil && !errors.Is(err, ErrNaoEncontrado) {
+		return err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (s *S3) requisicao(metodo, chave string, corpo io.Reader) (*http.Request, error) {
+	caminho := (&url.URL{Path: "/" + s.Bucket + "/" + chave}).EscapedPath()
+	return http.NewRequest(metodo, s.Endpoint+caminho, corpo)
+}
+
+// Assina e executa a requisição, convertendo respostas de erro do S3
+func (s *S3) executar(req *http.Request) (*http.Response, error) {
+	s.assinar(req, time.Now().UTC())
+
+	resp, err := s.HTTPCliente.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNaoEncontrado
+	}
+	if resp.StatusCode >= 300 {
+		detalhe, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("S3 respondeu %d: %s", resp.StatusCode, detalhe)
+	}
+	return resp, nil
+}
+
+// Assina a requisição com AWS Signature V4. O corpo não entra na assinatura
+// (UNSIGNED-PAYLOAD) para que o upload possa ser enviado em streaming.
+func (s *S3) assinar(req *http.Request, agora time.Time) {
+	data := agora.Format("20060102")
+	dataHora := agora.Format("20060102T150405Z")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", dataHora)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+
+	cabecalhosAssinados := "host;x-amz-content-sha256;x-amz-date"
+	canonica := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:UNSIGNED-PAYLOAD\n" +
+			"x-amz-date:" + dataHora + "\n",
+		cabecalhosAssinados,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	escopo := data + "/" + s.Regiao + "/s3/aws4_request"
+	hashCanonica := sha256.Sum256([]byte(canonica))
+	textoAssinado := "AWS4-HMAC-SHA256\n" + dataHora + "\n" + escopo + "\n" + hex.EncodeToString(hashCanonica[:])
+
+	chave := hmacSHA256([]byte("AWS4"+s.SecretKey), data)
+	chave = hmacSHA256(chave, s.Regiao)
+	chave = hmacSHA256(chave, "s3")
+	chave = hmacSHA256(chave, "aws4_request")
+	assinatura := hex.EncodeToString(hmacSHA256(chave, textoAssinado))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, escopo, cabecalhosAssinados, assinatura))
+}
+
+func hmacSHA256(chave []byte, dado string) []byte {
+	mac := hmac.New(sha256.New, chave)
+	mac.Write([]byte(dado))
+	return mac.Sum(nil)
+}
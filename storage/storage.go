@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// ErrNaoEncontrado indica que o objeto não existe no armazenamento
+var ErrNaoEncontrado = errors.New("objeto não encontrado no armazenamento")
+
+// Armazenamento é um backend onde os arquivos enviados são guardados.
+// As chaves usam "/" como separador, independentemente do backend.
+type Armazenamento interface {
+	Salvar(chave string, conteudo io.Reader, tamanho int64, contentType string) error
+	Abrir(chave string) (io.ReadCloser, error)
+	Remover(chave string) error
+}
+
+var (
+	once   sync.Once
+	padrao Armazenamento
+)
+
+// Padrao retorna o backend configurado em STORAGE_DRIVER (local ou s3).
+// Sem configuração os arquivos são gravados no sistema de arquivos local.
+func Padrao() Armazenamento {
+	once.Do(func() {
+		switch os.Getenv("STORAGE_DRIVER") {
+		case "s3":
+			s3, err := NovoS3DoAmbiente()
+			if err != nil {
+				log.Fatal("Erro ao configurar armazenamento S3: ", err)
+			}
+			padrao = s3
+		default:
+			diretorio := os.Getenv("STORAGE_DIR")
+			if diretorio == "" {
+				diretorio = "uploads"
+			}
+			padrao = NovoLocal(diretorio)
+		}
+	})
+	return padrao
+}
+
+// Definir substitui o backend padrão, útil para usar um armazenamento local
+// no lugar do S3 em desenvolvimento
+func Definir(a Armazenamento) {
+	once.Do(func() {})
+	padrao = a
+}
@@ -0,0 +1,39 @@
+package tasks
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go-api/notifications"
+	"go-api/services"
+)
+
+// AtualizarAniversariantes recalcula a flag de aniversariante dos clientes e,
+// se ANIVERSARIO_SAUDACAO=true, envia os parabéns para quem faz aniversário hoje.
+func AtualizarAniversariantes() {
+	hoje := time.Now()
+	if _, err := services.AtualizarFlagsAniversariante(hoje); err != nil {
+		log.Println("Erro ao atualizar aniversariantes:", err)
+		return
+	}
+
+	if os.Getenv("ANIVERSARIO_SAUDACAO") != "true" {
+		return
+	}
+
+	clientes, err := services.ListarAniversariantes(services.JanelaHoje, hoje)
+	if err != nil {
+		log.Println("Erro ao buscar aniversariantes do dia:", err)
+		return
+	}
+
+	for _, cliente := range clientes {
+		notifications.Enviar(notifications.Mensagem{
+			Destinatario: cliente.Email,
+			Assunto:      "Feliz aniversário!",
+			Texto:        fmt.Sprintf("Parabéns, %s! Toda a equipe deseja um ótimo aniversário.", cliente.Nome),
+		})
+	}
+}
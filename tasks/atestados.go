@@ -0,0 +1,44 @@
+package tasks
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"go-api/db"
+	"go-api/events"
+	"go-api/models"
+	"go-api/notifications"
+	"go-api/services"
+)
+
+// AvisarAtestadosVencendo avisa os clientes cujo atestado médico vence hoje ou
+// dentro de ATESTADO_AVISO_DIAS dias, publicando um evento para cada um.
+func AvisarAtestadosVencendo() {
+	hoje := time.Now()
+	perfis, err := services.AtestadosAVencer(hoje)
+	if err != nil {
+		log.Println("Erro ao buscar atestados a vencer:", err)
+		return
+	}
+
+	for _, perfil := range perfis {
+		var cliente models.Cliente
+		if err := config.DB.Select("id", "nome", "email").First(&cliente, "id = ?", perfil.ClienteID).Error; err != nil {
+			log.Printf("Erro ao buscar cliente %s: %v", perfil.ClienteID, err)
+			continue
+		}
+
+		validade := perfil.AtestadoValidoAte.Format("02/01/2006")
+		events.Publish(events.Evento{
+			Tipo:      events.AtestadoVencendo,
+			ClienteID: cliente.ID,
+			Dados:     map[string]interface{}{"atestado_valido_ate": perfil.AtestadoValidoAte},
+		})
+		notifications.Enviar(notifications.Mensagem{
+			Destinatario: cliente.Email,
+			Assunto:      "Atestado médico vencendo",
+			Texto:        fmt.Sprintf("Olá, %s! Seu atestado médico vale até %s. Traga um novo atestado para continuar treinando.", cliente.Nome, validade),
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package tasks
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"go-api/notifications"
+	"go-api/services"
+)
+
+// AtualizarCongelamentos inicia os congelamentos de assinatura agendados para
+// hoje e reativa as assinaturas cujo congelamento terminou, avisando o aluno.
+func AtualizarCongelamentos() {
+	hoje := time.Now()
+
+	iniciados, encerrados, err := services.AtualizarCongelamentos(hoje, "sistema")
+	if err != nil {
+		log.Println("Erro ao atualizar congelamentos de assinatura:", err)
+		return
+	}
+
+	for _, congelamento := range encerrados {
+		// Assinaturas canceladas durante o congelamento não são reativadas
+		if congelamento.Subscription == nil || !congelamento.Subscription.Active {
+			continue
+		}
+		cliente := congelamento.Subscription.Cliente
+		if cliente.Email == "" {
+			log.Printf("Cliente %s sem e-mail para o aviso de reativação da assinatura", cliente.ID)
+			continue
+		}
+		notifications.Enviar(notifications.Mensagem{
+			Destinatario: cliente.Email,
+			Assunto:      "Assinatura reativada",
+			Texto: fmt.Sprintf("Olá, %s! O congelamento da sua assinatura terminou e seu acesso às aulas foi liberado. A próxima cobrança será em %s.",
+				cliente.Nome, congelamento.Subscription.NextBillingDate.Format("02/01/2006")),
+		})
+	}
+	log.Printf("%d congelamentos iniciados e %d assinaturas reativadas", iniciados, len(encerrados))
+}
@@ -0,0 +1,17 @@
+package tasks
+
+import (
+	"log"
+	"time"
+
+	"go-api/services"
+)
+
+// LimparImportacoes descarta da memória os jobs de importação concluídos há
+// mais tempo que a retenção configurada
+func LimparImportacoes() {
+	removidos := services.LimparImportacoes(time.Now().Add(-services.RetencaoImportacao()))
+	if removidos > 0 {
+		log.Printf("%d jobs de importação concluídos removidos da memória", removidos)
+	}
+}
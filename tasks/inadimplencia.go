@@ -0,0 +1,15 @@
+package tasks
+
+import (
+	"log"
+
+	"go-api/services"
+)
+
+// RecalcularInadimplentes reavalia diariamente a inadimplência dos clientes,
+// já que vendas e assinaturas vencem com a passagem do tempo
+func RecalcularInadimplentes() {
+	if err := services.RecalcularTodosInadimplentes(); err != nil {
+		log.Println("Erro ao recalcular inadimplentes:", err)
+	}
+}
@@ -0,0 +1,65 @@
+package tasks
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"go-api/db"
+	"go-api/models"
+	"go-api/notifications"
+	"go-api/services"
+)
+
+// LembrarLeads avisa os responsáveis pelos leads com contato previsto para hoje
+// ou atrasado e lembra os leads das aulas experimentais de amanhã.
+func LembrarLeads() {
+	hoje := time.Now()
+
+	leads, err := services.LeadsParaContato(hoje)
+	if err != nil {
+		log.Println("Erro ao buscar leads para contato:", err)
+	} else {
+		for _, lead := range leads {
+			if lead.Responsavel == nil {
+				log.Printf("Lead %s aguarda contato e não tem responsável", lead.ID)
+				continue
+			}
+			notifications.Enviar(notifications.Mensagem{
+				Destinatario: lead.Responsavel.Email,
+				Assunto:      "Retorno pendente de lead",
+				Texto: fmt.Sprintf("O lead %s (%s, %s) aguarda contato desde %s.",
+					lead.Nome, lead.Telefone, lead.Status, lead.ProximoContatoEm.Format("02/01/2006")),
+			})
+		}
+	}
+
+	experimentais, err := services.AulasExperimentaisSemLembrete(hoje.AddDate(0, 0, 1))
+	if err != nil {
+		log.Println("Erro ao buscar aulas experimentais de amanhã:", err)
+		return
+	}
+	for _, experimental := range experimentais {
+		var lead models.Lead
+		if err := config.DB.First(&lead, "id = ?", experimental.LeadID).Error; err != nil {
+			log.Printf("Erro ao buscar lead %s: %v", experimental.LeadID, err)
+			continue
+		}
+
+		// Sem e-mail o lembrete segue pelo telefone, para canais como o WhatsApp
+		destinatario := lead.Email
+		if destinatario == "" {
+			destinatario = lead.Telefone
+		}
+		notifications.Enviar(notifications.Mensagem{
+			Destinatario: destinatario,
+			Assunto:      "Sua aula experimental é amanhã",
+			Texto: fmt.Sprintf("Olá, %s! Lembrete da sua aula experimental de %s amanhã às %s. Esperamos você!",
+				lead.Nome, experimental.Aula.Nome, experimental.Aula.HoraInicio),
+		})
+
+		if err := config.DB.Model(&experimental).Update("lembrete_enviado_em", time.Now()).Error; err != nil {
+			log.Printf("Erro ao registrar lembrete da aula experimental %s: %v", experimental.ID, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package tasks
+
+import (
+	"log"
+	"time"
+
+	"go-api/db"
+	"go-api/events"
+	"go-api/models"
+
+	"gorm.io/gorm"
+)
+
+// ArquivarResponsaveisMaioridade arquiva os responsáveis e os pais dos clientes
+// que completaram 18 anos. Os registros são mantidos para histórico e apenas
+// os clientes que ainda têm responsáveis ativos são consultados.
+func ArquivarResponsaveisMaioridade() {
+	var clientes []models.Cliente
+	err := config.DB.
+		Where("data_nascimento <= CURRENT_DATE - INTERVAL '18 years'").
+		Where(config.DB.
+			Where("EXISTS (SELECT 1 FROM guardians g WHERE g.cliente_id = clientes.id AND g.arquivado_em IS NULL)").
+			Or("EXISTS (SELECT 1 FROM pais p WHERE p.cliente_id = clientes.id AND p.arquivado_em IS NULL)")).
+		Find(&clientes).Error
+	if err != nil {
+		log.Println("Erro ao buscar clientes que atingiram a maioridade:", err)
+		return
+	}
+
+	for _, cliente := range clientes {
+		agora := time.Now()
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Guardian{}).
+				Where("cliente_id = ? AND arquivado_em IS NULL", cliente.ID).
+				Update("arquivado_em", agora).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Pais{}).
+				Where("cliente_id = ? AND arquivado_em IS NULL", cliente.ID).
+				Update("arquivado_em", agora).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.Cliente{}).Where("id = ?", cliente.ID).Update("pais_id", nil).Error
+		})
+		if err != nil {
+			log.Printf("Erro ao arquivar responsáveis do cliente %s: %v", cliente.ID, err)
+			continue
+		}
+
+		events.Publish(events.Evento{
+			Tipo:      events.ClienteMaioridade,
+			ClienteID: cliente.ID,
+			Dados: map[string]interface{}{
+				"nome":            cliente.Nome,
+				"data_nascimento": cliente.DataNascimento.Format("2006-01-02"),
+			},
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package tasks
+
+import (
+	"log"
+	"time"
+
+	"go-api/services"
+)
+
+// CalcularRemuneracoes gera no início do mês os rascunhos dos extratos da
+// competência anterior para conferência e aprovação
+func CalcularRemuneracoes() {
+	agora := time.Now()
+	competencia := time.Date(agora.Year(), agora.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 0).Format("2006-01")
+
+	extratos, err := services.CalcularExtratosCompetencia(competencia, "sistema")
+	if err != nil {
+		log.Printf("Erro ao calcular extratos de %s: %v", competencia, err)
+		return
+	}
+	log.Printf("%d extratos de remuneração calculados para %s", len(extratos), competencia)
+}
@@ -0,0 +1,53 @@
+package tasks
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"go-api/services"
+
+	"github.com/robfig/cron/v3"
+)
+
+// IniciarAgendador registra as tarefas periódicas e inicia o agendador.
+// As expressões cron podem ser sobrescritas por variáveis de ambiente.
+func IniciarAgendador() *cron.Cron {
+	c := cron.New(cron.WithChain(
+		cron.Recover(cron.DefaultLogger),
+		cron.SkipIfStillRunning(cron.DefaultLogger),
+	))
+
+	agendar(c, "CRON_MAIORIDADE", "0 3 * * *", ArquivarResponsaveisMaioridade)
+	agendar(c, "CRON_ANIVERSARIOS", "5 0 * * *", AtualizarAniversariantes)
+	agendar(c, "CRON_INADIMPLENCIA", "15 0 * * *", RecalcularInadimplentes)
+	agendar(c, "CRON_ATESTADOS", "0 8 * * *", AvisarAtestadosVencendo)
+	agendar(c, "CRON_LEADS", "0 9 * * *", LembrarLeads)
+	agendar(c, "CRON_REMUNERACAO", "0 6 1 * *", CalcularRemuneracoes)
+	agendar(c, "CRON_CONGELAMENTOS", "10 0 * * *", AtualizarCongelamentos)
+	agendar(c, "CRON_IMPORTACOES", "30 * * * *", LimparImportacoes)
+
+	c.Start()
+
+	// Recalcula as flags na inicialização para não depender do próximo disparo
+	go func() {
+		if _, err := services.AtualizarFlagsAniversariante(time.Now()); err != nil {
+			log.Println("Erro ao atualizar aniversariantes:", err)
+		}
+	}()
+
+	return c
+}
+
+// Agenda uma tarefa usando a expressão da variável de ambiente ou a padrão
+func agendar(c *cron.Cron, variavel, padrao string, tarefa func()) {
+	expressao := os.Getenv(variavel)
+	if expressao == "" {
+		expressao = padrao
+	}
+
+	if _, err := c.AddFunc(expressao, tarefa); err != nil {
+		log.Fatalf("Expressão cron inválida em %s (%s): %v", variavel, expressao, err)
+	}
+	log.Printf("Tarefa %s agendada: %s", variavel, expressao)
+}
@@ -0,0 +1,49 @@
+package utils
+
+import "strings"
+
+// NormalizarCPF remove pontuação e espaços, mantendo apenas os dígitos do CPF
+func NormalizarCPF(cpf string) string {
+	var b strings.Builder
+	for _, r := range cpf {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ValidarCPF verifica o tamanho e os dígitos verificadores de um CPF.
+// Aceita o CPF com ou sem pontuação.
+func ValidarCPF(cpf string) bool {
+	cpf = NormalizarCPF(cpf)
+	if len(cpf) != 11 {
+		return false
+	}
+
+	// CPFs com todos os dígitos iguais passam no cálculo, mas são inválidos
+	if strings.Count(cpf, cpf[:1]) == 11 {
+		return false
+	}
+
+	digitos := make([]int, 11)
+	for i, r := range cpf {
+		digitos[i] = int(r - '0')
+	}
+
+	for _, tamanho := range []int{9, 10} {
+		soma := 0
+		for i := 0; i < tamanho; i++ {
+			soma += digitos[i] * (tamanho + 1 - i)
+		}
+		dv := (soma * 10) % 11
+		if dv == 10 {
+			dv = 0
+		}
+		if dv != digitos[tamanho] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -4,8 +4,11 @@ package utils
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"io"
 	"os"
@@ -70,4 +73,50 @@ func Decrypt(encrypted string) ([]byte, error) {
 	stream.XORKeyStream(ciphertext, ciphertext)
 
 	return ciphertext, nil
-}
\ No newline at end of file
+}
+
+// Obter a chave dos índices cegos; sem BLIND_INDEX_KEY ela é derivada da chave de criptografia
+func getBlindIndexKey() []byte {
+	if key := os.Getenv("BLIND_INDEX_KEY"); key != "" {
+		return []byte(key)
+	}
+
+	mac := hmac.New(sha256.New, getEncryptionKey())
+	mac.Write([]byte("indice-cego"))
+	return mac.Sum(nil)
+}
+
+// IndiceCego gera um HMAC determinístico do valor, permitindo buscar e garantir
+// unicidade de campos criptografados sem expor o conteúdo original
+func IndiceCego(valor string) string {
+	if valor == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, getBlindIndexKey())
+	mac.Write([]byte(valor))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Obter a chave das URLs assinadas; sem URL_SIGNING_KEY ela é derivada da chave de criptografia
+func getURLSigningKey() []byte {
+	if key := os.Getenv("URL_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+
+	mac := hmac.New(sha256.New, getEncryptionKey())
+	mac.Write([]byte("url-assinada"))
+	return mac.Sum(nil)
+}
+
+// AssinarURL gera a assinatura de um link temporário
+func AssinarURL(valor string) string {
+	mac := hmac.New(sha256.New, getURLSigningKey())
+	mac.Write([]byte(valor))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerificarAssinaturaURL compara a assinatura em tempo constante
+func VerificarAssinaturaURL(valor, assinatura string) bool {
+	return hmac.Equal([]byte(AssinarURL(valor)), []byte(assinatura))
+}
@@ -0,0 +1,49 @@
+package utils
+
+import "strings"
+
+// MascararCPF exibe apenas os dígitos centrais do CPF, no formato ***.456.789-**
+func MascararCPF(cpf string) string {
+	digitos := NormalizarCPF(cpf)
+	if len(digitos) != 11 {
+		if cpf == "" {
+			return ""
+		}
+		return "***"
+	}
+	return "***." + digitos[3:6] + "." + digitos[6:9] + "-**"
+}
+
+// MascararEmail mantém a primeira letra do usuário e o domínio
+func MascararEmail(email string) string {
+	usuario, dominio, ok := strings.Cut(email, "@")
+	if !ok || usuario == "" {
+		if email == "" {
+			return ""
+		}
+		return "***"
+	}
+	return usuario[:1] + "***@" + dominio
+}
+
+// MascararTelefone mantém apenas os quatro últimos dígitos
+func MascararTelefone(telefone string) string {
+	digitos := NormalizarCPF(telefone)
+	if len(digitos) < 4 {
+		if telefone == "" {
+			return ""
+		}
+		return "***"
+	}
+	return "(**) *****-" + digitos[len(digitos)-4:]
+}
+
+// ManterSeMascarado devolve o valor gravado quando o recebido é a máscara dele
+// ou contém "*", para que dados exibidos mascarados e reenviados sem alteração
+// não sejam gravados sobre os reais
+func ManterSeMascarado(recebido, gravado string, mascarar func(string) string) string {
+	if recebido != gravado && (recebido == mascarar(gravado) || strings.Contains(recebido, "*")) {
+		return gravado
+	}
+	return recebido
+}
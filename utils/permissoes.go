@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// PodeVerPII indica se o papel pode ver dados pessoais completos (CPF, e-mail,
+// telefone, endereço...). Os papéis são configurados em PII_ROLES, separados
+// por vírgula, e por padrão apenas o superadmin tem acesso.
+func PodeVerPII(role string) bool {
+	return papelPermitido(role, "PII_ROLES", "superadmin")
+}
+
+// PodeVerSaude indica se o papel pode ler e alterar as informações médicas dos
+// clientes. Os papéis são configurados em SAUDE_ROLES e por padrão apenas o
+// superadmin tem acesso.
+func PodeVerSaude(role string) bool {
+	return papelPermitido(role, "SAUDE_ROLES", "superadmin")
+}
+
+// Verifica se o papel está na lista da variável de ambiente ou na lista padrão
+func papelPermitido(role, variavel, padrao string) bool {
+	lista := os.Getenv(variavel)
+	if lista == "" {
+		lista = padrao
+	}
+
+	for _, permitido := range strings.Split(lista, ",") {
+		if strings.TrimSpace(permitido) == role {
+			return true
+		}
+	}
+	return false
+}
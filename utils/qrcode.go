@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// QRCodePNG gera a imagem PNG do QR code com o tamanho em pixels informado
+func QRCodePNG(conteudo string, tamanho int) ([]byte, error) {
+	return qrcode.Encode(conteudo, qrcode.Medium, tamanho)
+}
+
+// QRCodeSVG gera o QR code em SVG, desenhando os módulos escuros em um único path
+func QRCodeSVG(conteudo string, tamanho int) ([]byte, error) {
+	qr, err := qrcode.New(conteudo, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+
+	modulos := qr.Bitmap()
+	var path strings.Builder
+	for y, linha := range modulos {
+		for x, escuro := range linha {
+			if escuro {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`,
+		tamanho, tamanho, len(modulos), len(modulos), path.String())
+	return []byte(svg), nil
+}
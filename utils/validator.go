@@ -2,4 +2,13 @@ package utils
 
 import "github.com/go-playground/validator/v10"
 
-var Validate = validator.New()
\ No newline at end of file
+var Validate = novoValidador()
+
+// Cria o validador registrando as validações customizadas da API
+func novoValidador() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("cpf", func(fl validator.FieldLevel) bool {
+		return ValidarCPF(fl.Field().String())
+	})
+	return v
+}